@@ -2,37 +2,77 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/testsabirweb/connect_llm/internal/config"
 	"github.com/testsabirweb/connect_llm/pkg/embeddings"
 	"github.com/testsabirweb/connect_llm/pkg/ingestion"
 	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/ollama"
 	"github.com/testsabirweb/connect_llm/pkg/processing"
+	"github.com/testsabirweb/connect_llm/pkg/secrets"
 	"github.com/testsabirweb/connect_llm/pkg/vector"
 )
 
 func main() {
 	// Define command-line flags
 	var (
-		inputPath      = flag.String("input", "", "Path to CSV file or directory to ingest (required)")
-		inputType      = flag.String("type", "auto", "Input type: 'file', 'directory', or 'auto' (default: auto)")
+		inputPath      = flag.String("input", "", "Path to the CSV file, directory, export ZIP or documentation to ingest (required)")
+		inputType      = flag.String("type", "auto", "Input type: 'file', 'directory', a connector name such as 'slack-zip', or 'auto' (default: auto)")
 		batchSize      = flag.Int("batch-size", 100, "Number of messages to process in each batch")
 		maxConcurrency = flag.Int("concurrency", 5, "Maximum number of concurrent workers")
+		embedWorkers   = flag.Int("embed-workers", 0, "Number of messages embedded at once (default: -concurrency)")
+		storeWorkers   = flag.Int("store-workers", 0, "Number of messages stored at once (default: -concurrency)")
+		queueSize      = flag.Int("queue-size", 0, "Messages queued for each stage before parsing waits (default: twice -embed-workers)")
+		fileWorkers    = flag.Int("file-workers", 0, "Number of files in a directory parsed at once (default: -concurrency)")
 		chunkSize      = flag.Int("chunk-size", 500, "Maximum chunk size in words")
 		chunkOverlap   = flag.Int("chunk-overlap", 50, "Chunk overlap in words")
 		skipEmpty      = flag.Bool("skip-empty", true, "Skip messages with empty content")
-		embeddingModel = flag.String("embedding-model", "llama3:8b", "Ollama model to use for embeddings")
-		help           = flag.Bool("help", false, "Show help message")
+		threads        = flag.Bool("threads", true, "Reconstruct threads and store a document per thread")
+		usersPath      = flag.String("users", "", "Path to users.csv (default: users.csv next to the input)")
+		channelsPath   = flag.String("channels", "", "Path to channels.csv (default: channels.csv next to the input)")
+		statePath      = flag.String("state", "", "Path to the checkpoint file for incremental ingestion (default: INGEST_STATE_PATH)")
+		deadLetterPath = flag.String("dead-letter", "", "Path to the JSONL file failed records are written to (default: INGEST_DEAD_LETTER_PATH)")
+		replayPath     = flag.String("replay", "", "Re-process the failed records of a dead-letter file instead of ingesting -input")
+		resetState     = flag.Bool("reset-state", false, "Discard checkpoints and ingest everything again")
+		filterPath     = flag.String("filter", "", "Path to a YAML or JSON file of include and exclude rules (default: INGEST_FILTER_PATH)")
+		filterFlags    = filterFlags{
+			includeChannels: flag.String("include-channels", "", "Comma-separated channel IDs or names to ingest exclusively"),
+			excludeChannels: flag.String("exclude-channels", "", "Comma-separated channel IDs or names to skip"),
+			excludeUsers:    flag.String("exclude-users", "", "Comma-separated user IDs or names to skip"),
+			excludeBots:     flag.String("exclude-bots", "", "Comma-separated bot IDs or names to skip, or '*' for every bot"),
+			excludeSubtypes: flag.String("exclude-subtypes", "", "Comma-separated message subtypes to skip, e.g. channel_join,channel_leave"),
+			excludeArchived: flag.Bool("exclude-archived", false, "Skip messages in archived channels"),
+			excludeText:     flag.String("exclude-text", "", "Skip messages whose text matches this regular expression"),
+			after:           flag.String("after", "", "Only ingest messages from this date on (YYYY-MM-DD or RFC 3339)"),
+			before:          flag.String("before", "", "Only ingest messages from before this date (YYYY-MM-DD or RFC 3339)"),
+		}
+		redactMode      = flag.String("redact", "", "Redact personal data before embedding: 'mask', 'hash' or 'drop' (default: INGEST_REDACT_MODE, off when empty)")
+		redactDetectors = flag.String("redact-detectors", "", "Comma-separated detectors to redact with: email, phone, ip, card (default: INGEST_REDACT_DETECTORS, all)")
+		redactPatterns  = patternFlag{}
+		secretAction    = flag.String("secrets", "", "What to do with messages holding API keys and other credentials: 'quarantine', 'redact' or 'off' (default: INGEST_SECRETS, quarantine)")
+		stages          = flag.String("stages", "", "Comma-separated processing stages in order (default: INGEST_STAGES, "+strings.Join(processing.DefaultStages, ",")+")")
+		secretAuditPath = flag.String("secrets-audit", "", "JSONL file to audit the secrets found to (default: INGEST_SECRETS_AUDIT_PATH)")
+		embeddingModel  = flag.String("embedding-model", "", "Ollama model to use for embeddings (default: OLLAMA_EMBEDDING_MODEL)")
+		dryRun          = flag.Bool("dry-run", false, "Parse, filter and chunk without calling the embedder or Weaviate, and report what would be ingested")
+		dryRunFormat    = flag.String("dry-run-format", "text", "Dry-run report format: 'text' or 'json'")
+		embedLatency    = flag.Duration("embed-latency", processing.DefaultEmbedLatency, "Time one embedding is assumed to take, for the dry-run runtime estimate")
+		help            = flag.Bool("help", false, "Show help message")
 	)
 
+	flag.Var(redactPatterns, "redact-pattern", "Extra detector to redact with, as name=regexp (repeatable)")
 	flag.Parse()
 
-	if *help || *inputPath == "" {
+	if *help || (*inputPath == "" && *replayPath == "") {
 		printUsage()
 		os.Exit(0)
 	}
@@ -43,82 +83,397 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Create Weaviate client
-	log.Println("Connecting to Weaviate...")
-	vectorClient, err := vector.NewWeaviateClient(
-		cfg.Weaviate.Scheme,
-		cfg.Weaviate.Host,
-		cfg.Weaviate.APIKey,
-	)
-	if err != nil {
-		log.Fatalf("Failed to create Weaviate client: %v", err)
+	ctx := context.Background()
+	if *dryRun && *replayPath != "" {
+		log.Fatal("-dry-run cannot be combined with -replay")
+	}
+	if *dryRun && *dryRunFormat != "text" && *dryRunFormat != "json" {
+		log.Fatalf("Invalid dry-run format: %s (available: text, json)", *dryRunFormat)
 	}
 
-	// Initialize Weaviate schema
-	ctx := context.Background()
-	if err := vectorClient.Initialize(ctx); err != nil {
-		log.Fatalf("Failed to initialize Weaviate schema: %v", err)
+	// A dry run stands in for Weaviate, counting the documents it would
+	// store
+	var vectorClient vector.Client
+	var dry *processing.DryRun
+	if *dryRun {
+		workers := *embedWorkers
+		if workers <= 0 {
+			workers = *maxConcurrency
+		}
+		dry = processing.NewDryRun(processing.DryRunConfig{EmbedLatency: *embedLatency, EmbedWorkers: workers})
+		vectorClient = dry
+	} else {
+		// Create Weaviate client
+		log.Println("Connecting to Weaviate...")
+		weaviateClient, err := vector.NewWeaviateClient(
+			cfg.Weaviate.Scheme,
+			cfg.Weaviate.Host,
+			cfg.Weaviate.APIKey,
+		)
+		if err != nil {
+			log.Fatalf("Failed to create Weaviate client: %v", err)
+		}
+
+		// Initialize Weaviate schema
+		if err := weaviateClient.Initialize(ctx); err != nil {
+			log.Fatalf("Failed to initialize Weaviate schema: %v", err)
+		}
+		vectorClient = weaviateClient
 	}
 
 	// Create embedder and document processor
+	if *embeddingModel == "" {
+		*embeddingModel = cfg.Ollama.EmbeddingModel
+	}
 	log.Printf("Creating embedder with model: %s", *embeddingModel)
-	embedder := embeddings.NewOllamaEmbedder(cfg.Ollama.URL, *embeddingModel)
+	ollamaPool, err := ollama.NewPool(cfg.Ollama.URLs, cfg.Ollama.Timeouts())
+	if err != nil {
+		log.Fatalf("Failed to create Ollama pool: %v", err)
+	}
+	if !*dryRun {
+		go ollamaPool.StartHealthChecks(ctx, cfg.Ollama.HealthCheckInterval)
+	}
+	embedder := embeddings.NewOllamaEmbedderWithPool(ollamaPool, *embeddingModel)
+	embedder.SetKeepAlive(cfg.Ollama.KeepAlive)
 	processor := processing.NewDocumentProcessor(embedder, *chunkSize, *chunkOverlap)
+	if *redactMode == "" {
+		*redactMode = cfg.Ingestion.RedactMode
+	}
+	var redactor *processing.Redactor
+	if *redactMode != "" {
+		detectors := splitList(*redactDetectors)
+		if len(detectors) == 0 {
+			detectors = cfg.Ingestion.RedactDetectors
+		}
+		redactor, err = processing.NewRedactor(processing.RedactionConfig{
+			Mode:      processing.RedactionMode(*redactMode),
+			Detectors: detectors,
+			Custom:    redactPatterns,
+			HashKey:   cfg.Ingestion.RedactHashKey,
+		})
+		if err != nil {
+			log.Fatalf("Invalid redaction settings: %v", err)
+		}
+		log.Printf("Redacting personal data in %s mode", redactor.Mode())
+		processor.SetRedactor(redactor)
+	}
+	if *secretAction == "" {
+		*secretAction = cfg.Ingestion.SecretAction
+	}
+	if *secretAuditPath == "" && !*dryRun {
+		*secretAuditPath = cfg.Ingestion.SecretAuditPath
+	}
+	var secretGuard *processing.SecretGuard
+	if *secretAction != "off" {
+		secretGuard, err = processing.NewSecretGuard(secrets.Default(), processing.SecretAction(*secretAction))
+		if err != nil {
+			log.Fatalf("Invalid secret settings: %v", err)
+		}
+		if *secretAuditPath != "" {
+			auditFile, err := os.OpenFile(*secretAuditPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+			if err != nil {
+				log.Fatalf("Failed to open secret audit log: %v", err)
+			}
+			defer auditFile.Close()
+			secretGuard.SetAuditLog(auditFile)
+		}
+		processor.SetSecretGuard(secretGuard)
+	}
+	stageNames := splitList(*stages)
+	if len(stageNames) == 0 {
+		stageNames = cfg.Ingestion.Stages
+	}
+	if dry != nil {
+		// The dry run counts messages where they would be embedded
+		processor.RegisterStage(dry.Stage())
+		stageNames = processing.DryRunStages(stageNames)
+	}
+	if err := processor.SetStages(stageNames...); err != nil {
+		log.Fatalf("Invalid processing stages: %v", err)
+	}
 
 	// Create ingestion service
 	ingestionConfig := ingestion.ServiceConfig{
-		BatchSize:        *batchSize,
-		MaxConcurrency:   *maxConcurrency,
-		SkipEmptyContent: *skipEmpty,
+		BatchSize:          *batchSize,
+		MaxConcurrency:     *maxConcurrency,
+		EmbedWorkers:       *embedWorkers,
+		StoreWorkers:       *storeWorkers,
+		QueueSize:          *queueSize,
+		FileWorkers:        *fileWorkers,
+		SkipEmptyContent:   *skipEmpty,
+		ReconstructThreads: *threads,
+	}
+	// Dry runs leave no checkpoints or dead letters behind
+	if *statePath == "" && !*dryRun {
+		*statePath = cfg.Ingestion.StatePath
+	}
+	if *statePath != "" {
+		state, err := ingestion.OpenStateStore(*statePath)
+		if err != nil {
+			log.Fatalf("Failed to open ingestion state: %v", err)
+		}
+		if *resetState {
+			if err := state.Reset(); err != nil {
+				log.Fatalf("Failed to reset ingestion state: %v", err)
+			}
+		}
+		log.Printf("Using ingestion checkpoints in %s", *statePath)
+		ingestionConfig.State = state
+	}
+	if *deadLetterPath == "" && !*dryRun {
+		*deadLetterPath = cfg.Ingestion.DeadLetterPath
+	}
+	// Without a file named, records that fail again are written back to the
+	// replayed file, and new failures beside the checkpoints
+	switch {
+	case *deadLetterPath != "" || *dryRun:
+	case *replayPath != "":
+		*deadLetterPath = *replayPath
+	case *statePath != "":
+		*deadLetterPath = ingestion.DeadLetterPathFor(*statePath)
+	}
+	if *filterPath == "" {
+		*filterPath = cfg.Ingestion.FilterPath
+	}
+	filter, err := filterFlags.filter(*filterPath)
+	if err != nil {
+		log.Fatalf("Invalid filter rules: %v", err)
+	}
+	ingestionConfig.Filter = filter
+
+	// Replayed records are read before any new failures are written, so
+	// the dead-letter file can be replayed onto itself
+	var replayRecords []ingestion.DeadLetter
+	if *replayPath != "" {
+		replayRecords, err = ingestion.ReadDeadLetters(*replayPath)
+		if err != nil {
+			log.Fatalf("Failed to read dead letters: %v", err)
+		}
+		if *deadLetterPath == *replayPath {
+			if err := os.Rename(*replayPath, *replayPath+".replaying"); err != nil {
+				log.Fatalf("Failed to move dead letters aside: %v", err)
+			}
+		}
+	}
+	var deadLetters *ingestion.DeadLetterWriter
+	if *deadLetterPath != "" {
+		deadLetters = ingestion.NewDeadLetterWriter(*deadLetterPath)
+		defer deadLetters.Close()
+		ingestionConfig.DeadLetters = deadLetters
 	}
 
 	// Create adapter for processor
 	adapter := &documentProcessorAdapter{processor: processor}
 	service := ingestion.NewService(vectorClient, adapter, ingestionConfig)
 
+	if *replayPath != "" {
+		log.Printf("Replaying %d dead letters from %s", len(replayRecords), *replayPath)
+		startTime := time.Now()
+		stats := service.ReplayDeadLetters(ctx, replayRecords)
+		if *deadLetterPath == *replayPath {
+			os.Remove(*replayPath + ".replaying") //nolint:errcheck // Records still failing were rewritten to the dead-letter file
+		}
+		printStats("Replay", stats, time.Since(startTime), deadLetters)
+		printRedactions(redactor)
+		printSecrets(secretGuard, *secretAuditPath)
+		printStages(processor.Pipeline())
+		return
+	}
+
 	// Determine input type
 	if *inputType == "auto" {
 		fileInfo, err := os.Stat(*inputPath)
 		if err != nil {
 			log.Fatalf("Failed to stat input path: %v", err)
 		}
-		if fileInfo.IsDir() {
+		ext := strings.ToLower(filepath.Ext(*inputPath))
+		switch {
+		case fileInfo.IsDir():
+			// Directories without message tables are documentation, such
+			// as docs/
+			*inputType = "markdown"
+			if hasMessageFiles(*inputPath) {
+				*inputType = "directory"
+			}
+		case ext == ".zip":
+			// ZIP archives of CSV tables are read like directories
 			*inputType = "directory"
-		} else {
+			if files, err := ingestion.DiscoverFiles(*inputPath); err == nil && len(files) == 1 && files[0].Kind == ingestion.KindSlackExport {
+				*inputType = "slack-zip"
+			}
+		case ext == ".md" || ext == ".markdown" || ext == ".txt":
+			*inputType = "markdown"
+		case ext == ".html" || ext == ".htm":
+			*inputType = "html"
+		case ext == ".mbox" || ext == ".mbx" || ext == ".eml":
+			*inputType = "email"
+		default:
 			*inputType = "file"
 		}
 	}
 
-	// Perform ingestion
-	startTime := time.Now()
-	var stats *ingestion.IngestionStats
+	// Without -users and -channels, the tables next to the input are used
+	// if present
+	if *usersPath != "" {
+		if err := service.LoadUsers(*usersPath); err != nil {
+			log.Fatalf("Failed to load users: %v", err)
+		}
+	}
+	if *channelsPath != "" {
+		membersPath := filepath.Join(filepath.Dir(*channelsPath), ingestion.ChannelMembersFile)
+		if _, err := os.Stat(membersPath); err != nil {
+			membersPath = ""
+		}
+		if err := service.LoadChannels(*channelsPath, membersPath); err != nil {
+			log.Fatalf("Failed to load channels: %v", err)
+		}
+	}
 
-	switch *inputType {
-	case "file":
-		log.Printf("Ingesting file: %s", *inputPath)
-		stats, err = service.IngestFile(ctx, *inputPath)
-	case "directory":
-		log.Printf("Ingesting directory: %s", *inputPath)
-		stats, err = service.IngestDirectory(ctx, *inputPath)
-	default:
-		log.Fatalf("Invalid input type: %s", *inputType)
+	connector, ok := service.Connector(*inputType)
+	if !ok {
+		log.Fatalf("Invalid input type: %s (available: file, directory, %s)", *inputType, strings.Join(service.Connectors().Names(), ", "))
 	}
 
+	// Perform ingestion
+	startTime := time.Now()
+	stats := &ingestion.IngestionStats{}
+
+	log.Printf("Ingesting %s with the %s connector", *inputPath, connector.Name())
+	err = service.Ingest(ctx, ingestion.IngestRequest{Type: *inputType, Path: *inputPath}, stats)
 	if err != nil {
 		log.Fatalf("Ingestion failed: %v", err)
 	}
 
-	// Print results
-	duration := time.Since(startTime)
-	fmt.Println("\n=== Ingestion Complete ===")
+	if dry != nil {
+		if err := printDryRun(dry.Report(), stats, *dryRunFormat); err != nil {
+			log.Fatalf("Failed to print dry-run report: %v", err)
+		}
+		return
+	}
+
+	printStats("Ingestion", stats, time.Since(startTime), deadLetters)
+	printRedactions(redactor)
+	printSecrets(secretGuard, *secretAuditPath)
+	printStages(processor.Pipeline())
+}
+
+// printDryRun prints what a dry run found, as text or JSON
+func printDryRun(report processing.DryRunReport, stats *ingestion.IngestionStats, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			processing.DryRunReport
+			Ingestion map[string]interface{} `json:"ingestion"`
+		}{report, stats.GetSummary()})
+	}
+
+	fmt.Println("\n=== Dry Run ===")
+	fmt.Printf("Messages read: %d (%d skipped, %d filtered, %d failed)\n",
+		stats.TotalMessages, stats.SkippedMessages, stats.FilteredMessages, stats.FailedMessages)
+	fmt.Printf("Messages to embed: %d\n", report.Messages)
+	printCounts("By channel", report.ByChannel)
+	printCounts("By subtype", report.BySubtype)
+	fmt.Printf("Bot messages: %d\n", report.BotMessages)
+	printCounts("By bot", report.ByBot)
+
+	fmt.Printf("\nDocuments to store: %d (%d for whole threads)\n", report.Documents, report.ThreadDocuments)
+	fmt.Printf("Chunk size (words, average %.1f):\n", report.AverageWords)
+	largest := 0
+	for _, bucket := range report.ChunkWords {
+		largest = max(largest, bucket.Count)
+	}
+	previous := processing.HistogramBucket{}
+	for _, bucket := range report.ChunkWords {
+		bar := ""
+		if largest > 0 {
+			bar = strings.Repeat("#", (bucket.Count*40+largest-1)/largest)
+		}
+		fmt.Printf("  %9s %7d %s\n", bucket.Label(previous), bucket.Count, bar)
+		previous = bucket
+	}
+
+	fmt.Printf("\nEstimated tokens: %d\n", report.EstimatedTokens)
+	fmt.Printf("Dry run took: %s\n", report.Elapsed.Round(time.Millisecond))
+	fmt.Printf("Estimated runtime: %s (at %s per embedding, %d at once)\n",
+		report.EstimatedRuntime.Round(time.Second), report.EmbedLatency, report.EmbedWorkers)
+	return nil
+}
+
+// printCounts prints the ten largest counts of a breakdown
+func printCounts(title string, counts []processing.Count) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Printf("%s:\n", title)
+	for i, count := range counts {
+		if i >= 10 {
+			fmt.Printf("  ... and %d more\n", len(counts)-10)
+			break
+		}
+		fmt.Printf("  %-30s %7d\n", count.Name, count.Count)
+	}
+}
+
+// printStages prints the metrics of each processing stage
+func printStages(pipeline *processing.Pipeline) {
+	fmt.Println("Processing stages:")
+	for _, m := range pipeline.Metrics() {
+		fmt.Printf("  %-10s %6d processed, %5d dropped, %5d errors, %s\n",
+			m.Name, m.Processed, m.Dropped, m.Errors, m.Duration.Round(time.Millisecond))
+		if m.LastError != "" {
+			fmt.Printf("  %-10s last error: %s\n", "", m.LastError)
+		}
+	}
+}
+
+// printRedactions prints what the redactor found, if redaction is enabled
+func printRedactions(redactor *processing.Redactor) {
+	if redactor == nil {
+		return
+	}
+	fmt.Printf("Redactions (%s): %s\n", redactor.Mode(), redactor.Report())
+}
+
+// printSecrets prints what the secret guard found, if it is enabled
+func printSecrets(guard *processing.SecretGuard, auditPath string) {
+	if guard == nil {
+		return
+	}
+	audit := guard.Audit()
+	fmt.Printf("Secrets (%s): %s\n", guard.Action(), audit)
+	if len(audit.Findings) > 0 && auditPath != "" {
+		fmt.Printf("Secret findings audited to %s\n", auditPath)
+	}
+}
+
+// printStats prints the results of an ingestion or replay run
+func printStats(run string, stats *ingestion.IngestionStats, duration time.Duration, deadLetters *ingestion.DeadLetterWriter) {
+	fmt.Printf("\n=== %s Complete ===\n", run)
 	fmt.Printf("Duration: %s\n", duration.Round(time.Second))
 	fmt.Printf("Total messages: %d\n", stats.TotalMessages)
 	fmt.Printf("Processed messages: %d\n", stats.ProcessedMessages)
 	fmt.Printf("Skipped messages: %d\n", stats.SkippedMessages)
+	fmt.Printf("Unchanged messages: %d\n", stats.UnchangedMessages)
 	fmt.Printf("Failed messages: %d\n", stats.FailedMessages)
 	fmt.Printf("Total documents created: %d\n", stats.TotalDocuments)
 	fmt.Printf("Documents stored: %d\n", stats.StoredDocuments)
 	fmt.Printf("Documents failed: %d\n", stats.FailedDocuments)
+	fmt.Printf("Threads reconstructed: %d\n", stats.Threads)
+	if len(stats.RuleMatches) > 0 {
+		fmt.Printf("Filtered messages: %d\n", stats.FilteredMessages)
+		rules := make([]string, 0, len(stats.RuleMatches))
+		for rule := range stats.RuleMatches {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		for _, rule := range rules {
+			fmt.Printf("  - %s: %d\n", rule, stats.RuleMatches[rule])
+		}
+	}
+	if deadLetters != nil && stats.DeadLetters > 0 {
+		fmt.Printf("Dead letters: %d written to %s (replay with -replay %s)\n", stats.DeadLetters, deadLetters.Path(), deadLetters.Path())
+	}
 
 	if len(stats.Errors) > 0 {
 		fmt.Printf("\nErrors encountered: %d\n", len(stats.Errors))
@@ -143,18 +498,124 @@ func printUsage() {
 	fmt.Println("  ingest -input <path> [options]")
 	fmt.Println("\nRequired:")
 	fmt.Println("  -input string")
-	fmt.Println("        Path to CSV file or directory to ingest")
+	fmt.Println("        Path to the CSV file, directory, export ZIP or documentation to ingest")
 	fmt.Println("\nOptions:")
 	flag.PrintDefaults()
 	fmt.Println("\nExamples:")
 	fmt.Println("  # Ingest a single CSV file")
 	fmt.Println("  ingest -input slack/channel_general.csv")
-	fmt.Println("\n  # Ingest all CSV files in a directory")
+	fmt.Println("\n  # Ingest all CSV, .csv.gz and ZIP files in a directory tree")
 	fmt.Println("  ingest -input slack/")
+	fmt.Println("\n  # Ingest a Slack workspace export without extracting it")
+	fmt.Println("  ingest -input export.zip -type slack-zip")
+	fmt.Println("\n  # Index the project's documentation next to Slack")
+	fmt.Println("  ingest -input docs/ -type markdown")
+	fmt.Println("  ingest -input README.md")
+	fmt.Println("\n  # Index a Confluence HTML export or a mailing-list archive")
+	fmt.Println("  ingest -input confluence-export/ -type html")
+	fmt.Println("  ingest -input dev-2024-03.mbox")
+	fmt.Println("\n  # Ingest only messages that are new since the last run")
+	fmt.Println("  ingest -input slack/ -state data/ingest-state.json")
+	fmt.Println("\n  # Skip membership notices, CI bots and everything before 2021")
+	fmt.Println("  ingest -input slack/ -exclude-subtypes channel_join,channel_leave -exclude-bots B2 -after 2021-01-01")
+	fmt.Println("  ingest -input export.zip -filter ingest-filter.yaml")
+	fmt.Println("\n  # Mask email addresses, phone numbers, IPs, card numbers and employee IDs")
+	fmt.Println("  ingest -input slack/ -redact mask -redact-pattern 'employee_id=EMP-\\d{6}'")
+	fmt.Println("  ingest -input slack/ -secrets redact -secrets-audit secrets.jsonl")
+	fmt.Println("\n  # See what would be ingested, and how long it would take, before embedding")
+	fmt.Println("  ingest -input slack/ -dry-run")
+	fmt.Println("  ingest -input slack/ -dry-run -dry-run-format json > plan.json")
+	fmt.Println("\n  # Retry the records that failed, e.g. on Ollama timeouts")
+	fmt.Println("  ingest -replay data/dead-letters.jsonl")
 	fmt.Println("\n  # Ingest with custom settings")
 	fmt.Println("  ingest -input slack/ -batch-size 200 -concurrency 10")
 }
 
+// filterFlags are the command-line shorthands for filter rules
+type filterFlags struct {
+	includeChannels *string
+	excludeChannels *string
+	excludeUsers    *string
+	excludeBots     *string
+	excludeSubtypes *string
+	excludeArchived *bool
+	excludeText     *string
+	after           *string
+	before          *string
+}
+
+// filter combines the rules of the file at path, if any, with the rules
+// given as flags. Flag rules are named after their flag. It returns nil if
+// there are no rules.
+func (f filterFlags) filter(path string) (*ingestion.Filter, error) {
+	var rules ingestion.FilterConfig
+	if path != "" {
+		var err error
+		if rules, err = ingestion.LoadFilterConfig(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if channels := splitList(*f.includeChannels); len(channels) > 0 {
+		rules.Include = append(rules.Include, ingestion.FilterRule{Name: "include-channels", Channels: channels})
+	}
+	for _, rule := range []ingestion.FilterRule{
+		{Name: "exclude-channels", Channels: splitList(*f.excludeChannels)},
+		{Name: "exclude-users", Users: splitList(*f.excludeUsers)},
+		{Name: "exclude-bots", Bots: splitList(*f.excludeBots)},
+		{Name: "exclude-subtypes", Subtypes: splitList(*f.excludeSubtypes)},
+		{Name: "exclude-archived", Archived: *f.excludeArchived},
+		{Name: "exclude-text", Text: *f.excludeText},
+		// Messages outside the range are excluded: -after excludes what
+		// came before it, and -before what came after it
+		{Name: "after", Before: *f.after},
+		{Name: "before", After: *f.before},
+	} {
+		if !reflect.DeepEqual(rule, ingestion.FilterRule{Name: rule.Name}) {
+			rules.Exclude = append(rules.Exclude, rule)
+		}
+	}
+
+	if len(rules.Include) == 0 && len(rules.Exclude) == 0 {
+		return nil, nil
+	}
+	return ingestion.NewFilter(rules)
+}
+
+// patternFlag collects repeated name=regexp flags
+type patternFlag map[string]string
+
+// String implements flag.Value
+func (f patternFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for name, pattern := range f {
+		pairs = append(pairs, name+"="+pattern)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value
+func (f patternFlag) Set(value string) error {
+	name, pattern, ok := strings.Cut(value, "=")
+	if !ok || name == "" || pattern == "" {
+		return fmt.Errorf("expected name=regexp, got %q", value)
+	}
+	f[name] = pattern
+	return nil
+}
+
+// splitList splits a comma-separated flag value, skipping empty entries
+func splitList(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // documentProcessorAdapter adapts processing.DocumentProcessor to ingestion.DocumentProcessor interface
 type documentProcessorAdapter struct {
 	processor *processing.DocumentProcessor
@@ -164,3 +625,37 @@ type documentProcessorAdapter struct {
 func (a *documentProcessorAdapter) ProcessMessage(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
 	return a.processor.ProcessMessage(ctx, msg)
 }
+
+// ProcessThread implements the ingestion.ThreadProcessor interface
+func (a *documentProcessorAdapter) ProcessThread(ctx context.Context, thread models.SlackThread) ([]vector.Document, error) {
+	return a.processor.ProcessThread(ctx, thread)
+}
+
+// ProcessRecord implements the ingestion.RecordProcessor interface
+func (a *documentProcessorAdapter) ProcessRecord(ctx context.Context, record models.Record) ([]vector.Document, error) {
+	return a.processor.ProcessRecord(ctx, record)
+}
+
+// SetUserDirectory implements the ingestion.UserAwareProcessor interface
+func (a *documentProcessorAdapter) SetUserDirectory(users *ingestion.UserDirectory) {
+	a.processor.SetUserResolver(users)
+}
+
+// SetChannelDirectory implements the ingestion.ChannelAwareProcessor interface
+func (a *documentProcessorAdapter) SetChannelDirectory(channels *ingestion.ChannelDirectory) {
+	a.processor.SetChannelResolver(channels)
+}
+
+// hasMessageFiles reports whether a directory holds Slack message tables
+func hasMessageFiles(dir string) bool {
+	files, err := ingestion.DiscoverFiles(dir)
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		if file.IsMessages() {
+			return true
+		}
+	}
+	return false
+}
@@ -4,13 +4,19 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/ollama"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Server   ServerConfig
-	Weaviate WeaviateConfig
-	Ollama   OllamaConfig
+	Server    ServerConfig
+	Weaviate  WeaviateConfig
+	Ollama    OllamaConfig
+	Ingestion IngestionConfig
+	Chat      ChatConfig
 }
 
 // ServerConfig holds server-specific configuration
@@ -29,10 +35,105 @@ type WeaviateConfig struct {
 // OllamaConfig holds Ollama-specific configuration
 type OllamaConfig struct {
 	URL string
+
+	// URLs lists every Ollama endpoint to load-balance across. URL is
+	// always its first entry.
+	URLs                []string
+	HealthCheckInterval time.Duration
+
+	// Stage timeouts for Ollama requests. None of them bound the total
+	// length of a streamed response.
+	ConnectTimeout    time.Duration
+	FirstTokenTimeout time.Duration
+	IdleTimeout       time.Duration
+
+	ChatModel      string
+	EmbeddingModel string
+
+	// KeepAlive is sent with every chat and embedding request to control
+	// how long models stay resident; "-1" pins them, empty uses the
+	// Ollama server default
+	KeepAlive string
+	// Warmup loads the chat and embedding models on every node at startup
+	Warmup bool
+}
+
+// IngestionConfig holds ingestion-specific configuration
+type IngestionConfig struct {
+	// StatePath is the file ingestion checkpoints are kept in. Empty
+	// disables incremental ingestion.
+	StatePath string
+	// DeadLetterPath is the JSONL file records that fail ingestion are
+	// written to. Empty writes them beside StatePath, if set, or disables
+	// them.
+	DeadLetterPath string
+	// FilterPath is a YAML or JSON file of rules excluding messages and
+	// records from ingestion. Empty ingests everything.
+	FilterPath string
+	// RedactMode is "mask", "hash" or "drop" to redact personal data
+	// before embedding, or empty to store text as is
+	RedactMode string
+	// RedactDetectors names the built-in detectors to run, all if empty
+	RedactDetectors []string
+	// RedactHashKey keys the hashes of the "hash" mode
+	RedactHashKey string
+	// SecretAction is "quarantine" or "redact" for messages holding API
+	// keys and other credentials, or "off" to index them as is
+	SecretAction string
+	// SecretAuditPath is the JSONL file secrets found during ingestion are
+	// audited to. Empty disables it.
+	SecretAuditPath string
+	// Stages names the document processing stages in order, the default
+	// stages if empty
+	Stages []string
+}
+
+// ChatConfig holds chat-specific configuration
+type ChatConfig struct {
+	// RAGEngagementBoost ranks pinned and highly reacted documents higher
+	// when retrieving context. Zero disables it.
+	RAGEngagementBoost float64
 }
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
+	connectTimeout, err := getEnvDuration("OLLAMA_CONNECT_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	firstTokenTimeout, err := getEnvDuration("OLLAMA_FIRST_TOKEN_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout, err := getEnvDuration("OLLAMA_IDLE_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	healthCheckInterval, err := getEnvDuration("OLLAMA_HEALTH_INTERVAL", 15*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	keepAlive, err := ollama.ParseKeepAlive(getEnv("OLLAMA_KEEP_ALIVE", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OLLAMA_KEEP_ALIVE: %w", err)
+	}
+	warmup, err := getEnvBool("OLLAMA_WARMUP", true)
+	if err != nil {
+		return nil, err
+	}
+
+	engagementBoost, err := getEnvFloat("RAG_ENGAGEMENT_BOOST", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// OLLAMA_URLS takes precedence over OLLAMA_URL when both are set
+	ollamaURLs := getEnvList("OLLAMA_URLS")
+	if len(ollamaURLs) == 0 {
+		ollamaURLs = []string{getEnv("OLLAMA_URL", "http://localhost:11434")}
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
 			Port: getEnv("PORT", "8080"),
@@ -44,7 +145,30 @@ func Load() (*Config, error) {
 			APIKey: getEnv("WEAVIATE_API_KEY", ""),
 		},
 		Ollama: OllamaConfig{
-			URL: getEnv("OLLAMA_URL", "http://localhost:11434"),
+			URL:                 ollamaURLs[0],
+			URLs:                ollamaURLs,
+			HealthCheckInterval: healthCheckInterval,
+			ConnectTimeout:      connectTimeout,
+			FirstTokenTimeout:   firstTokenTimeout,
+			IdleTimeout:         idleTimeout,
+			ChatModel:           getEnv("OLLAMA_CHAT_MODEL", "llama3:8b"),
+			EmbeddingModel:      getEnv("OLLAMA_EMBEDDING_MODEL", "llama3:8b"),
+			KeepAlive:           keepAlive,
+			Warmup:              warmup,
+		},
+		Ingestion: IngestionConfig{
+			StatePath:       getEnv("INGEST_STATE_PATH", ""),
+			DeadLetterPath:  getEnv("INGEST_DEAD_LETTER_PATH", ""),
+			FilterPath:      getEnv("INGEST_FILTER_PATH", ""),
+			RedactMode:      getEnv("INGEST_REDACT_MODE", ""),
+			RedactDetectors: getEnvList("INGEST_REDACT_DETECTORS"),
+			RedactHashKey:   getEnv("INGEST_REDACT_HASH_KEY", ""),
+			SecretAction:    getEnv("INGEST_SECRETS", "quarantine"),
+			SecretAuditPath: getEnv("INGEST_SECRETS_AUDIT_PATH", ""),
+			Stages:          getEnvList("INGEST_STAGES"),
+		},
+		Chat: ChatConfig{
+			RAGEngagementBoost: engagementBoost,
 		},
 	}
 
@@ -75,9 +199,22 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("WEAVIATE_SCHEME must be http or https")
 	}
 
+	if c.Ollama.HealthCheckInterval <= 0 && len(c.Ollama.URLs) > 0 {
+		return fmt.Errorf("OLLAMA_HEALTH_INTERVAL must be positive")
+	}
+
 	return nil
 }
 
+// Timeouts converts the configured Ollama stage timeouts
+func (c OllamaConfig) Timeouts() ollama.Timeouts {
+	return ollama.Timeouts{
+		Connect:    c.ConnectTimeout,
+		FirstToken: c.FirstTokenTimeout,
+		Idle:       c.IdleTimeout,
+	}
+}
+
 // getEnv gets an environment variable with a fallback default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -85,3 +222,53 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvDuration gets a duration environment variable (e.g. "90s") with a fallback default value
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", key, value)
+	}
+	return d, nil
+}
+
+// getEnvBool gets a boolean environment variable (e.g. "true", "0") with a fallback default value
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %s", key, value)
+	}
+	return b, nil
+}
+
+// getEnvFloat gets a non-negative number environment variable (e.g. "0.2") with a fallback default value
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", key, value)
+	}
+	return f, nil
+}
+
+// getEnvList gets a comma-separated environment variable as a list, skipping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
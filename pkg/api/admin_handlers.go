@@ -0,0 +1,338 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/ollama"
+)
+
+// ModelPullStatus tracks the progress of a background model pull
+type ModelPullStatus struct {
+	Node       string    `json:"node"`
+	Model      string    `json:"model"`
+	Status     string    `json:"status"`
+	Digest     string    `json:"digest,omitempty"`
+	Total      int64     `json:"total,omitempty"`
+	Completed  int64     `json:"completed,omitempty"`
+	Percent    float64   `json:"percent"`
+	Done       bool      `json:"done"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// modelPullTracker keeps the latest status of each model pull started from
+// the API, keyed by node URL and model name
+type modelPullTracker struct {
+	pulls map[string]*ModelPullStatus
+	mu    sync.RWMutex
+}
+
+// newModelPullTracker creates a new pull tracker
+func newModelPullTracker() *modelPullTracker {
+	return &modelPullTracker{
+		pulls: make(map[string]*ModelPullStatus),
+	}
+}
+
+// start registers a new pull, returning false if one is already in progress for the model
+func (t *modelPullTracker) start(node, model string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := node + " " + model
+	if existing, ok := t.pulls[key]; ok && !existing.Done {
+		return false
+	}
+
+	t.pulls[key] = &ModelPullStatus{
+		Node:      node,
+		Model:     model,
+		Status:    "starting",
+		StartedAt: time.Now(),
+	}
+	return true
+}
+
+// update records a progress update for a model pull
+func (t *modelPullTracker) update(node, model string, progress ollama.PullProgress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status, ok := t.pulls[node+" "+model]
+	if !ok {
+		return
+	}
+	status.Status = progress.Status
+	status.Digest = progress.Digest
+	status.Total = progress.Total
+	status.Completed = progress.Completed
+	status.Percent = progress.Percent()
+}
+
+// finish marks a model pull as complete
+func (t *modelPullTracker) finish(node, model string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status, ok := t.pulls[node+" "+model]
+	if !ok {
+		return
+	}
+	status.Done = true
+	status.FinishedAt = time.Now()
+	if err != nil {
+		status.Status = "failed"
+		status.Error = err.Error()
+	} else {
+		status.Status = "success"
+		status.Percent = 100
+	}
+}
+
+// list returns a snapshot of all tracked pulls, most recent first
+func (t *modelPullTracker) list() []ModelPullStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	pulls := make([]ModelPullStatus, 0, len(t.pulls))
+	for _, status := range t.pulls {
+		pulls = append(pulls, *status)
+	}
+	sort.Slice(pulls, func(i, j int) bool {
+		return pulls[i].StartedAt.After(pulls[j].StartedAt)
+	})
+	return pulls
+}
+
+// ModelPullRequest represents a request to pull a model
+type ModelPullRequest struct {
+	Model string `json:"model"`
+}
+
+// ModelCopyRequest represents a request to copy a model
+type ModelCopyRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// adminClient returns the Ollama node selected by the "node" query
+// parameter, defaulting to the first configured node. It writes an error
+// response and returns nil if the node is unknown.
+func (s *Server) adminClient(w http.ResponseWriter, r *http.Request) *ollama.Client {
+	node := r.URL.Query().Get("node")
+	client := s.ollamaPool.Client(node)
+	if client == nil {
+		http.Error(w, fmt.Sprintf("Unknown Ollama node: %s", node), http.StatusBadRequest)
+	}
+	return client
+}
+
+// handleModels lists installed and running models
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	client := s.adminClient(w, r)
+	if client == nil {
+		return
+	}
+
+	ctx := r.Context()
+
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		log.Printf("Failed to list models: %v", err)
+		http.Error(w, "Failed to list models", http.StatusBadGateway)
+		return
+	}
+
+	running, err := client.ListRunning(ctx)
+	if err != nil {
+		log.Printf("Failed to list running models: %v", err)
+		http.Error(w, "Failed to list running models", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"models":  models,
+		"running": running,
+		"warmup":  s.warmup.get(),
+	})
+}
+
+// handleModel dispatches model operations under /api/v1/admin/models/
+func (s *Server) handleModel(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/admin/models/")
+	path = strings.TrimSuffix(path, "/")
+
+	switch path {
+	case "":
+		http.Error(w, "Model name required", http.StatusBadRequest)
+	case "pull":
+		s.handleModelPull(w, r)
+	case "copy":
+		s.handleModelCopy(w, r)
+	default:
+		switch r.Method {
+		case http.MethodGet:
+			s.showModel(w, r, path)
+		case http.MethodDelete:
+			s.deleteModel(w, r, path)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleModelPull starts a background pull (POST) or reports pull progress (GET)
+func (s *Server) handleModelPull(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"pulls": s.modelPulls.list(),
+		})
+	case http.MethodPost:
+		var req ModelPullRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Model == "" {
+			http.Error(w, "Model is required", http.StatusBadRequest)
+			return
+		}
+
+		client := s.adminClient(w, r)
+		if client == nil {
+			return
+		}
+
+		node := client.BaseURL()
+		if !s.modelPulls.start(node, req.Model) {
+			http.Error(w, "Pull already in progress", http.StatusConflict)
+			return
+		}
+
+		// Pulls can take far longer than the HTTP write timeout, so run
+		// them in the background and let operators poll for progress
+		go func(model string) {
+			log.Printf("Pulling model %s on %s", model, node)
+			err := client.PullModelWithProgress(context.Background(), model, func(progress ollama.PullProgress) {
+				s.modelPulls.update(node, model, progress)
+			})
+			if err != nil {
+				log.Printf("Failed to pull model %s on %s: %v", model, node, err)
+			} else {
+				log.Printf("Model %s pulled successfully on %s", model, node)
+			}
+			s.modelPulls.finish(node, model, err)
+		}(req.Model)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"node":   node,
+			"model":  req.Model,
+			"status": "started",
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleModelCopy copies a model under a new name
+func (s *Server) handleModelCopy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ModelCopyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Source == "" || req.Destination == "" {
+		http.Error(w, "Source and destination are required", http.StatusBadRequest)
+		return
+	}
+
+	client := s.adminClient(w, r)
+	if client == nil {
+		return
+	}
+
+	if err := client.CopyModel(r.Context(), req.Source, req.Destination); err != nil {
+		log.Printf("Failed to copy model %s to %s: %v", req.Source, req.Destination, err)
+		http.Error(w, "Failed to copy model", http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// showModel returns details about a single model
+func (s *Server) showModel(w http.ResponseWriter, r *http.Request, model string) {
+	client := s.adminClient(w, r)
+	if client == nil {
+		return
+	}
+
+	details, err := client.ShowModel(r.Context(), model)
+	if err != nil {
+		log.Printf("Failed to show model %s: %v", model, err)
+		http.Error(w, "Model not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"model":          model,
+		"context_length": details.ContextLength(),
+		"details":        details,
+	})
+}
+
+// deleteModel removes a model from the Ollama server
+func (s *Server) deleteModel(w http.ResponseWriter, r *http.Request, model string) {
+	client := s.adminClient(w, r)
+	if client == nil {
+		return
+	}
+
+	if err := client.DeleteModel(r.Context(), model); err != nil {
+		log.Printf("Failed to delete model %s: %v", model, err)
+		http.Error(w, "Failed to delete model", http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePipeline reports the document processing stages and their metrics
+func (s *Server) handlePipeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pipeline := s.processor.Pipeline()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"stages":  pipeline.Stages(),
+		"metrics": pipeline.Metrics(),
+	})
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/ingestion"
+)
+
+// ingestEventInterval is how often job progress is pushed to event streams
+const ingestEventInterval = time.Second
+
+// handleIngest starts an ingestion job. The job runs in the background and
+// the response carries its ID; with "wait" set the request blocks until
+// the job finishes and returns its stats instead.
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request body
+	var req ingestion.IngestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if _, ok := s.ingestionService.Connector(req.Type); !ok {
+		types := append([]string{"file", "directory"}, s.ingestionService.Connectors().Names()...)
+		http.Error(w, fmt.Sprintf("Invalid type: must be one of %s", strings.Join(types, ", ")), http.StatusBadRequest)
+		return
+	}
+
+	if req.Path == "" {
+		http.Error(w, "Path is required", http.StatusBadRequest)
+		return
+	}
+
+	job := s.ingestJobs.Start(req)
+	log.Printf("Queued ingestion job %s: %s %s", job.ID, req.Type, req.Path)
+
+	if !req.Wait {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/v1/ingest/jobs/"+job.ID)
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(job)
+		return
+	}
+
+	// The job keeps running if the client gives up waiting
+	clearWriteDeadline(w)
+	done, _ := s.ingestJobs.Done(job.ID)
+	select {
+	case <-done:
+	case <-r.Context().Done():
+		return
+	}
+	job, _ = s.ingestJobs.Get(job.ID)
+
+	response := ingestion.IngestResponse{
+		Success: job.Status == ingestion.JobSucceeded,
+		Stats:   job.Stats,
+		Errors:  job.Errors,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !response.Success {
+		log.Printf("Ingestion error: %s", job.Error)
+		response.Errors = append([]string{job.Error}, response.Errors...)
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		log.Printf("Ingestion completed successfully. Stats: %+v", job.Stats)
+	}
+	json.NewEncoder(w).Encode(response) //nolint:errcheck // Response write errors are handled by HTTP framework
+}
+
+// handleIngestJobs lists past and running ingestion jobs, most recent first
+func (s *Server) handleIngestJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"jobs": s.ingestJobs.List(),
+	})
+}
+
+// handleIngestJob reports (GET) or cancels (DELETE) a job under
+// /api/v1/ingest/jobs/{id}, and streams its progress under
+// /api/v1/ingest/jobs/{id}/events
+func (s *Server) handleIngestJob(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/ingest/jobs/"), "/")
+	id, sub, _ := strings.Cut(path, "/")
+	if id == "" {
+		http.Error(w, "Job ID required", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case sub == "events" && r.Method == http.MethodGet:
+		s.streamIngestJob(w, r, id)
+	case sub != "":
+		http.NotFound(w, r)
+	case r.Method == http.MethodGet:
+		job, err := s.ingestJobs.Get(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(job)
+	case r.Method == http.MethodDelete:
+		err := s.ingestJobs.Cancel(id)
+		switch {
+		case errors.Is(err, ingestion.ErrJobNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, ingestion.ErrJobFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("Cancelled ingestion job %s", id)
+		job, _ := s.ingestJobs.Get(id)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(job)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// streamIngestJob pushes job snapshots as server-sent events: a "progress"
+// event every ingestEventInterval and a final "done" event
+func (s *Server) streamIngestJob(w http.ResponseWriter, r *http.Request, id string) {
+	done, err := s.ingestJobs.Done(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	clearWriteDeadline(w)
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string) bool {
+		job, err := s.ingestJobs.Get(id)
+		if err != nil {
+			return false
+		}
+		data, err := json.Marshal(job)
+		if err != nil {
+			log.Printf("Failed to encode job %s: %v", id, err)
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	ticker := time.NewTicker(ingestEventInterval)
+	defer ticker.Stop()
+
+	if !send("progress") {
+		return
+	}
+	for {
+		select {
+		case <-done:
+			send("done")
+			return
+		case <-ticker.C:
+			if !send("progress") {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// clearWriteDeadline lifts the server's write timeout for responses that
+// last as long as an ingestion job
+func clearWriteDeadline(w http.ResponseWriter) {
+	err := http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Failed to clear write deadline: %v", err)
+	}
+}
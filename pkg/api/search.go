@@ -54,6 +54,10 @@ type SearchResult struct {
 	// Content snippet (may be truncated)
 	Content string `json:"content"`
 
+	// Source text as written, e.g. with Slack markup, when Content was
+	// rendered from it
+	RawContent string `json:"rawContent,omitempty"`
+
 	// Relevance score (0-1, higher is more relevant)
 	Score float32 `json:"score"`
 
@@ -64,11 +68,22 @@ type SearchResult struct {
 	// Document metadata
 	Title     string    `json:"title,omitempty"`
 	Author    string    `json:"author,omitempty"`
+	AuthorID  string    `json:"authorId,omitempty"`
 	URL       string    `json:"url,omitempty"`
 	CreatedAt time.Time `json:"createdAt,omitempty"`
 	UpdatedAt time.Time `json:"updatedAt,omitempty"`
 	Tags      []string  `json:"tags,omitempty"`
 
+	// Thread information for conversation sources
+	ThreadID string `json:"threadId,omitempty"`
+	ParentID string `json:"parentId,omitempty"`
+
+	// Engagement signals: reactions, thread replies and whether the
+	// message is pinned
+	ReactionCount int  `json:"reactionCount,omitempty"`
+	ReplyCount    int  `json:"replyCount,omitempty"`
+	Pinned        bool `json:"pinned,omitempty"`
+
 	// Highlighted content with search terms emphasized
 	Highlights []string `json:"highlights,omitempty"`
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"time"
 
@@ -14,7 +15,9 @@ import (
 	"github.com/testsabirweb/connect_llm/pkg/embeddings"
 	"github.com/testsabirweb/connect_llm/pkg/ingestion"
 	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/ollama"
 	"github.com/testsabirweb/connect_llm/pkg/processing"
+	"github.com/testsabirweb/connect_llm/pkg/secrets"
 	"github.com/testsabirweb/connect_llm/pkg/vector"
 )
 
@@ -28,13 +31,38 @@ func (a *documentProcessorAdapter) ProcessMessage(ctx context.Context, msg model
 	return a.processor.ProcessMessage(ctx, msg)
 }
 
+// ProcessThread implements the ingestion.ThreadProcessor interface
+func (a *documentProcessorAdapter) ProcessThread(ctx context.Context, thread models.SlackThread) ([]vector.Document, error) {
+	return a.processor.ProcessThread(ctx, thread)
+}
+
+// ProcessRecord implements the ingestion.RecordProcessor interface
+func (a *documentProcessorAdapter) ProcessRecord(ctx context.Context, record models.Record) ([]vector.Document, error) {
+	return a.processor.ProcessRecord(ctx, record)
+}
+
+// SetUserDirectory implements the ingestion.UserAwareProcessor interface
+func (a *documentProcessorAdapter) SetUserDirectory(users *ingestion.UserDirectory) {
+	a.processor.SetUserResolver(users)
+}
+
+// SetChannelDirectory implements the ingestion.ChannelAwareProcessor interface
+func (a *documentProcessorAdapter) SetChannelDirectory(channels *ingestion.ChannelDirectory) {
+	a.processor.SetChannelResolver(channels)
+}
+
 // Server represents the API server
 type Server struct {
 	config           *config.Config
 	vectorClient     vector.Client
 	ingestionService *ingestion.Service
+	processor        *processing.DocumentProcessor
+	ingestJobs       *ingestion.JobManager
 	chatHub          *chat.Hub
 	chatService      *chat.Service
+	ollamaPool       *ollama.Pool
+	modelPulls       *modelPullTracker
+	warmup           *modelWarmup
 }
 
 // NewServer creates a new API server instance
@@ -57,18 +85,80 @@ func NewServer(cfg *config.Config) (*Server, error) {
 
 	log.Println("Weaviate schema initialized successfully")
 
+	// Create the Ollama pool and start probing its nodes
+	ollamaPool, err := ollama.NewPool(cfg.Ollama.URLs, cfg.Ollama.Timeouts())
+	if err != nil {
+		return nil, err
+	}
+	go ollamaPool.StartHealthChecks(context.Background(), cfg.Ollama.HealthCheckInterval)
+
 	// Create embedder and document processor
-	embedder := embeddings.NewOllamaEmbedder(cfg.Ollama.URL, "llama3:8b")
+	embedder := embeddings.NewOllamaEmbedderWithPool(ollamaPool, cfg.Ollama.EmbeddingModel)
+	embedder.SetKeepAlive(cfg.Ollama.KeepAlive)
 	processor := processing.NewDocumentProcessor(embedder, 500, 50)
+	if cfg.Ingestion.RedactMode != "" {
+		redactor, err := processing.NewRedactor(processing.RedactionConfig{
+			Mode:      processing.RedactionMode(cfg.Ingestion.RedactMode),
+			Detectors: cfg.Ingestion.RedactDetectors,
+			HashKey:   cfg.Ingestion.RedactHashKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction settings: %w", err)
+		}
+		processor.SetRedactor(redactor)
+	}
+	if cfg.Ingestion.SecretAction != "off" {
+		guard, err := processing.NewSecretGuard(secrets.Default(), processing.SecretAction(cfg.Ingestion.SecretAction))
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret settings: %w", err)
+		}
+		if cfg.Ingestion.SecretAuditPath != "" {
+			auditFile, err := os.OpenFile(cfg.Ingestion.SecretAuditPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open secret audit log: %w", err)
+			}
+			guard.SetAuditLog(auditFile)
+		}
+		processor.SetSecretGuard(guard)
+	}
+	if err := processor.SetStages(cfg.Ingestion.Stages...); err != nil {
+		return nil, fmt.Errorf("invalid processing stages: %w", err)
+	}
 
 	// Wrap processor with adapter
 	adapter := &documentProcessorAdapter{processor: processor}
 
 	// Create ingestion service
 	ingestionConfig := ingestion.ServiceConfig{
-		BatchSize:        100,
-		MaxConcurrency:   5,
-		SkipEmptyContent: true,
+		BatchSize:          100,
+		MaxConcurrency:     5,
+		SkipEmptyContent:   true,
+		ReconstructThreads: true,
+	}
+	if cfg.Ingestion.StatePath != "" {
+		state, err := ingestion.OpenStateStore(cfg.Ingestion.StatePath)
+		if err != nil {
+			return nil, err
+		}
+		ingestionConfig.State = state
+	}
+	deadLetterPath := cfg.Ingestion.DeadLetterPath
+	if deadLetterPath == "" && cfg.Ingestion.StatePath != "" {
+		deadLetterPath = ingestion.DeadLetterPathFor(cfg.Ingestion.StatePath)
+	}
+	if deadLetterPath != "" {
+		ingestionConfig.DeadLetters = ingestion.NewDeadLetterWriter(deadLetterPath)
+	}
+	if cfg.Ingestion.FilterPath != "" {
+		rules, err := ingestion.LoadFilterConfig(cfg.Ingestion.FilterPath)
+		if err != nil {
+			return nil, err
+		}
+		filter, err := ingestion.NewFilter(rules)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter rules: %w", err)
+		}
+		ingestionConfig.Filter = filter
 	}
 	ingestionService := ingestion.NewService(vectorClient, adapter, ingestionConfig)
 
@@ -76,18 +166,36 @@ func NewServer(cfg *config.Config) (*Server, error) {
 	chatHub := chat.NewHub()
 	chatConfig := chat.DefaultServiceConfig()
 	chatConfig.OllamaURL = cfg.Ollama.URL
+	chatConfig.OllamaModel = cfg.Ollama.ChatModel
+	chatConfig.EmbeddingModel = cfg.Ollama.EmbeddingModel
+	chatConfig.KeepAlive = cfg.Ollama.KeepAlive
+	chatConfig.OllamaTimeouts = cfg.Ollama.Timeouts()
+	chatConfig.OllamaPool = ollamaPool
+	chatConfig.RAGEngagementBoost = cfg.Chat.RAGEngagementBoost
 	chatService := chat.NewService(chatHub, vectorClient, chatConfig)
 
 	// Start the chat hub
 	go chatHub.Run(context.Background())
 
-	return &Server{
+	server := &Server{
 		config:           cfg,
 		vectorClient:     vectorClient,
 		ingestionService: ingestionService,
+		processor:        processor,
+		ingestJobs:       ingestion.NewJobManager(ingestionService, 0),
 		chatHub:          chatHub,
 		chatService:      chatService,
-	}, nil
+		ollamaPool:       ollamaPool,
+		modelPulls:       newModelPullTracker(),
+		warmup:           &modelWarmup{},
+	}
+
+	// Load models in the background so startup is not blocked on Ollama
+	if cfg.Ollama.Warmup {
+		go server.warmupModels(context.Background())
+	}
+
+	return server, nil
 }
 
 // Router returns the HTTP handler for the server
@@ -100,12 +208,19 @@ func (s *Server) Router() http.Handler {
 	// API endpoints
 	mux.HandleFunc("/api/v1/search", s.handleSearch)
 	mux.HandleFunc("/api/v1/ingest", s.handleIngest)
+	mux.HandleFunc("/api/v1/ingest/jobs", s.handleIngestJobs)
+	mux.HandleFunc("/api/v1/ingest/jobs/", s.handleIngestJob)
 
 	// Chat endpoints
 	mux.HandleFunc("/api/v1/chat/ws", s.handleWebSocket)
 	mux.HandleFunc("/api/v1/chat/conversations", s.handleConversations)
 	mux.HandleFunc("/api/v1/chat/conversations/", s.handleConversation)
 
+	// Model administration endpoints
+	mux.HandleFunc("/api/v1/admin/models", s.handleModels)
+	mux.HandleFunc("/api/v1/admin/models/", s.handleModel)
+	mux.HandleFunc("/api/v1/admin/pipeline", s.handlePipeline)
+
 	// Add middleware
 	return s.withMiddleware(mux)
 }
@@ -139,6 +254,15 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		weaviateError = err.Error()
 	}
 
+	// Ollama node health comes from the pool's background probes
+	ollamaNodes := s.ollamaPool.Status()
+	healthyNodes := 0
+	for _, node := range ollamaNodes {
+		if node.Healthy {
+			healthyNodes++
+		}
+	}
+
 	response := map[string]interface{}{
 		"status":  "healthy",
 		"service": "connect-llm",
@@ -147,13 +271,21 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 				"healthy": weaviateHealthy,
 				"error":   weaviateError,
 			},
+			"ollama": map[string]interface{}{
+				"healthy":       healthyNodes > 0,
+				"healthy_nodes": healthyNodes,
+				"total_nodes":   len(ollamaNodes),
+				"nodes":         ollamaNodes,
+			},
 		},
 	}
 
 	// Set overall status based on component health
-	if !weaviateHealthy {
+	if !weaviateHealthy || healthyNodes == 0 {
 		response["status"] = "unhealthy"
 		w.WriteHeader(http.StatusServiceUnavailable)
+	} else if healthyNodes < len(ollamaNodes) {
+		response["status"] = "degraded"
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -201,7 +333,8 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate embeddings for the query
-	embedder := embeddings.NewOllamaEmbedder(s.config.Ollama.URL, "llama3:8b")
+	embedder := embeddings.NewOllamaEmbedderWithPool(s.ollamaPool, s.config.Ollama.EmbeddingModel)
+	embedder.SetKeepAlive(s.config.Ollama.KeepAlive)
 	queryEmbeddings, err := embedder.GenerateEmbedding(ctx, req.Query)
 	if err != nil {
 		log.Printf("Failed to generate embeddings: %v", err)
@@ -262,17 +395,24 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		}
 
 		result := SearchResult{
-			ID:        doc.ID,
-			Content:   contentSnippet,
-			Score:     score,
-			Source:    doc.Source,
-			SourceID:  doc.SourceID,
-			Title:     doc.Metadata.Title,
-			Author:    doc.Metadata.Author,
-			URL:       doc.Metadata.URL,
-			CreatedAt: doc.Metadata.CreatedAt,
-			UpdatedAt: doc.Metadata.UpdatedAt,
-			Tags:      doc.Metadata.Tags,
+			ID:            doc.ID,
+			Content:       contentSnippet,
+			RawContent:    doc.RawContent,
+			Score:         score,
+			Source:        doc.Source,
+			SourceID:      doc.SourceID,
+			Title:         doc.Metadata.Title,
+			Author:        doc.Metadata.Author,
+			AuthorID:      doc.Metadata.AuthorID,
+			URL:           doc.Metadata.URL,
+			CreatedAt:     doc.Metadata.CreatedAt,
+			UpdatedAt:     doc.Metadata.UpdatedAt,
+			Tags:          doc.Metadata.Tags,
+			ThreadID:      doc.Metadata.ThreadID,
+			ParentID:      doc.Metadata.ParentID,
+			ReactionCount: doc.Metadata.ReactionCount,
+			ReplyCount:    doc.Metadata.ReplyCount,
+			Pinned:        doc.Metadata.Pinned,
 		}
 
 		results = append(results, result)
@@ -301,73 +441,3 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Failed to encode response: %v", err)
 	}
 }
-
-// handleIngest handles data ingestion requests
-func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
-	// Only accept POST requests
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Parse request body
-	var req ingestion.IngestRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
-		return
-	}
-
-	// Validate request
-	if req.Type != "file" && req.Type != "directory" {
-		http.Error(w, "Invalid type: must be 'file' or 'directory'", http.StatusBadRequest)
-		return
-	}
-
-	if req.Path == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
-		return
-	}
-
-	// Perform ingestion based on type
-	ctx := r.Context()
-	var stats *ingestion.IngestionStats
-	var err error
-
-	switch req.Type {
-	case "file":
-		log.Printf("Starting file ingestion: %s", req.Path)
-		stats, err = s.ingestionService.IngestFile(ctx, req.Path)
-	case "directory":
-		log.Printf("Starting directory ingestion: %s", req.Path)
-		stats, err = s.ingestionService.IngestDirectory(ctx, req.Path)
-	}
-
-	// Prepare response
-	var response ingestion.IngestResponse
-	if err != nil {
-		log.Printf("Ingestion error: %v", err)
-		response = ingestion.IngestResponse{
-			Success: false,
-			Stats:   make(map[string]interface{}),
-			Errors:  []string{err.Error()},
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-	} else {
-		// Convert errors to string array
-		errorStrings := make([]string, 0, len(stats.Errors))
-		for _, e := range stats.Errors {
-			errorStrings = append(errorStrings, e.Error())
-		}
-
-		response = ingestion.IngestResponse{
-			Success: true,
-			Stats:   stats.GetSummary(),
-			Errors:  errorStrings,
-		}
-
-		log.Printf("Ingestion completed successfully. Stats: %+v", stats.GetSummary())
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response) //nolint:errcheck // Response write errors are handled by HTTP framework
-}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/ollama"
+)
+
+// WarmupStatus reports the outcome of the startup model warmup
+type WarmupStatus struct {
+	Done       bool                   `json:"done"`
+	StartedAt  time.Time              `json:"started_at,omitempty"`
+	FinishedAt time.Time              `json:"finished_at,omitempty"`
+	Results    []ollama.PreloadResult `json:"results,omitempty"`
+}
+
+// modelWarmup records the most recent warmup run
+type modelWarmup struct {
+	status WarmupStatus
+	mu     sync.RWMutex
+}
+
+// get returns a snapshot of the warmup status
+func (m *modelWarmup) get() WarmupStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// warmupModels loads the configured chat and embedding models on every
+// Ollama node so the first user request does not pay the model load time
+func (s *Server) warmupModels(ctx context.Context) {
+	cfg := s.config.Ollama
+
+	reqs := []ollama.PreloadRequest{
+		{Model: cfg.ChatModel, KeepAlive: cfg.KeepAlive},
+	}
+	if cfg.EmbeddingModel != cfg.ChatModel {
+		reqs = append(reqs, ollama.PreloadRequest{
+			Model:     cfg.EmbeddingModel,
+			Embedding: true,
+			KeepAlive: cfg.KeepAlive,
+		})
+	}
+
+	s.warmup.mu.Lock()
+	s.warmup.status = WarmupStatus{StartedAt: time.Now()}
+	s.warmup.mu.Unlock()
+
+	results := s.ollamaPool.Preload(ctx, reqs...)
+	for _, result := range results {
+		if result.Error != "" {
+			log.Printf("Failed to warm up model %s on %s: %s", result.Model, result.Node, result.Error)
+			continue
+		}
+		log.Printf("Model %s warmed up on %s (load time %v)", result.Model, result.Node, result.LoadDuration)
+	}
+
+	s.warmup.mu.Lock()
+	s.warmup.status.Done = true
+	s.warmup.status.FinishedAt = time.Now()
+	s.warmup.status.Results = results
+	s.warmup.mu.Unlock()
+}
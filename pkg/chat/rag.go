@@ -3,10 +3,12 @@ package chat
 import (
 	"context"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 
 	"github.com/testsabirweb/connect_llm/pkg/embeddings"
+	"github.com/testsabirweb/connect_llm/pkg/secrets"
 	"github.com/testsabirweb/connect_llm/pkg/vector"
 )
 
@@ -18,8 +20,16 @@ type RAGConfig struct {
 	ChunkSize       int     // Approximate size of each chunk in tokens
 	IncludeMetadata bool    // Whether to include document metadata
 	DiversityFactor float64 // Factor for result diversity (0-1)
+	// EngagementBoost is added to the score of documents in proportion to
+	// their engagement, so pinned messages and answers with many reactions
+	// or replies surface first. Zero disables it.
+	EngagementBoost float64
 }
 
+// engagementSaturation is the number of reactions and replies at which a
+// document counts as fully engaged
+const engagementSaturation = 20
+
 // DefaultRAGConfig returns default RAG configuration
 func DefaultRAGConfig() RAGConfig {
 	return RAGConfig{
@@ -37,6 +47,9 @@ type RAGRetriever struct {
 	vectorClient vector.Client
 	embedder     *embeddings.OllamaEmbedder
 	config       RAGConfig
+	// secrets screens retrieved documents, so credentials indexed before
+	// ingestion quarantined them never reach a prompt
+	secrets *secrets.Scanner
 }
 
 // NewRAGRetriever creates a new RAG retriever
@@ -50,6 +63,7 @@ func NewRAGRetriever(vectorClient vector.Client, embedder *embeddings.OllamaEmbe
 		vectorClient: vectorClient,
 		embedder:     embedder,
 		config:       cfg,
+		secrets:      secrets.Default(),
 	}
 }
 
@@ -94,6 +108,8 @@ func (r *RAGRetriever) RetrieveContext(ctx context.Context, query string, filter
 		return nil, fmt.Errorf("failed to search documents: %w", err)
 	}
 
+	documents, withheld := r.withholdSecrets(documents)
+
 	// Process and rank results
 	results := r.processResults(documents, query)
 
@@ -104,10 +120,26 @@ func (r *RAGRetriever) RetrieveContext(ctx context.Context, query string, filter
 
 	// Build context within token limits
 	context := r.buildContext(query, results)
+	context.Metadata["withheld_documents"] = withheld
 
 	return context, nil
 }
 
+// withholdSecrets drops documents whose content, raw content or title
+// holds a secret, returning the rest and how many were dropped
+func (r *RAGRetriever) withholdSecrets(documents []vector.Document) ([]vector.Document, int) {
+	kept := documents[:0:0]
+	for _, doc := range documents {
+		if len(r.secrets.Scan(doc.Content)) > 0 ||
+			len(r.secrets.Scan(doc.RawContent)) > 0 ||
+			len(r.secrets.Scan(doc.Metadata.Title)) > 0 {
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	return kept, len(documents) - len(kept)
+}
+
 // processResults processes and ranks search results
 func (r *RAGRetriever) processResults(documents []vector.Document, query string) []RetrievalResult {
 	results := make([]RetrievalResult, 0, len(documents))
@@ -120,6 +152,11 @@ func (r *RAGRetriever) processResults(documents []vector.Document, query string)
 			continue
 		}
 
+		// Engagement reorders relevant documents but never lets an
+		// irrelevant one past MinScore, so the boost may take the score
+		// above 1
+		score += r.config.EngagementBoost * engagementScore(doc.Metadata)
+
 		// Estimate token count
 		tokenCount := r.estimateTokens(doc.Content)
 
@@ -140,7 +177,7 @@ func (r *RAGRetriever) processResults(documents []vector.Document, query string)
 	}
 
 	// Sort by score descending
-	sort.Slice(results, func(i, j int) bool {
+	sort.SliceStable(results, func(i, j int) bool {
 		return results[i].Score > results[j].Score
 	})
 
@@ -185,6 +222,20 @@ func (r *RAGRetriever) calculateRelevance(doc vector.Document, query string) flo
 	return finalScore
 }
 
+// engagementScore rates a document's engagement from 0 to 1. Pinned
+// documents score 1; otherwise reactions and replies count on a log scale,
+// so the first few matter most.
+func engagementScore(metadata vector.DocumentMetadata) float64 {
+	if metadata.Pinned {
+		return 1
+	}
+	count := metadata.ReactionCount + metadata.ReplyCount
+	if count <= 0 {
+		return 0
+	}
+	return math.Min(1, math.Log1p(float64(count))/math.Log1p(engagementSaturation))
+}
+
 // applyDiversity ensures diverse results
 func (r *RAGRetriever) applyDiversity(results []RetrievalResult) []RetrievalResult {
 	if len(results) <= r.config.MaxDocuments {
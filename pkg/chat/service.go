@@ -3,6 +3,7 @@ package chat
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -15,13 +16,20 @@ import (
 	"github.com/testsabirweb/connect_llm/pkg/vector"
 )
 
+// LLMClient generates chat completions. It is satisfied by both
+// *ollama.Client and *ollama.Pool.
+type LLMClient interface {
+	Chat(ctx context.Context, req ollama.ChatRequest) (*ollama.ChatResponse, error)
+	ChatStream(ctx context.Context, req ollama.ChatRequest) (<-chan ollama.StreamResponse, <-chan error)
+}
+
 // Service represents the main chat service
 type Service struct {
 	hub                 *Hub
 	conversationManager *ConversationManager
 	ragRetriever        *RAGRetriever
 	promptBuilder       *PromptBuilder
-	ollamaClient        *ollama.Client
+	ollamaClient        LLMClient
 	embedder            *embeddings.OllamaEmbedder
 	config              ServiceConfig
 	mu                  sync.RWMutex
@@ -31,12 +39,19 @@ type Service struct {
 type ServiceConfig struct {
 	OllamaURL         string
 	OllamaModel       string
+	EmbeddingModel    string // Defaults to OllamaModel
+	KeepAlive         string // keep_alive sent with chat and embedding requests
+	OllamaTimeouts    ollama.Timeouts
+	OllamaPool        *ollama.Pool // When set, used instead of OllamaURL for chat and embeddings
 	MaxResponseTokens int
 	StreamingEnabled  bool
 	IncludeCitations  bool
 	Temperature       float64
 	EnableRAG         bool
 	MinRAGScore       float64
+	// RAGEngagementBoost ranks engaged documents higher; see
+	// RAGConfig.EngagementBoost
+	RAGEngagementBoost float64
 }
 
 // DefaultServiceConfig returns default service configuration
@@ -44,6 +59,7 @@ func DefaultServiceConfig() ServiceConfig {
 	return ServiceConfig{
 		OllamaURL:         "http://localhost:11434",
 		OllamaModel:       "llama3:8b",
+		OllamaTimeouts:    ollama.DefaultTimeouts(),
 		MaxResponseTokens: 2000,
 		StreamingEnabled:  true,
 		IncludeCitations:  true,
@@ -59,15 +75,27 @@ func NewService(
 	vectorClient vector.Client,
 	config ServiceConfig,
 ) *Service {
-	// Create Ollama client
-	ollamaClient := ollama.NewClient(config.OllamaURL)
+	// Create Ollama client and embedder, load-balanced when a pool is configured
+	embeddingModel := config.EmbeddingModel
+	if embeddingModel == "" {
+		embeddingModel = config.OllamaModel
+	}
 
-	// Create embedder
-	embedder := embeddings.NewOllamaEmbedder(config.OllamaURL, config.OllamaModel)
+	var ollamaClient LLMClient
+	var embedder *embeddings.OllamaEmbedder
+	if config.OllamaPool != nil {
+		ollamaClient = config.OllamaPool
+		embedder = embeddings.NewOllamaEmbedderWithPool(config.OllamaPool, embeddingModel)
+	} else {
+		ollamaClient = ollama.NewClient(config.OllamaURL, config.OllamaTimeouts)
+		embedder = embeddings.NewOllamaEmbedder(config.OllamaURL, embeddingModel)
+	}
+	embedder.SetKeepAlive(config.KeepAlive)
 
 	// Create RAG retriever
 	ragConfig := RAGConfig{
-		MinScore: config.MinRAGScore,
+		MinScore:        config.MinRAGScore,
+		EngagementBoost: config.RAGEngagementBoost,
 	}
 	ragRetriever := NewRAGRetriever(vectorClient, embedder, ragConfig)
 
@@ -173,6 +201,7 @@ func (s *Service) streamResponse(
 			Temperature: s.config.Temperature,
 			NumPredict:  s.config.MaxResponseTokens,
 		},
+		KeepAlive: s.config.KeepAlive,
 	}
 
 	// Start streaming
@@ -181,6 +210,7 @@ func (s *Service) streamResponse(
 	// Create response message ID
 	responseID := uuid.New().String()
 	var fullResponse strings.Builder
+	var metrics *GenerationMetrics
 
 	// Send initial streaming message
 	if !s.safeSend(client, Message{
@@ -209,7 +239,7 @@ func (s *Service) streamResponse(
 			}
 
 			// Send final message
-			s.sendStreamingComplete(client, messageID, responseID, fullResponse.String())
+			s.sendStreamingComplete(client, messageID, responseID, fullResponse.String(), metrics)
 
 			// Save assistant message to conversation
 			assistantMsg := ConversationMessage{
@@ -264,12 +294,13 @@ func (s *Service) streamResponse(
 				}
 
 				if chunk.Done {
+					metrics = newGenerationMetrics(chunk.Metrics)
 					return
 				}
 
 			case err := <-errChan:
 				if err != nil {
-					s.sendError(client, messageID, fmt.Sprintf("Streaming error: %v", err))
+					s.sendGenerationError(client, messageID, "Streaming error", err)
 					return
 				}
 			}
@@ -277,6 +308,18 @@ func (s *Service) streamResponse(
 	}()
 }
 
+// newGenerationMetrics converts Ollama's response metrics
+func newGenerationMetrics(m ollama.Metrics) *GenerationMetrics {
+	return &GenerationMetrics{
+		LoadMs:           m.LoadDuration.Milliseconds(),
+		PromptEvalMs:     m.PromptEvalDuration.Milliseconds(),
+		EvalMs:           m.EvalDuration.Milliseconds(),
+		TotalMs:          m.TotalDuration.Milliseconds(),
+		PromptTokens:     m.PromptEvalCount,
+		CompletionTokens: m.EvalCount,
+	}
+}
+
 // Helper function to marshal JSON without error handling
 func mustMarshal(v interface{}) json.RawMessage {
 	data, _ := json.Marshal(v)
@@ -302,12 +345,13 @@ func (s *Service) generateResponse(
 			Temperature: s.config.Temperature,
 			NumPredict:  s.config.MaxResponseTokens,
 		},
+		KeepAlive: s.config.KeepAlive,
 	}
 
 	// Generate response
 	resp, err := s.ollamaClient.Chat(ctx, chatReq)
 	if err != nil {
-		s.sendError(client, messageID, fmt.Sprintf("Failed to generate response: %v", err))
+		s.sendGenerationError(client, messageID, "Failed to generate response", err)
 		return
 	}
 
@@ -337,7 +381,7 @@ func (s *Service) generateResponse(
 	}
 
 	// Send response to client
-	s.sendResponse(client, messageID, responseID, resp.Message.Content)
+	s.sendResponse(client, messageID, responseID, resp.Message.Content, newGenerationMetrics(resp.Metrics))
 }
 
 // Helper methods for sending messages
@@ -378,10 +422,34 @@ func (s *Service) sendError(client *Client, messageID, error string) {
 	s.safeSend(client, errorMsg)
 }
 
-func (s *Service) sendResponse(client *Client, requestID, responseID, content string) {
-	respData, _ := json.Marshal(map[string]string{
+// sendGenerationError reports an LLM failure, using a typed timeout error
+// when Ollama stalled so clients can tell it apart from other failures
+func (s *Service) sendGenerationError(client *Client, messageID, prefix string, err error) {
+	var timeoutErr *ollama.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		s.sendError(client, messageID, fmt.Sprintf("%s: %v", prefix, err))
+		return
+	}
+
+	errorMsg := Message{
+		Type:  MessageTypeError,
+		ID:    messageID,
+		Code:  ErrorCodeTimeout,
+		Error: timeoutErr.Error(),
+		Metadata: mustMarshal(TimeoutErrorDetails{
+			Stage:     string(timeoutErr.Stage),
+			TimeoutMs: timeoutErr.After.Milliseconds(),
+		}),
+		Timestamp: time.Now(),
+	}
+	s.safeSend(client, errorMsg)
+}
+
+func (s *Service) sendResponse(client *Client, requestID, responseID, content string, metrics *GenerationMetrics) {
+	respData, _ := json.Marshal(map[string]interface{}{
 		"response_id": responseID,
 		"content":     content,
+		"metrics":     metrics,
 	})
 
 	responseMsg := Message{
@@ -394,11 +462,12 @@ func (s *Service) sendResponse(client *Client, requestID, responseID, content st
 	s.safeSend(client, responseMsg)
 }
 
-func (s *Service) sendStreamingComplete(client *Client, requestID, responseID, fullContent string) {
+func (s *Service) sendStreamingComplete(client *Client, requestID, responseID, fullContent string, metrics *GenerationMetrics) {
 	streamData, _ := json.Marshal(StreamingResponse{
 		MessageID: responseID,
 		Chunk:     "",
 		Done:      true,
+		Metrics:   metrics,
 	})
 
 	msg := Message{
@@ -38,12 +38,21 @@ const (
 	MessageTypeCitation  MessageType = "citation"
 )
 
+// ErrorCode classifies error messages sent to clients
+type ErrorCode string
+
+const (
+	// ErrorCodeTimeout means the LLM did not respond within a stage timeout
+	ErrorCodeTimeout ErrorCode = "timeout"
+)
+
 // Message represents a WebSocket message
 type Message struct {
 	Type      MessageType     `json:"type"`
 	ID        string          `json:"id"`
 	Content   string          `json:"content,omitempty"`
 	Error     string          `json:"error,omitempty"`
+	Code      ErrorCode       `json:"code,omitempty"`
 	Metadata  json.RawMessage `json:"metadata,omitempty"`
 	Timestamp time.Time       `json:"timestamp"`
 }
@@ -57,9 +66,28 @@ type ChatMessage struct {
 
 // StreamingResponse represents a streaming response chunk
 type StreamingResponse struct {
-	Chunk     string `json:"chunk"`
-	Done      bool   `json:"done"`
-	MessageID string `json:"message_id"`
+	Chunk     string             `json:"chunk"`
+	Done      bool               `json:"done"`
+	MessageID string             `json:"message_id"`
+	Metrics   *GenerationMetrics `json:"metrics,omitempty"` // Only on the final message
+}
+
+// GenerationMetrics reports where the time for a response went. LoadMs is
+// reported separately so clients can tell a cold model load apart from
+// slow generation.
+type GenerationMetrics struct {
+	LoadMs           int64 `json:"load_ms"`
+	PromptEvalMs     int64 `json:"prompt_eval_ms"`
+	EvalMs           int64 `json:"eval_ms"`
+	TotalMs          int64 `json:"total_ms"`
+	PromptTokens     int   `json:"prompt_tokens"`
+	CompletionTokens int   `json:"completion_tokens"`
+}
+
+// TimeoutErrorDetails describes which stage of an LLM request timed out
+type TimeoutErrorDetails struct {
+	Stage     string `json:"stage"` // connect, first_token or idle
+	TimeoutMs int64  `json:"timeout_ms"`
 }
 
 // CitationResponse represents document citations
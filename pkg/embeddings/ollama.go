@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/ollama"
 )
 
 // OllamaEmbedder handles embedding generation using Ollama
@@ -14,6 +16,10 @@ type OllamaEmbedder struct {
 	client  *http.Client
 	baseURL string
 	model   string
+	pool    *ollama.Pool
+
+	// keepAlive is forwarded as keep_alive on every request
+	keepAlive string
 }
 
 // NewOllamaEmbedder creates a new Ollama embedder
@@ -27,10 +33,31 @@ func NewOllamaEmbedder(baseURL, model string) *OllamaEmbedder {
 	}
 }
 
+// NewOllamaEmbedderWithPool creates an embedder that load-balances requests
+// across the nodes of an Ollama pool
+func NewOllamaEmbedderWithPool(pool *ollama.Pool, model string) *OllamaEmbedder {
+	return &OllamaEmbedder{
+		model: model,
+		pool:  pool,
+	}
+}
+
+// SetKeepAlive sets how long Ollama keeps the embedding model loaded after
+// each request (e.g. "30m", or "-1" to keep it loaded)
+func (e *OllamaEmbedder) SetKeepAlive(keepAlive string) {
+	e.keepAlive = keepAlive
+}
+
+// Model returns the embedding model name
+func (e *OllamaEmbedder) Model() string {
+	return e.model
+}
+
 // EmbedRequest represents the request to Ollama embed API
 type EmbedRequest struct {
-	Model string `json:"model"`
-	Input string `json:"input"`
+	Model     string `json:"model"`
+	Input     string `json:"input"`
+	KeepAlive string `json:"keep_alive,omitempty"`
 }
 
 // EmbedResponse represents the response from Ollama embed API
@@ -45,9 +72,14 @@ func (e *OllamaEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]
 		return nil, fmt.Errorf("text cannot be empty")
 	}
 
+	if e.pool != nil {
+		return e.generateWithPool(ctx, text)
+	}
+
 	req := EmbedRequest{
-		Model: e.model,
-		Input: text,
+		Model:     e.model,
+		Input:     text,
+		KeepAlive: e.keepAlive,
 	}
 
 	jsonData, err := json.Marshal(req)
@@ -84,6 +116,24 @@ func (e *OllamaEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]
 	return embedResp.Embeddings[0], nil
 }
 
+// generateWithPool generates an embedding on the least loaded pool node
+func (e *OllamaEmbedder) generateWithPool(ctx context.Context, text string) ([]float32, error) {
+	embedResp, err := e.pool.Embed(ctx, ollama.EmbedRequest{
+		Model:     e.model,
+		Input:     text,
+		KeepAlive: e.keepAlive,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(embedResp.Embeddings) == 0 || len(embedResp.Embeddings[0]) == 0 {
+		return nil, fmt.Errorf("no embeddings returned")
+	}
+
+	return embedResp.Embeddings[0], nil
+}
+
 // GenerateEmbeddings generates embeddings for multiple texts
 func (e *OllamaEmbedder) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
 	embeddings := make([][]float32, len(texts))
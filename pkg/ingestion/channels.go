@@ -0,0 +1,138 @@
+package ingestion
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// Channel metadata files in a Slack export
+const (
+	ChannelsFile       = "channels.csv"
+	ChannelMembersFile = "channel_members.csv"
+)
+
+// ChannelDirectory maps Slack channel IDs to channel metadata and members
+type ChannelDirectory struct {
+	channels map[string]models.SlackChannel
+}
+
+// NewChannelDirectory creates a directory holding the given channels
+func NewChannelDirectory(channels ...models.SlackChannel) *ChannelDirectory {
+	dir := &ChannelDirectory{channels: make(map[string]models.SlackChannel, len(channels))}
+	for _, channel := range channels {
+		dir.channels[channel.ID] = channel
+	}
+	return dir
+}
+
+// LoadChannels reads channels.csv and, if membersPath is not empty, the
+// channel_members.csv listing who belongs to each channel
+func LoadChannels(channelsPath, membersPath string) (*ChannelDirectory, error) {
+	file, err := os.Open(channelsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channels file: %w", err)
+	}
+	defer file.Close()
+
+	dir, err := ParseChannels(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", channelsPath, err)
+	}
+
+	if membersPath == "" {
+		return dir, nil
+	}
+
+	members, err := os.Open(membersPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel members file: %w", err)
+	}
+	defer members.Close()
+
+	if err := dir.ParseMembers(members); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", membersPath, err)
+	}
+	return dir, nil
+}
+
+// ParseChannels reads channels from CSV data with at least an id column.
+// Exports that leave id empty use channel_id instead.
+func ParseChannels(r io.Reader) (*ChannelDirectory, error) {
+	dir := NewChannelDirectory()
+	err := readTable(r, []string{"id"}, func(field func(string) string) error {
+		id := field("id")
+		if id == "" {
+			id = field("channel_id")
+		}
+		if id == "" {
+			return nil
+		}
+		dir.channels[id] = models.SlackChannel{
+			ID:         id,
+			Name:       field("name"),
+			IsPrivate:  parseBool(field("is_private")),
+			IsArchived: parseBool(field("is_archived")),
+			IsGeneral:  parseBool(field("is_general")),
+			Purpose:    field("purpose__value"),
+			Topic:      field("topic__value"),
+			Members:    parseJSONArrayString(field("members")),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dir, nil
+}
+
+// ParseMembers adds channel memberships from CSV data with channel_id and
+// user_id columns. Memberships of unknown channels are ignored.
+func (d *ChannelDirectory) ParseMembers(r io.Reader) error {
+	seen := make(map[string]map[string]bool)
+	for id, channel := range d.channels {
+		seen[id] = make(map[string]bool, len(channel.Members))
+		for _, member := range channel.Members {
+			seen[id][member] = true
+		}
+	}
+
+	return readTable(r, []string{"channel_id", "user_id"}, func(field func(string) string) error {
+		id, user := field("channel_id"), field("user_id")
+		channel, ok := d.channels[id]
+		if !ok || user == "" || seen[id][user] {
+			return nil
+		}
+		seen[id][user] = true
+		channel.Members = append(channel.Members, user)
+		d.channels[id] = channel
+		return nil
+	})
+}
+
+// merge returns d with the channels of other added, creating d if it is
+// nil
+func (d *ChannelDirectory) merge(other *ChannelDirectory) *ChannelDirectory {
+	if d == nil {
+		d = NewChannelDirectory()
+	}
+	if other != nil {
+		for id, channel := range other.channels {
+			d.channels[id] = channel
+		}
+	}
+	return d
+}
+
+// Lookup returns the channel with the given ID
+func (d *ChannelDirectory) Lookup(id string) (models.SlackChannel, bool) {
+	channel, ok := d.channels[id]
+	return channel, ok
+}
+
+// Len returns the number of channels in the directory
+func (d *ChannelDirectory) Len() int {
+	return len(d.channels)
+}
@@ -0,0 +1,96 @@
+package ingestion
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// channelAwareMockProcessor records the channel directory it was given
+type channelAwareMockProcessor struct {
+	mockDocumentProcessor
+	channels *ChannelDirectory
+}
+
+func (m *channelAwareMockProcessor) SetChannelDirectory(channels *ChannelDirectory) {
+	m.channels = channels
+}
+
+const testChannelsCSV = `channel_id,id,is_archived,is_general,is_private,members,name,purpose__value,topic__value
+,C8GMCQ10D,True,,,,geospatial,,
+,CT43EC37H,,True,,,general,Company-wide announcements,Say hi
+,GPRIVATE1,,,True,"[""U1""]",superset-champions,,
+,,,,,,nameless,,`
+
+const testChannelMembersCSV = `channel_id,user_id
+GPRIVATE1,U1
+GPRIVATE1,U2
+CT43EC37H,U3
+CUNKNOWN,U4`
+
+func TestParseChannels(t *testing.T) {
+	channels, err := ParseChannels(strings.NewReader(testChannelsCSV))
+	if err != nil {
+		t.Fatalf("ParseChannels() error = %v", err)
+	}
+	if err := channels.ParseMembers(strings.NewReader(testChannelMembersCSV)); err != nil {
+		t.Fatalf("ParseMembers() error = %v", err)
+	}
+	if channels.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", channels.Len())
+	}
+
+	general, ok := channels.Lookup("CT43EC37H")
+	if !ok {
+		t.Fatal("general channel not found")
+	}
+	if general.DisplayName() != "#general" || !general.IsGeneral || general.Purpose != "Company-wide announcements" || general.Topic != "Say hi" {
+		t.Errorf("unexpected general channel: %+v", general)
+	}
+
+	archived, _ := channels.Lookup("C8GMCQ10D")
+	if !archived.IsArchived || archived.IsPrivate {
+		t.Errorf("unexpected archived channel: %+v", archived)
+	}
+
+	// Members listed in channels.csv are merged with channel_members.csv
+	private, _ := channels.Lookup("GPRIVATE1")
+	if !private.IsPrivate || !reflect.DeepEqual(private.Members, []string{"U1", "U2"}) {
+		t.Errorf("unexpected private channel: %+v", private)
+	}
+}
+
+func TestIngestDirectory_LoadsChannels(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		ChannelsFile:         testChannelsCSV,
+		ChannelMembersFile:   testChannelMembersCSV,
+		"users_channels.csv": "name,user_id\ngeneral,U3\n",
+		"exported_stats.csv": "date,total_membership\n2020-01-01,8\n",
+		"messages.csv": "channel_id,text,ts,type,user\n" +
+			"GPRIVATE1,Secret plans,1599934232.150700,message,U1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	processor := &channelAwareMockProcessor{}
+	stats, err := NewService(&mockVectorClient{}, processor).IngestDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("IngestDirectory() error = %v", err)
+	}
+	if processor.channels == nil {
+		t.Fatal("processor was not given the channel directory")
+	}
+	if private, _ := processor.channels.Lookup("GPRIVATE1"); len(private.Members) != 2 {
+		t.Errorf("channel members were not loaded: %+v", private)
+	}
+	if stats.ProcessedMessages != 1 || len(stats.Errors) != 0 {
+		t.Errorf("processed %d messages with errors %v; metadata tables should not be ingested", stats.ProcessedMessages, stats.Errors)
+	}
+}
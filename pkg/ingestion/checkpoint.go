@@ -0,0 +1,366 @@
+package ingestion
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// checkpointInterval bounds how often a file's progress is written to disk
+const checkpointInterval = 5 * time.Second
+
+// IngestionState is the persisted progress of ingestion runs
+type IngestionState struct {
+	Files     map[string]*FileCheckpoint    `json:"files"`
+	Channels  map[string]*ChannelCheckpoint `json:"channels"`
+	UpdatedAt time.Time                     `json:"updated_at"`
+}
+
+// FileCheckpoint records how far a file has been ingested. Offset counts
+// the messages read from the file, in parser order, whose batches have all
+// been stored.
+type FileCheckpoint struct {
+	Hash      string    `json:"hash"`
+	Offset    int       `json:"offset"`
+	Completed bool      `json:"completed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChannelCheckpoint records the newest message of a channel ingested by a
+// completed run
+type ChannelCheckpoint struct {
+	HighWaterMark   time.Time `json:"high_water_mark"`
+	HighWaterMarkTS string    `json:"high_water_mark_ts,omitempty"`
+}
+
+// StateStore persists IngestionState as a JSON file. It is safe for
+// concurrent use.
+type StateStore struct {
+	path  string
+	state IngestionState
+	mu    sync.Mutex
+}
+
+// OpenStateStore loads the state at path, starting empty if the file does
+// not exist yet
+func OpenStateStore(path string) (*StateStore, error) {
+	store := &StateStore{path: path, state: newIngestionState()}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ingestion state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &store.state); err != nil {
+		return nil, fmt.Errorf("failed to parse ingestion state %s: %w", path, err)
+	}
+	if store.state.Files == nil {
+		store.state.Files = make(map[string]*FileCheckpoint)
+	}
+	if store.state.Channels == nil {
+		store.state.Channels = make(map[string]*ChannelCheckpoint)
+	}
+	return store, nil
+}
+
+func newIngestionState() IngestionState {
+	return IngestionState{
+		Files:    make(map[string]*FileCheckpoint),
+		Channels: make(map[string]*ChannelCheckpoint),
+	}
+}
+
+// Path returns the file the state is persisted to
+func (s *StateStore) Path() string {
+	return s.path
+}
+
+// Reset discards all recorded progress, so the next run ingests everything
+func (s *StateStore) Reset() error {
+	s.mu.Lock()
+	s.state = newIngestionState()
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// Save writes the state to disk. The file is replaced atomically so a crash
+// never leaves a truncated state behind.
+func (s *StateStore) Save() error {
+	s.mu.Lock()
+	s.state.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode ingestion state: %w", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write ingestion state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write ingestion state: %w", err)
+	}
+	return nil
+}
+
+// File returns a copy of the checkpoint recorded for a file
+func (s *StateStore) File(path string) (FileCheckpoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.state.Files[path]
+	if !ok {
+		return FileCheckpoint{}, false
+	}
+	return *cp, true
+}
+
+// Channel returns a copy of the checkpoint recorded for a channel
+func (s *StateStore) Channel(id string) (ChannelCheckpoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.state.Channels[id]
+	if !ok {
+		return ChannelCheckpoint{}, false
+	}
+	return *cp, true
+}
+
+// highWaterMarks returns a snapshot of every channel's high-water mark
+func (s *StateStore) highWaterMarks() map[string]time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	marks := make(map[string]time.Time, len(s.state.Channels))
+	for id, cp := range s.state.Channels {
+		marks[id] = cp.HighWaterMark
+	}
+	return marks
+}
+
+// setFile records a file's progress
+func (s *StateStore) setFile(path string, cp FileCheckpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp.UpdatedAt = time.Now()
+	s.state.Files[path] = &cp
+}
+
+// raiseChannel moves a channel's high-water mark forward
+func (s *StateStore) raiseChannel(id string, mark ChannelCheckpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cp, ok := s.state.Channels[id]; ok && !mark.HighWaterMark.After(cp.HighWaterMark) {
+		return
+	}
+	s.state.Channels[id] = &mark
+}
+
+// runCheckpoint tracks one ingestion run over one or more files. Channel
+// high-water marks are only raised when the whole run completes, so files
+// of the same run that share channels, such as messages.csv and
+// threads.csv, do not hide each other's messages after a crash.
+type runCheckpoint struct {
+	store *StateStore
+	// marks are the high-water marks of earlier completed runs
+	marks map[string]time.Time
+
+	latest map[string]ChannelCheckpoint
+	mu     sync.Mutex
+}
+
+// newRunCheckpoint starts a run, or returns nil if store is nil
+func newRunCheckpoint(store *StateStore) *runCheckpoint {
+	if store == nil {
+		return nil
+	}
+	return &runCheckpoint{
+		store:  store,
+		marks:  store.highWaterMarks(),
+		latest: make(map[string]ChannelCheckpoint),
+	}
+}
+
+// seen records a message read during the run
+func (r *runCheckpoint) seen(msg models.SlackMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if mark, ok := r.latest[msg.Channel]; ok && !msg.Timestamp.After(mark.HighWaterMark) {
+		return
+	}
+	r.latest[msg.Channel] = ChannelCheckpoint{HighWaterMark: msg.Timestamp, HighWaterMarkTS: msg.TS}
+}
+
+// unchanged reports whether an earlier run already ingested the message:
+// it is no newer than its channel's high-water mark and was not edited
+// after it
+func (r *runCheckpoint) unchanged(msg models.SlackMessage) bool {
+	mark, ok := r.marks[msg.Channel]
+	if !ok || msg.Timestamp.After(mark) {
+		return false
+	}
+	if msg.Edited != "" {
+		if edited, err := parseSlackTimestamp(msg.Edited); err == nil && edited.After(mark) {
+			return false
+		}
+	}
+	return true
+}
+
+// complete raises the channel high-water marks and saves the state
+func (r *runCheckpoint) complete() error {
+	r.mu.Lock()
+	for id, mark := range r.latest {
+		r.store.raiseChannel(id, mark)
+	}
+	r.mu.Unlock()
+	return r.store.Save()
+}
+
+// fileCheckpoint tracks the ingestion of one file within a run. Batches may
+// be stored out of order by the workers, so the saved offset only advances
+// over a contiguous prefix of completed batches.
+type fileCheckpoint struct {
+	run  *runCheckpoint
+	path string
+	hash string
+	// resumeFrom is the number of leading messages stored by an
+	// interrupted run of the same file
+	resumeFrom int
+	// read counts the messages read so far in this run; it is only
+	// touched by the parsing goroutine
+	read int
+
+	nextBatch int
+	pending   map[int]int
+	confirmed int
+	lastSave  time.Time
+	mu        sync.Mutex
+}
+
+// startFile begins tracking a file, or returns nil if the run is not
+// checkpointed. A file whose content is unchanged since an earlier run
+// resumes where that run stopped; a completed file is still read, so its
+// messages count towards threads and high-water marks, but nothing in it is
+// ingested again.
+func (r *runCheckpoint) startFile(path string) (*fileCheckpoint, error) {
+	return r.startExportFile(ExportFile{Path: path})
+}
+
+// startExportFile begins tracking a file found by DiscoverFiles. Files
+// within a ZIP archive are hashed with the whole archive, so they only
+// resume while the archive is unchanged.
+func (r *runCheckpoint) startExportFile(file ExportFile) (*fileCheckpoint, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	key, err := filepath.Abs(file.Name())
+	if err != nil {
+		key = file.Name()
+	}
+	hash, err := hashFile(file.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &fileCheckpoint{run: r, path: key, hash: hash, pending: make(map[int]int)}
+	if saved, ok := r.store.File(key); ok && saved.Hash == hash {
+		cp.resumeFrom = saved.Offset
+	}
+	return cp, nil
+}
+
+// alreadyStored reports whether an earlier run stored the message. It must
+// be called for every message read from the file, in order.
+func (c *fileCheckpoint) alreadyStored(msg models.SlackMessage) bool {
+	if c == nil {
+		return false
+	}
+
+	c.read++
+	c.run.seen(msg)
+	return c.read <= c.resumeFrom || c.run.unchanged(msg)
+}
+
+// batchDone records that batch batchNum, which held size messages as read
+// from the file, has been stored. Progress is saved periodically.
+func (c *fileCheckpoint) batchDone(batchNum, size int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.pending[batchNum] = size
+	for {
+		n, ok := c.pending[c.nextBatch]
+		if !ok {
+			break
+		}
+		delete(c.pending, c.nextBatch)
+		c.nextBatch++
+		c.confirmed += n
+	}
+	save := time.Since(c.lastSave) >= checkpointInterval
+	c.mu.Unlock()
+
+	if save {
+		c.save() //nolint:errcheck // A failed periodic save is retried at the next checkpoint
+	}
+}
+
+// save persists the file's progress
+func (c *fileCheckpoint) save() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.lastSave = time.Now()
+	// Messages before the resume point were confirmed by the earlier run
+	offset := max(c.confirmed, c.resumeFrom)
+	c.mu.Unlock()
+
+	c.run.store.setFile(c.path, FileCheckpoint{Hash: c.hash, Offset: offset})
+	return c.run.store.Save()
+}
+
+// finish records the file as fully ingested
+func (c *fileCheckpoint) finish() error {
+	if c == nil {
+		return nil
+	}
+	c.run.store.setFile(c.path, FileCheckpoint{Hash: c.hash, Offset: c.read, Completed: true})
+	return c.run.store.Save()
+}
+
+// hashFile returns the hex SHA-256 of a file's content
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
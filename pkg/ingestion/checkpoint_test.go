@@ -0,0 +1,258 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+// recordingProcessor returns a thread-aware processor that records the
+// content of every message it processes
+func recordingProcessor(contents *[]string) *threadMockProcessor {
+	return &threadMockProcessor{mockDocumentProcessor: mockDocumentProcessor{
+		processFunc: func(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
+			*contents = append(*contents, msg.Content)
+			return []vector.Document{{ID: msg.MessageID, Content: msg.Content}}, nil
+		},
+	}}
+}
+
+func checkpointService(t *testing.T, processor DocumentProcessor, statePath string) *Service {
+	t.Helper()
+	state, err := OpenStateStore(statePath)
+	if err != nil {
+		t.Fatalf("OpenStateStore() error = %v", err)
+	}
+	cfg := DefaultServiceConfig()
+	cfg.BatchSize = 2
+	cfg.MaxConcurrency = 1
+	cfg.State = state
+	return NewService(&mockVectorClient{}, processor, cfg)
+}
+
+func writeCSV(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "ingest.json")
+
+	store, err := OpenStateStore(path)
+	if err != nil {
+		t.Fatalf("OpenStateStore() error = %v", err)
+	}
+	store.setFile("/data/messages.csv", FileCheckpoint{Hash: "abc", Offset: 42})
+	mark := time.Date(2020, 9, 12, 18, 10, 32, 0, time.UTC)
+	store.raiseChannel("C1", ChannelCheckpoint{HighWaterMark: mark, HighWaterMarkTS: "1599934232.150700"})
+	store.raiseChannel("C1", ChannelCheckpoint{HighWaterMark: mark.Add(-time.Hour)})
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reopened, err := OpenStateStore(path)
+	if err != nil {
+		t.Fatalf("OpenStateStore() error = %v", err)
+	}
+	if file, ok := reopened.File("/data/messages.csv"); !ok || file.Hash != "abc" || file.Offset != 42 || file.Completed {
+		t.Errorf("File() = %+v, %v", file, ok)
+	}
+	if channel, ok := reopened.Channel("C1"); !ok || !channel.HighWaterMark.Equal(mark) {
+		t.Errorf("high-water mark moved backwards: %+v", channel)
+	}
+
+	if err := reopened.Reset(); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if _, ok := reopened.File("/data/messages.csv"); ok {
+		t.Error("file checkpoint survived Reset()")
+	}
+	if _, ok := reopened.Channel("C1"); ok {
+		t.Error("channel checkpoint survived Reset()")
+	}
+}
+
+func TestFileCheckpoint_OutOfOrderBatches(t *testing.T) {
+	dir := t.TempDir()
+	file := writeCSV(t, dir, "messages.csv", "channel_id,text,ts,type,user\n")
+	store, err := OpenStateStore(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := newRunCheckpoint(store).startFile(file)
+	if err != nil {
+		t.Fatalf("startFile() error = %v", err)
+	}
+
+	offset := func() int {
+		t.Helper()
+		if err := cp.save(); err != nil {
+			t.Fatalf("save() error = %v", err)
+		}
+		saved, _ := store.File(cp.path)
+		return saved.Offset
+	}
+
+	// Batch 1 finishes first, but batch 0 may still fail
+	cp.batchDone(1, 10)
+	if got := offset(); got != 0 {
+		t.Errorf("offset after batch 1 = %d, want 0", got)
+	}
+	cp.batchDone(0, 5)
+	if got := offset(); got != 15 {
+		t.Errorf("offset after batches 0 and 1 = %d, want 15", got)
+	}
+	cp.batchDone(3, 5)
+	if got := offset(); got != 15 {
+		t.Errorf("offset with batch 2 missing = %d, want 15", got)
+	}
+}
+
+func TestIngestFile_Resume(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	file := writeCSV(t, dir, "messages.csv", `channel_id,text,ts,type,user
+C1,First,1599934232.000100,message,U1
+C1,Second,1599934233.000100,message,U1
+C1,Third,1599934234.000100,message,U2
+C1,Fourth,1599934235.000100,message,U2
+`)
+
+	// An earlier run stored the first batch and was interrupted
+	store, err := OpenStateStore(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp, err := newRunCheckpoint(store).startFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.batchDone(0, 2)
+	if err := cp.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var contents []string
+	stats, err := checkpointService(t, recordingProcessor(&contents), statePath).IngestFile(context.Background(), file)
+	if err != nil {
+		t.Fatalf("IngestFile() error = %v", err)
+	}
+	if want := []string{"Third", "Fourth"}; !reflect.DeepEqual(contents, want) {
+		t.Errorf("processed %v, want %v", contents, want)
+	}
+	if stats.UnchangedMessages != 2 || stats.ProcessedMessages != 2 {
+		t.Errorf("unchanged %d, processed %d; want 2 and 2", stats.UnchangedMessages, stats.ProcessedMessages)
+	}
+
+	// The completed file is not ingested again
+	contents = nil
+	if _, err := checkpointService(t, recordingProcessor(&contents), statePath).IngestFile(context.Background(), file); err != nil {
+		t.Fatalf("IngestFile() error = %v", err)
+	}
+	if len(contents) != 0 {
+		t.Errorf("completed file processed again: %v", contents)
+	}
+}
+
+func TestIngestFile_ResumeWithFilter(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	file := writeCSV(t, dir, "messages.csv", `channel_id,text,ts,type,subtype,user
+C1,joined,1599934232.000100,message,channel_join,U1
+C1,Second,1599934233.000100,message,,U1
+C1,Third,1599934234.000100,message,,U2
+C1,Fourth,1599934235.000100,message,,U2
+`)
+	filter, err := NewFilter(FilterConfig{Exclude: []FilterRule{{Subtypes: []string{"channel_join"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := func(processor DocumentProcessor) *Service {
+		svc := checkpointService(t, processor, statePath)
+		svc.filter = filter
+		return svc
+	}
+
+	// The first run fails to process the third message, so only the first
+	// batch, with the filtered join, is checkpointed
+	var contents []string
+	failing := recordingProcessor(&contents)
+	failing.processFunc = func(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
+		if msg.Content == "Third" {
+			return nil, errors.New("embedding failed")
+		}
+		contents = append(contents, msg.Content)
+		return []vector.Document{{ID: msg.MessageID, Content: msg.Content}}, nil
+	}
+	service(failing).IngestFile(context.Background(), file) //nolint:errcheck // The failure is expected
+	if want := []string{"Second", "Fourth"}; !reflect.DeepEqual(contents, want) {
+		t.Fatalf("first run processed %v, want %v", contents, want)
+	}
+
+	contents = nil
+	if _, err := service(recordingProcessor(&contents)).IngestFile(context.Background(), file); err != nil {
+		t.Fatalf("IngestFile() error = %v", err)
+	}
+	if want := []string{"Third", "Fourth"}; !reflect.DeepEqual(contents, want) {
+		t.Errorf("resumed run processed %v, want %v", contents, want)
+	}
+}
+
+func TestIngestFile_Incremental(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	header := "channel_id,text,ts,type,user,thread_ts,reply_count,reply_users,edited__ts\n"
+
+	first := writeCSV(t, dir, "export1.csv", header+
+		"C1,Release plan?,1599934232.150700,message,U1,1599934232.150700,1,\"[\"\"U2\"\"]\",\n"+
+		"C1,Ship it Monday,1599934240.150700,message,U2,1599934232.150700,0,[],\n"+
+		"C1,Typo here,1599934250.000100,message,U1,,0,[],\n"+
+		"C2,Other channel,1599934260.000100,message,U3,,0,[],\n")
+
+	var contents []string
+	processor := recordingProcessor(&contents)
+	if _, err := checkpointService(t, processor, statePath).IngestFile(context.Background(), first); err != nil {
+		t.Fatalf("IngestFile() error = %v", err)
+	}
+	if len(contents) != 4 || len(processor.threads) != 1 {
+		t.Fatalf("first export: processed %v, %d threads", contents, len(processor.threads))
+	}
+
+	// A later export repeats everything, fixes the typo and adds a reply
+	second := writeCSV(t, dir, "export2.csv", header+
+		"C1,Release plan?,1599934232.150700,message,U1,1599934232.150700,2,\"[\"\"U2\"\",\"\"U3\"\"]\",\n"+
+		"C1,Ship it Monday,1599934240.150700,message,U2,1599934232.150700,0,[],\n"+
+		"C1,No typo here,1599934250.000100,message,U1,,0,[],1599990000.000100\n"+
+		"C2,Other channel,1599934260.000100,message,U3,,0,[],\n"+
+		"C1,Monday works,1599934300.000100,message,U3,1599934232.150700,0,[],\n")
+
+	contents = nil
+	processor = recordingProcessor(&contents)
+	stats, err := checkpointService(t, processor, statePath).IngestFile(context.Background(), second)
+	if err != nil {
+		t.Fatalf("IngestFile() error = %v", err)
+	}
+	if want := []string{"No typo here", "Monday works"}; !reflect.DeepEqual(contents, want) {
+		t.Errorf("processed %v, want %v", contents, want)
+	}
+	if stats.UnchangedMessages != 3 {
+		t.Errorf("UnchangedMessages = %d, want 3", stats.UnchangedMessages)
+	}
+
+	// The thread with a new reply is rebuilt from all its messages
+	if len(processor.threads) != 1 || len(processor.threads[0].Replies) != 2 {
+		t.Fatalf("unexpected threads: %+v", processor.threads)
+	}
+}
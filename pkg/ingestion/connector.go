@@ -0,0 +1,171 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+// Connector reads one kind of source, such as a Slack export or a folder
+// of Markdown files, into normalized records. Connectors are registered by
+// name in a Registry and chosen by the type of an IngestRequest.
+//
+// The Service ingests records from Read one by one, without threading or
+// checkpoints. Only the built-in Slack connectors get those, through a
+// hook that connectors outside this package cannot implement.
+type Connector interface {
+	// Name identifies the connector in requests, e.g. "slack-csv"
+	Name() string
+	// Read streams the records found at path to fn
+	Read(ctx context.Context, path string, fn RecordFunc) error
+}
+
+// RecordFunc receives each record a connector reads. An item that cannot
+// be read is passed as a zero record and an error, preferably a
+// *ParseError; reading goes on unless RecordFunc returns an error.
+type RecordFunc func(record models.Record, err error) error
+
+// RecordProcessor is implemented by processors that can turn records from
+// any connector into documents
+type RecordProcessor interface {
+	ProcessRecord(ctx context.Context, record models.Record) ([]vector.Document, error)
+}
+
+// messageConnector is implemented by the Slack connectors, which ingest
+// through the service's message pipeline so that export metadata is
+// loaded, threads are reconstructed and runs are checkpointed. It is
+// unexported on purpose: the message pipeline depends on Service
+// internals, so it is not an extension point.
+type messageConnector interface {
+	ingestMessages(ctx context.Context, s *Service, path string, stats *IngestionStats) error
+}
+
+// Registry holds connectors by name. It is safe for concurrent use.
+type Registry struct {
+	connectors map[string]Connector
+	mu         sync.RWMutex
+}
+
+// NewRegistry creates a registry holding the given connectors
+func NewRegistry(connectors ...Connector) *Registry {
+	r := &Registry{connectors: make(map[string]Connector, len(connectors))}
+	for _, connector := range connectors {
+		r.Register(connector)
+	}
+	return r
+}
+
+// DefaultRegistry returns a registry holding the built-in connectors.
+// Documentation, wiki pages and mailing-list archives are public, except
+// for Markdown files whose front matter lists permissions.
+func DefaultRegistry() *Registry {
+	public := []string{vector.PublicPermission}
+	return NewRegistry(
+		SlackCSVConnector{},
+		SlackZipConnector{},
+		MarkdownConnector{DefaultPermissions: public},
+		HTMLConnector{DefaultPermissions: public},
+		EmailConnector{DefaultPermissions: public},
+	)
+}
+
+// Register adds a connector, replacing any registered under the same name
+func (r *Registry) Register(connector Connector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.connectors[connector.Name()] = connector
+}
+
+// Get returns the connector registered under name
+func (r *Registry) Get(name string) (Connector, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	connector, ok := r.connectors[name]
+	return connector, ok
+}
+
+// Names returns the names of the registered connectors in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.connectors))
+	for name := range r.connectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// legacyTypes maps the ingestion types that predate connectors to the
+// connector that now reads them
+var legacyTypes = map[string]string{
+	"file":      SlackCSVConnectorName,
+	"directory": SlackCSVConnectorName,
+}
+
+// Connectors returns the service's connector registry, to which further
+// connectors can be added
+func (s *Service) Connectors() *Registry {
+	return s.connectors
+}
+
+// Connector returns the connector handling an ingestion type. Besides the
+// registered names, "file" and "directory" select the Slack CSV connector.
+func (s *Service) Connector(ingestType string) (Connector, bool) {
+	if name, ok := legacyTypes[ingestType]; ok {
+		ingestType = name
+	}
+	return s.connectors.Get(ingestType)
+}
+
+// ingestConnector ingests path with connector into stats
+func (s *Service) ingestConnector(ctx context.Context, connector Connector, path string, stats *IngestionStats) error {
+	if c, ok := connector.(messageConnector); ok {
+		return c.ingestMessages(ctx, s, path, stats)
+	}
+	return s.ingestRecords(ctx, connector, path, stats)
+}
+
+// ingestRecords streams the records of a connector through the ingestion
+// pipeline. Unlike Slack messages, records are neither threaded nor
+// checkpointed; re-ingesting a source overwrites its documents.
+func (s *Service) ingestRecords(ctx context.Context, connector Connector, path string, stats *IngestionStats) error {
+	if _, ok := s.processor.(RecordProcessor); !ok {
+		return fmt.Errorf("processor cannot ingest %s records", connector.Name())
+	}
+
+	pipeline := s.startPipeline(ctx, stats)
+
+	read := 0
+	err := connector.Read(ctx, path, func(record models.Record, err error) error {
+		read++
+		stats.addTotal(1)
+		if read%1000 == 0 {
+			log.Printf("Progress: %d records read from %s", read, path)
+		}
+
+		if err != nil {
+			stats.AddError(err)
+			for _, record := range parseDeadLetters(path, []error{err}) {
+				s.deadLetter(stats, record)
+			}
+			return nil
+		}
+		if !s.keepRecord(record, stats) {
+			return nil
+		}
+		return pipeline.submit(&work{record: &record})
+	})
+
+	pipeline.close()
+
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
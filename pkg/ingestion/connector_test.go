@@ -0,0 +1,196 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+// staticConnector yields a fixed list of records, with an error for every
+// nil entry
+type staticConnector struct {
+	name    string
+	records []*models.Record
+}
+
+func (c staticConnector) Name() string {
+	return c.name
+}
+
+func (c staticConnector) Read(ctx context.Context, path string, fn RecordFunc) error {
+	for i, record := range c.records {
+		var err error
+		if record == nil {
+			record = &models.Record{}
+			err = &ParseError{Record: i + 1, Err: errors.New("unreadable item")}
+		}
+		if err := fn(*record, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordMockProcessor implements DocumentProcessor and RecordProcessor
+type recordMockProcessor struct {
+	mockDocumentProcessor
+	recordFunc func(context.Context, models.Record) ([]vector.Document, error)
+	records    []models.Record
+	mu         sync.Mutex
+}
+
+func (m *recordMockProcessor) ProcessRecord(ctx context.Context, record models.Record) ([]vector.Document, error) {
+	m.mu.Lock()
+	m.records = append(m.records, record)
+	m.mu.Unlock()
+	if m.recordFunc != nil {
+		return m.recordFunc(ctx, record)
+	}
+	return []vector.Document{{ID: record.ID, Content: record.Text, Source: record.Source}}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	registry := DefaultRegistry()
+	if got := registry.Names(); !reflect.DeepEqual(got, []string{"email", "html", "markdown", "slack-csv", "slack-zip"}) {
+		t.Errorf("Names() = %v", got)
+	}
+
+	registry.Register(staticConnector{name: "notes"})
+	if _, ok := registry.Get("notes"); !ok {
+		t.Error("registered connector not found")
+	}
+	if _, ok := registry.Get("wiki"); ok {
+		t.Error("unknown connector found")
+	}
+
+	cfg := DefaultServiceConfig()
+	cfg.Connectors = registry
+	service := NewService(&mockVectorClient{}, &recordMockProcessor{}, cfg)
+	for ingestType, want := range map[string]string{
+		"file":      "slack-csv",
+		"directory": "slack-csv",
+		"slack-zip": "slack-zip",
+		"notes":     "notes",
+	} {
+		connector, ok := service.Connector(ingestType)
+		if !ok || connector.Name() != want {
+			t.Errorf("Connector(%q) = %v, %v; want %s", ingestType, connector, ok, want)
+		}
+	}
+}
+
+func TestService_IngestConnector(t *testing.T) {
+	connector := staticConnector{name: "notes", records: []*models.Record{
+		{ID: "notes:a", Source: "notes", Text: "Deploys happen on Tuesdays"},
+		{ID: "notes:b", Source: "notes", Text: ""},
+		nil,
+		{ID: "notes:c", Source: "notes", Text: "Fails to embed"},
+	}}
+
+	processor := &recordMockProcessor{recordFunc: func(ctx context.Context, record models.Record) ([]vector.Document, error) {
+		if record.ID == "notes:c" {
+			return nil, errors.New("context deadline exceeded")
+		}
+		return []vector.Document{{ID: record.ID, Content: record.Text}}, nil
+	}}
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	cfg := DefaultServiceConfig()
+	cfg.Connectors = NewRegistry(connector)
+	cfg.DeadLetters = NewDeadLetterWriter(deadLetterPath)
+	defer cfg.DeadLetters.Close()
+	store := &mockVectorClient{}
+	service := NewService(store, processor, cfg)
+
+	stats := &IngestionStats{}
+	if err := service.Ingest(context.Background(), IngestRequest{Type: "notes", Path: "notes/"}, stats); err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if stats.TotalMessages != 4 || stats.ProcessedMessages != 1 || stats.SkippedMessages != 1 || stats.FailedMessages != 1 {
+		t.Errorf("unexpected stats: %+v", stats.GetSummary())
+	}
+	if store.storeCount != 1 || len(stats.Errors) != 2 || stats.DeadLetters != 2 {
+		t.Errorf("stored %d documents, %d errors, %d dead letters; want 1, 2 and 2", store.storeCount, len(stats.Errors), stats.DeadLetters)
+	}
+
+	records, err := ReadDeadLetters(deadLetterPath)
+	if err != nil {
+		t.Fatalf("ReadDeadLetters() error = %v", err)
+	}
+	var item *models.Record
+	for _, record := range records {
+		if record.Item != nil {
+			item = record.Item
+		}
+	}
+	if len(records) != 2 || item == nil || item.ID != "notes:c" {
+		t.Fatalf("unexpected dead letters: %+v", records)
+	}
+
+	// Replaying sends the record through the processor again
+	replay := NewService(&mockVectorClient{}, &recordMockProcessor{}, DefaultServiceConfig())
+	stats = replay.ReplayDeadLetters(context.Background(), []DeadLetter{{Stage: StageProcess, Item: item}})
+	if stats.ProcessedMessages != 1 || stats.FailedMessages != 0 {
+		t.Errorf("unexpected replay stats: %+v", stats.GetSummary())
+	}
+
+	// Records need a processor that understands them
+	cfg.DeadLetters = nil
+	withoutRecords := NewService(&mockVectorClient{}, &mockDocumentProcessor{}, cfg)
+	if err := withoutRecords.Ingest(context.Background(), IngestRequest{Type: "notes"}, &IngestionStats{}); err == nil {
+		t.Error("Ingest() with a message-only processor succeeded")
+	}
+	if err := service.Ingest(context.Background(), IngestRequest{Type: "wiki"}, &IngestionStats{}); err == nil {
+		t.Error("Ingest() with an unknown type succeeded")
+	}
+}
+
+func TestSlackCSVConnector_Read(t *testing.T) {
+	dir := t.TempDir()
+	writeCSV(t, dir, "general.csv", `channel_id,text,ts,type,user,thread_ts
+C1,Hello,1599934232.000100,message,U1,
+C1,Bad timestamp,yesterday,message,U2,
+`)
+	writeCSV(t, dir, "random.csv", `channel_id,text,ts,type,user,thread_ts
+C2,Reply,1599934240.000100,message,U2,1599934232.000100
+`)
+	writeCSV(t, dir, UsersFile, "id,name\nU1,alice\n")
+
+	var records []models.Record
+	var readErrors []error
+	err := SlackCSVConnector{}.Read(context.Background(), dir, func(record models.Record, err error) error {
+		if err != nil {
+			readErrors = append(readErrors, err)
+			return nil
+		}
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if len(records) != 2 || len(readErrors) != 1 {
+		t.Fatalf("read %d records and %d errors, want 2 and 1", len(records), len(readErrors))
+	}
+	first := records[0]
+	if first.Source != "slack" || first.Text != "Hello" || first.AuthorID != "U1" || first.Metadata["channel"] != "C1" {
+		t.Errorf("unexpected record: %+v", first)
+	}
+	if records[1].ThreadID != "C2:1599934232.000100" {
+		t.Errorf("ThreadID = %q", records[1].ThreadID)
+	}
+	var parseErr *ParseError
+	if !errors.As(readErrors[0], &parseErr) || parseErr.Fields["ts"] != "yesterday" {
+		t.Errorf("unexpected read error: %v", readErrors[0])
+	}
+
+	if err := (SlackCSVConnector{}).Read(context.Background(), t.TempDir(), func(models.Record, error) error { return nil }); err == nil {
+		t.Error("Read() of a directory without CSV files succeeded")
+	}
+}
@@ -50,27 +50,50 @@ func NewCSVParser(config ...ParserConfig) *CSVParser {
 	}
 }
 
+// ParseError is a record the parser could not turn into a message. Fields
+// holds the record's columns by header name when the record could be read.
+type ParseError struct {
+	Record int
+	Fields map[string]string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 // BatchCallback is called for each batch of messages
 type BatchCallback func(messages []models.SlackMessage, batchNum int) error
 
 // ProgressCallback is called to report progress
 type ProgressCallback func(processed, total int, errors int)
 
-// ParseFile parses a CSV file with batch processing and progress tracking
+// ParseFile parses a CSV file with batch processing and progress tracking.
+// Files ending in .gz are decompressed.
 func (p *CSVParser) ParseFile(filename string, batchCallback BatchCallback, progressCallback ProgressCallback) error {
-	file, err := os.Open(filename)
+	return p.ParseExportFile(ExportFile{Path: filename}, batchCallback, progressCallback)
+}
+
+// ParseExportFile parses a CSV file, or a CSV within a ZIP archive, found by
+// DiscoverFiles
+func (p *CSVParser) ParseExportFile(file ExportFile, batchCallback BatchCallback, progressCallback ProgressCallback) error {
+	r, err := file.Open()
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return err
 	}
-	defer file.Close()
+	defer r.Close()
 
 	// Get file size for progress tracking
-	fileInfo, err := file.Stat()
+	fileInfo, err := os.Stat(file.Path)
 	if err != nil {
 		return fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	return p.ParseWithCallbacks(file, fileInfo.Size(), batchCallback, progressCallback)
+	return p.ParseWithCallbacks(r, fileInfo.Size(), batchCallback, progressCallback)
 }
 
 // ParseWithCallbacks parses CSV data with batch processing
@@ -92,8 +115,7 @@ func (p *CSVParser) ParseWithCallbacks(r io.Reader, totalSize int64, batchCallba
 	}
 
 	// Validate required columns
-	requiredColumns := []string{"text", "user", "channel_id", "ts", "type"}
-	for _, col := range requiredColumns {
+	for _, col := range messageColumns {
 		if _, ok := columnMap[col]; !ok {
 			return fmt.Errorf("required column %s not found in CSV", col)
 		}
@@ -119,7 +141,10 @@ func (p *CSVParser) ParseWithCallbacks(r io.Reader, totalSize int64, batchCallba
 		}
 		if err != nil {
 			if p.config.SkipErrors {
-				p.recordError(fmt.Errorf("failed to read record %d: %w", p.totalRecords+1, err))
+				p.recordError(&ParseError{
+					Record: p.totalRecords + 1,
+					Err:    fmt.Errorf("failed to read record %d: %w", p.totalRecords+1, err),
+				})
 				p.totalRecords++
 				continue
 			}
@@ -131,7 +156,11 @@ func (p *CSVParser) ParseWithCallbacks(r io.Reader, totalSize int64, batchCallba
 		msg, err := p.parseRecord(record, columnMap)
 		if err != nil {
 			if p.config.SkipErrors {
-				p.recordError(fmt.Errorf("failed to parse record %d: %w", p.totalRecords, err))
+				p.recordError(&ParseError{
+					Record: p.totalRecords,
+					Fields: recordFields(record, header),
+					Err:    fmt.Errorf("failed to parse record %d: %w", p.totalRecords, err),
+				})
 				continue
 			}
 			return fmt.Errorf("failed to parse record %d: %w", p.totalRecords, err)
@@ -141,7 +170,11 @@ func (p *CSVParser) ParseWithCallbacks(r io.Reader, totalSize int64, batchCallba
 		if p.config.ValidateRecords {
 			if err := p.validateMessage(msg); err != nil {
 				if p.config.SkipErrors {
-					p.recordError(fmt.Errorf("invalid record %d: %w", p.totalRecords, err))
+					p.recordError(&ParseError{
+						Record: p.totalRecords,
+						Fields: recordFields(record, header),
+						Err:    fmt.Errorf("invalid record %d: %w", p.totalRecords, err),
+					})
 					continue
 				}
 				return fmt.Errorf("invalid record %d: %w", p.totalRecords, err)
@@ -190,6 +223,39 @@ func (p *CSVParser) Parse(r io.Reader) ([]models.SlackMessage, error) {
 	return allMessages, nil
 }
 
+// ParseFields parses and validates a single record given as columns by
+// header name, such as the Fields of a ParseError
+func (p *CSVParser) ParseFields(fields map[string]string) (models.SlackMessage, error) {
+	record := make([]string, 0, len(fields))
+	columnMap := make(map[string]int, len(fields))
+	for name, value := range fields {
+		columnMap[name] = len(record)
+		record = append(record, value)
+	}
+
+	msg, err := p.parseRecord(record, columnMap)
+	if err != nil {
+		return msg, err
+	}
+	if p.config.ValidateRecords {
+		if err := p.validateMessage(msg); err != nil {
+			return msg, err
+		}
+	}
+	return msg, nil
+}
+
+// recordFields maps a record's values to their header names
+func recordFields(record, header []string) map[string]string {
+	fields := make(map[string]string, len(header))
+	for i, name := range header {
+		if i < len(record) {
+			fields[strings.TrimSpace(name)] = record[i]
+		}
+	}
+	return fields
+}
+
 // parseRecord converts a CSV record to SlackMessage
 func (p *CSVParser) parseRecord(record []string, columnMap map[string]int) (models.SlackMessage, error) {
 	msg := models.SlackMessage{}
@@ -204,6 +270,9 @@ func (p *CSVParser) parseRecord(record []string, columnMap map[string]int) (mode
 
 	// Parse core fields
 	msg.Content = getField("text")
+	if blocks := getField("blocks"); blocks != "null" {
+		msg.Blocks = blocks
+	}
 	msg.User = getField("user")
 	msg.Channel = getField("channel_id")
 	msg.Type = getField("type")
@@ -227,17 +296,37 @@ func (p *CSVParser) parseRecord(record []string, columnMap map[string]int) (mode
 		}
 	}
 
+	msg.TS = messageTS(tsStr, msg.ThreadTS, msg.Timestamp)
+
 	// Parse additional fields
 	msg.ParentUserID = getField("parent_user_id")
+	msg.LatestReply = getField("latest_reply")
+	msg.Edited = getField("edited__ts")
 	msg.BotID = getField("bot_id")
-	msg.Reactions = getField("reactions")
+	msg.BotAppID = getField("bot_profile__app_id")
+	msg.BotName = getField("bot_profile__name")
+	if msg.BotName == "" && msg.BotID != "" {
+		msg.BotName = getField("username")
+	}
+	// Malformed reactions only cost the engagement signal, not the message
+	msg.Reactions, _ = models.ParseSlackReactions(getField("reactions"))
 
-	// Parse reply count
+	// Parse reply counts
 	if replyCountStr := getField("reply_count"); replyCountStr != "" {
 		if count, err := strconv.Atoi(replyCountStr); err == nil {
 			msg.ReplyCount = count
 		}
 	}
+	if replyUsersCountStr := getField("reply_users_count"); replyUsersCountStr != "" {
+		if count, err := strconv.Atoi(replyUsersCountStr); err == nil {
+			msg.ReplyUsersCount = count
+		}
+	}
+
+	// Parse the channels the message is pinned in (JSON array string)
+	if pinnedToStr := getField("pinned_to"); pinnedToStr != "" {
+		msg.PinnedTo = parseJSONArrayString(pinnedToStr)
+	}
 
 	// Parse reply users (JSON array string)
 	if replyUsersStr := getField("reply_users"); replyUsersStr != "" {
@@ -292,6 +381,35 @@ func parseSlackTimestamp(ts string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("invalid timestamp format: %s", ts)
 }
 
+// messageTS returns the message's own Slack timestamp. Some exports write
+// ts as a datetime and move the original Slack timestamp into thread_ts, so
+// thread_ts is used when it denotes the same instant as ts.
+func messageTS(ts, threadTS string, timestamp time.Time) string {
+	if isSlackTS(ts) {
+		return ts
+	}
+	if isSlackTS(threadTS) && !timestamp.IsZero() {
+		if parsed, err := parseSlackTimestamp(threadTS); err == nil && parsed.Unix() == timestamp.Unix() {
+			return threadTS
+		}
+	}
+	return ""
+}
+
+// isSlackTS reports whether s looks like a Slack timestamp ("1599934232.150700")
+func isSlackTS(s string) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return false
+	}
+	for _, part := range parts {
+		if _, err := strconv.ParseUint(part, 10, 64); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
 // parseJSONArrayString parses a JSON array string like ["user1", "user2"]
 func parseJSONArrayString(s string) []string {
 	s = strings.TrimSpace(s)
@@ -322,6 +440,11 @@ func parseJSONArrayString(s string) []string {
 
 // validateMessage validates a SlackMessage
 func (p *CSVParser) validateMessage(msg models.SlackMessage) error {
+	return validateSlackMessage(msg)
+}
+
+// validateSlackMessage checks that a parsed message can be ingested
+func validateSlackMessage(msg models.SlackMessage) error {
 	// Skip system messages without user
 	if msg.Subtype == "channel_join" || msg.Subtype == "channel_leave" {
 		return nil
@@ -2,6 +2,7 @@ package ingestion
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -157,6 +158,9 @@ null,,C01234567,<@U01234567> has joined the channel,1599934250.150700,message,U0
 	if messages[0].Channel != "C01234567" {
 		t.Errorf("Expected channel 'C01234567', got '%s'", messages[0].Channel)
 	}
+	if messages[0].Blocks != "" {
+		t.Errorf("Expected null blocks to be dropped, got '%s'", messages[0].Blocks)
+	}
 
 	// Check threaded message
 	if messages[1].ThreadTS != "1599934232.150700" {
@@ -169,6 +173,72 @@ null,,C01234567,<@U01234567> has joined the channel,1599934250.150700,message,U0
 	}
 }
 
+func TestCSVParser_BotProfile(t *testing.T) {
+	csvData := `bot_id,bot_profile__app_id,bot_profile__name,channel_id,text,ts,type,user,username
+BDG87ESBD,A8GBNUWU8,GitHub,C1,Pull request opened,1599934232.150700,message,U1,
+B2,,,C1,Build passed,1599934240.150700,message,,ci-bot
+,,,C1,Hello,1599934250.150700,message,U2,alice`
+
+	messages, err := NewCSVParser().Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+
+	if messages[0].BotName != "GitHub" || messages[0].BotAppID != "A8GBNUWU8" {
+		t.Errorf("unexpected bot profile: %q, %q", messages[0].BotName, messages[0].BotAppID)
+	}
+	// Legacy integrations are named by the username they post as
+	if messages[1].BotName != "ci-bot" {
+		t.Errorf("BotName = %q, want ci-bot", messages[1].BotName)
+	}
+	if messages[2].BotName != "" {
+		t.Errorf("BotName = %q for a user message", messages[2].BotName)
+	}
+}
+
+func TestCSVParser_Engagement(t *testing.T) {
+	csvData := `channel_id,text,ts,type,user,reactions,reply_count,reply_users_count,pinned_to
+C1,Deploy guide,1599934232.150700,message,U1,"[{""name"": ""wave"", ""count"": 2, ""users"": [""U2"", ""U3""]}, {""name"": ""+1"", ""count"": 1, ""users"": [""U2""]}]",3,2,"[""C1""]"
+C1,Broken reactions,1599934240.150700,message,U2,[{not json,,,`
+
+	messages, err := NewCSVParser().Parse(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+
+	msg := messages[0]
+	want := models.SlackReactions{
+		{Name: "wave", Count: 2, Users: []string{"U2", "U3"}},
+		{Name: "+1", Count: 1, Users: []string{"U2"}},
+	}
+	if !reflect.DeepEqual(msg.Reactions, want) {
+		t.Errorf("Reactions = %+v, want %+v", msg.Reactions, want)
+	}
+	if msg.ReplyUsersCount != 2 || !msg.IsPinned() {
+		t.Errorf("ReplyUsersCount = %d, PinnedTo = %v", msg.ReplyUsersCount, msg.PinnedTo)
+	}
+
+	record := msg.Record()
+	wantEngagement := models.Engagement{Reactions: 3, Replies: 3, Pinned: true}
+	if record.Engagement != wantEngagement {
+		t.Errorf("Engagement = %+v, want %+v", record.Engagement, wantEngagement)
+	}
+	if record.Metadata["reactions"] != "wave:2,+1:1" {
+		t.Errorf("reactions metadata = %q", record.Metadata["reactions"])
+	}
+
+	// Malformed reactions only lose the engagement signal
+	if messages[1].Reactions != nil || messages[1].Content != "Broken reactions" {
+		t.Errorf("unexpected message: %+v", messages[1])
+	}
+}
+
 func TestCSVParser_ParseWithErrors(t *testing.T) {
 	// Test CSV with some valid and some invalid records
 	invalidCSV := `channel_id,text,ts,type,user
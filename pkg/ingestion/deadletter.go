@@ -0,0 +1,241 @@
+package ingestion
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// Pipeline stages a record can fail in
+const (
+	StageParse   = "parse"   // the record could not be parsed or validated
+	StageProcess = "process" // chunking or embedding failed
+	StageStore   = "store"   // a document could not be stored
+	StageThread  = "thread"  // a thread document could not be built or stored
+)
+
+// DeadLetter is a record that failed ingestion, with everything needed to
+// replay it. Exactly one of Fields, Message, Thread and Item is set, except
+// for parse failures of unreadable records, which carry none.
+type DeadLetter struct {
+	Stage  string    `json:"stage"`
+	Error  string    `json:"error"`
+	Time   time.Time `json:"time"`
+	Source string    `json:"source,omitempty"`
+	// Record is the 1-based record number of a parse failure in Source
+	Record int `json:"record,omitempty"`
+	// Fields are the columns of a record that failed to parse
+	Fields  map[string]string    `json:"fields,omitempty"`
+	Message *models.SlackMessage `json:"message,omitempty"`
+	Thread  *models.SlackThread  `json:"thread,omitempty"`
+	// Item is a record read by a connector other than Slack's
+	Item *models.Record `json:"item,omitempty"`
+}
+
+// DeadLetterWriter appends dead letters to a JSONL file. The file is only
+// created once the first record is written. It is safe for concurrent use.
+type DeadLetterWriter struct {
+	path  string
+	file  *os.File
+	enc   *json.Encoder
+	count int
+	mu    sync.Mutex
+}
+
+// DeadLetterPathFor returns the dead-letter file kept beside the state
+// file at statePath, for runs that checkpoint but name no dead-letter file
+func DeadLetterPathFor(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), "dead-letters.jsonl")
+}
+
+// NewDeadLetterWriter creates a writer appending to the file at path
+func NewDeadLetterWriter(path string) *DeadLetterWriter {
+	return &DeadLetterWriter{path: path}
+}
+
+// Path returns the file dead letters are written to
+func (w *DeadLetterWriter) Path() string {
+	return w.path
+}
+
+// Write appends a record to the file
+func (w *DeadLetterWriter) Write(record DeadLetter) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if dir := filepath.Dir(w.path); dir != "" {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("failed to create dead-letter directory: %w", err)
+			}
+		}
+		file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open dead-letter file: %w", err)
+		}
+		w.file = file
+		w.enc = json.NewEncoder(file)
+	}
+
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	if err := w.enc.Encode(record); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	w.count++
+	return nil
+}
+
+// Count returns the number of records written
+func (w *DeadLetterWriter) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Close closes the file if it was opened
+func (w *DeadLetterWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// ReadDeadLetters reads every record of a dead-letter file
+func ReadDeadLetters(path string) ([]DeadLetter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer file.Close()
+
+	var records []DeadLetter
+	scanner := bufio.NewScanner(file)
+	// Threads can make for long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse dead letter on line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter file: %w", err)
+	}
+	return records, nil
+}
+
+// parseDeadLetters converts the parse errors recorded while reading source
+// into dead letters
+func parseDeadLetters(source string, parseErrors []error) []DeadLetter {
+	records := make([]DeadLetter, 0, len(parseErrors))
+	for _, err := range parseErrors {
+		record := DeadLetter{Stage: StageParse, Error: err.Error(), Source: source}
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			record.Record = parseErr.Record
+			record.Fields = parseErr.Fields
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// deadLetter records a failed record if a dead-letter file is configured
+func (s *Service) deadLetter(stats *IngestionStats, record DeadLetter) {
+	if s.deadLetters == nil {
+		return
+	}
+	if err := s.deadLetters.Write(record); err != nil {
+		log.Printf("Failed to write dead letter: %v", err)
+		return
+	}
+	stats.mu.Lock()
+	stats.DeadLetters++
+	stats.mu.Unlock()
+}
+
+// ReplayDeadLetters re-processes failed records. Records that fail again,
+// and records that cannot be replayed, are written to the service's
+// dead-letter file.
+func (s *Service) ReplayDeadLetters(ctx context.Context, records []DeadLetter) *IngestionStats {
+	stats := &IngestionStats{TotalMessages: len(records)}
+	stats.start()
+	defer stats.finish()
+
+	parser := NewCSVParser(s.parserConfig())
+	_, canProcessThreads := s.processor.(ThreadProcessor)
+	_, canProcessRecords := s.processor.(RecordProcessor)
+
+	var replays []*work
+	for _, record := range records {
+		switch {
+		case record.Thread != nil && canProcessThreads:
+			replays = append(replays, &work{thread: record.Thread})
+		case record.Item != nil && canProcessRecords:
+			replays = append(replays, &work{record: record.Item})
+		case record.Message != nil:
+			replays = append(replays, &work{message: record.Message})
+		case record.Fields != nil:
+			msg, err := parser.ParseFields(record.Fields)
+			if err != nil {
+				stats.UpdateStats(0, 0, 1, 0, 0, 0)
+				record.Error = err.Error()
+				record.Time = time.Time{}
+				s.deadLetter(stats, record)
+				continue
+			}
+			replays = append(replays, &work{message: &msg})
+		default:
+			stats.UpdateStats(0, 0, 1, 0, 0, 0)
+			switch {
+			case record.Thread != nil:
+				stats.AddError(fmt.Errorf("thread %s cannot be replayed: processor does not build thread documents", record.Thread.ID()))
+			case record.Item != nil:
+				stats.AddError(fmt.Errorf("record %s cannot be replayed: processor does not process records", record.Item.ID))
+			default:
+				stats.AddError(fmt.Errorf("dead letter from %s record %d cannot be replayed: %s", record.Source, record.Record, record.Error))
+			}
+			record.Time = time.Time{}
+			s.deadLetter(stats, record)
+		}
+	}
+
+	pipeline := s.startPipeline(ctx, stats)
+	pipeline.replay = true
+	for i, w := range replays {
+		if err := pipeline.submit(w); err != nil {
+			// Keep what was not replayed for the next attempt
+			stats.AddError(err)
+			for _, w := range replays[i:] {
+				record := DeadLetter{Stage: StageProcess, Error: err.Error(), Message: w.message, Item: w.record, Thread: w.thread}
+				if w.thread != nil {
+					record.Stage = StageThread
+				}
+				s.deadLetter(stats, record)
+			}
+			break
+		}
+	}
+	pipeline.close()
+
+	return stats
+}
@@ -0,0 +1,179 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+func TestDeadLetterWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failed", "dead-letters.jsonl")
+	writer := NewDeadLetterWriter(path)
+	defer writer.Close()
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("file created before the first record: %v", err)
+	}
+
+	records := []DeadLetter{
+		{Stage: StageParse, Error: "invalid record 3", Source: "messages.csv", Record: 3, Fields: map[string]string{"text": "hi"}},
+		{Stage: StageProcess, Error: "timeout", Message: &models.SlackMessage{MessageID: "m1", Content: "Hello"}},
+		{Stage: StageThread, Error: "timeout", Thread: &models.SlackThread{Channel: "C1", ThreadTS: "1599934232.150700"}},
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if writer.Count() != 3 {
+		t.Errorf("Count() = %d, want 3", writer.Count())
+	}
+
+	got, err := ReadDeadLetters(path)
+	if err != nil {
+		t.Fatalf("ReadDeadLetters() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("read %d records, want 3", len(got))
+	}
+	if got[0].Record != 3 || got[0].Fields["text"] != "hi" || got[0].Time.IsZero() {
+		t.Errorf("unexpected parse record: %+v", got[0])
+	}
+	if got[1].Message == nil || got[1].Message.Content != "Hello" {
+		t.Errorf("unexpected process record: %+v", got[1])
+	}
+	if got[2].Thread == nil || got[2].Thread.ID() != "C1:1599934232.150700" {
+		t.Errorf("unexpected thread record: %+v", got[2])
+	}
+}
+
+func TestDeadLettersAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	file := writeCSV(t, dir, "messages.csv", `channel_id,text,ts,type,user
+C1,Hello,1599934232.000100,message,U1
+C1,Embedding times out,1599934233.000100,message,U1
+C1,Storage fails,1599934234.000100,message,U2
+C1,Bad timestamp,yesterday,message,U2
+`)
+	deadLetterPath := filepath.Join(dir, "dead-letters.jsonl")
+
+	// Ollama and Weaviate are flaky during the first run
+	processor := &mockDocumentProcessor{processFunc: func(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
+		if msg.Content == "Embedding times out" {
+			return nil, errors.New("context deadline exceeded")
+		}
+		return []vector.Document{{ID: msg.MessageID, Content: msg.Content}}, nil
+	}}
+	store := &mockVectorClient{storeFunc: func(ctx context.Context, doc vector.Document) error {
+		if doc.Content == "Storage fails" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}
+
+	cfg := DefaultServiceConfig()
+	cfg.MaxConcurrency = 1
+	writer := NewDeadLetterWriter(deadLetterPath)
+	cfg.DeadLetters = writer
+	stats, err := NewService(store, processor, cfg).IngestFile(context.Background(), file)
+	if err != nil {
+		t.Fatalf("IngestFile() error = %v", err)
+	}
+	if stats.DeadLetters != 3 {
+		t.Errorf("DeadLetters = %d, want 3", stats.DeadLetters)
+	}
+	writer.Close()
+
+	records, err := ReadDeadLetters(deadLetterPath)
+	if err != nil {
+		t.Fatalf("ReadDeadLetters() error = %v", err)
+	}
+	var stages []string
+	for _, record := range records {
+		stages = append(stages, record.Stage)
+	}
+	sort.Strings(stages)
+	if len(stages) != 3 || stages[0] != StageParse || stages[1] != StageProcess || stages[2] != StageStore {
+		t.Fatalf("dead-letter stages = %v", stages)
+	}
+
+	// Once the services recover, replaying stores the failed messages. The
+	// record with the bad timestamp fails again and is written back.
+	replayPath := filepath.Join(dir, "replayed.jsonl")
+	cfg.DeadLetters = NewDeadLetterWriter(replayPath)
+	defer cfg.DeadLetters.Close()
+	replayStore := &mockVectorClient{}
+	stats = NewService(replayStore, &mockDocumentProcessor{}, cfg).ReplayDeadLetters(context.Background(), records)
+
+	if stats.ProcessedMessages != 2 || replayStore.storeCount != 2 {
+		t.Errorf("replay processed %d messages, stored %d documents; want 2 and 2", stats.ProcessedMessages, replayStore.storeCount)
+	}
+	if stats.FailedMessages != 1 || stats.DeadLetters != 1 {
+		t.Errorf("replay failed %d, dead-lettered %d; want 1 and 1", stats.FailedMessages, stats.DeadLetters)
+	}
+
+	again, err := ReadDeadLetters(replayPath)
+	if err != nil {
+		t.Fatalf("ReadDeadLetters() error = %v", err)
+	}
+	if len(again) != 1 || again[0].Stage != StageParse || again[0].Fields["ts"] != "yesterday" {
+		t.Errorf("unexpected records after replay: %+v", again)
+	}
+}
+
+func TestDeadLetters_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	content := "channel_id,text,ts,type,user\n"
+	for i := 0; i < 50; i++ {
+		content += fmt.Sprintf("C1,Message %d,1599934%03d.000100,message,U1\n", i, i)
+	}
+	file := writeCSV(t, dir, "messages.csv", content)
+	deadLetterPath := filepath.Join(dir, "dead-letters.jsonl")
+
+	// The run is cancelled part way through, failing whatever is in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	processed := 0
+	processor := &mockDocumentProcessor{processFunc: func(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
+		mu.Lock()
+		processed++
+		if processed == 5 {
+			cancel()
+		}
+		mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return []vector.Document{{ID: msg.MessageID, Content: msg.Content}}, nil
+	}}
+	store := &mockVectorClient{storeFunc: func(ctx context.Context, doc vector.Document) error {
+		return ctx.Err()
+	}}
+
+	cfg := DefaultServiceConfig()
+	cfg.BatchSize = 5
+	writer := NewDeadLetterWriter(deadLetterPath)
+	cfg.DeadLetters = writer
+	stats, _ := NewService(store, processor, cfg).IngestFile(ctx, file)
+	writer.Close()
+
+	if stats != nil && stats.DeadLetters != 0 {
+		t.Errorf("DeadLetters = %d, want 0", stats.DeadLetters)
+	}
+	records, err := ReadDeadLetters(deadLetterPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("ReadDeadLetters() error = %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("cancelled work was dead-lettered: %+v", records)
+	}
+}
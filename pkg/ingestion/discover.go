@@ -0,0 +1,297 @@
+package ingestion
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileKind is what a file found in an export holds, as told by its content
+type FileKind string
+
+// Kinds of export files
+const (
+	KindMessages       FileKind = "messages"        // a message table
+	KindThreads        FileKind = "threads"         // a message table of thread parents and replies
+	KindUsers          FileKind = "users"           // a user directory such as users.csv
+	KindChannels       FileKind = "channels"        // channel metadata such as channels.csv
+	KindChannelMembers FileKind = "channel_members" // channel memberships such as channel_members.csv
+	KindSlackExport    FileKind = "slack_export"    // a Slack workspace export ZIP of JSON files
+	KindUnknown        FileKind = "unknown"         // anything else, e.g. exported_stats.csv
+)
+
+// messageColumns are the columns every message table has
+var messageColumns = []string{"text", "user", "channel_id", "ts", "type"}
+
+// ExportFile is a table found by DiscoverFiles: a CSV file, possibly
+// gzip-compressed, an entry of a ZIP archive of such files, or a Slack
+// export ZIP
+type ExportFile struct {
+	// Path is the file on disk
+	Path string `json:"path"`
+	// Entry is the file within the ZIP archive at Path, if any
+	Entry string   `json:"entry,omitempty"`
+	Kind  FileKind `json:"kind"`
+}
+
+// Name returns the file's path, with the entry appended for files within
+// an archive
+func (f ExportFile) Name() string {
+	if f.Entry == "" {
+		return f.Path
+	}
+	return filepath.Join(f.Path, f.Entry)
+}
+
+// IsMessages reports whether the file holds messages to ingest
+func (f ExportFile) IsMessages() bool {
+	return f.Kind == KindMessages || f.Kind == KindThreads || f.Kind == KindSlackExport
+}
+
+// Open opens the file for reading, decompressing .gz files
+func (f ExportFile) Open() (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	if f.Entry == "" {
+		file, err := os.Open(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		rc = file
+	} else {
+		archive, err := zip.OpenReader(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		entry, err := archive.Open(f.Entry)
+		if err != nil {
+			archive.Close()
+			return nil, fmt.Errorf("failed to open %s in archive: %w", f.Entry, err)
+		}
+		rc = &multiCloser{Reader: entry, closers: []io.Closer{entry, archive}}
+	}
+
+	if !strings.HasSuffix(strings.ToLower(f.Name()), ".gz") {
+		return rc, nil
+	}
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("failed to decompress %s: %w", f.Name(), err)
+	}
+	return &multiCloser{Reader: gz, closers: []io.Closer{gz, rc}}, nil
+}
+
+// multiCloser reads from a reader and closes a stack of closers, innermost
+// first
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close implements io.Closer
+func (m *multiCloser) Close() error {
+	var first error
+	for _, c := range m.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// DiscoverFiles returns the export files at path: path itself if it is a
+// file, or every CSV, .csv.gz and ZIP file below it if it is a directory.
+// Hidden directories are skipped. ZIP archives of CSV files contribute each
+// of their CSVs; Slack workspace exports are returned whole. Each file's
+// kind is sniffed from its header, and files are returned in path order.
+// Files and directories below path that cannot be read are logged and
+// returned as KindUnknown, or skipped, so one stray file does not fail the
+// whole scan.
+func DiscoverFiles(path string) ([]ExportFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return discoverFile(path)
+	}
+
+	var files []ExportFile
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if file == path {
+				return err
+			}
+			log.Printf("Skipping %s: %v", file, err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if file != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !isExportFileName(d.Name()) && !isZipName(d.Name()) {
+			return nil
+		}
+		found, err := discoverFile(file)
+		if err != nil {
+			log.Printf("Skipping %s: %v", file, err)
+			found = []ExportFile{{Path: file, Kind: KindUnknown}}
+		}
+		files = append(files, found...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", path, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	return files, nil
+}
+
+// discoverFile sniffs a single file, or the CSVs in it if it is a ZIP
+// archive
+func discoverFile(path string) ([]ExportFile, error) {
+	if !isZipName(path) {
+		f := ExportFile{Path: path}
+		kind, err := sniffFile(f)
+		if err != nil {
+			return nil, err
+		}
+		f.Kind = kind
+		return []ExportFile{f}, nil
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+	defer archive.Close()
+
+	var entries []string
+	for _, entry := range archive.File {
+		// Slack workspace exports hold JSON files described by channels.json
+		if entry.Name == "channels.json" {
+			return []ExportFile{{Path: path, Kind: KindSlackExport}}, nil
+		}
+		if !entry.FileInfo().IsDir() && isExportFileName(entry.Name) {
+			entries = append(entries, entry.Name)
+		}
+	}
+
+	files := make([]ExportFile, 0, len(entries))
+	for _, entry := range entries {
+		f := ExportFile{Path: path, Entry: entry}
+		kind, err := sniffFile(f)
+		if err != nil {
+			// The other entries of the archive may still be readable
+			log.Printf("Skipping %s: %v", f.Name(), err)
+			kind = KindUnknown
+		}
+		f.Kind = kind
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// sniffFile tells the kind of a CSV file from its header
+func sniffFile(f ExportFile) (FileKind, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	kind, err := sniffCSV(rc)
+	if err != nil {
+		return "", fmt.Errorf("failed to sniff %s: %w", f.Name(), err)
+	}
+	// Thread tables have the columns of message tables and differ only in
+	// which messages they hold, so they are told apart by name
+	if kind == KindMessages && isThreadsName(f.Name()) {
+		kind = KindThreads
+	}
+	return kind, nil
+}
+
+// sniffCSV tells the kind of a CSV table from its header. Tables it does
+// not recognize, and empty ones, are KindUnknown.
+func sniffCSV(r io.Reader) (FileKind, error) {
+	reader := csv.NewReader(r)
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return KindUnknown, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := make(map[string]bool, len(header))
+	for _, col := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(col, "\ufeff"))] = true
+	}
+	hasAll := func(names ...string) bool {
+		for _, name := range names {
+			if !columns[name] {
+				return false
+			}
+		}
+		return true
+	}
+	hasAny := func(names ...string) bool {
+		for _, name := range names {
+			if columns[name] {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case hasAll(messageColumns...):
+		return KindMessages, nil
+	case hasAll("channel_id", "user_id") && !columns["id"]:
+		return KindChannelMembers, nil
+	case columns["id"] && hasAny("is_channel", "is_private", "is_archived", "is_general", "purpose__value", "topic__value", "members"):
+		return KindChannels, nil
+	case columns["id"] && hasAny("real_name", "is_bot", "tz", "is_admin", "deleted"):
+		return KindUsers, nil
+	default:
+		return KindUnknown, nil
+	}
+}
+
+// isExportFileName reports whether a file name looks like a CSV table
+func isExportFileName(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".csv") || strings.HasSuffix(name, ".csv.gz")
+}
+
+// isThreadsName reports whether a file is a thread table by name:
+// threads.csv, compressed or not
+func isThreadsName(name string) bool {
+	base := strings.ToLower(filepath.Base(name))
+	return base == "threads.csv" || base == "threads.csv.gz"
+}
+
+// isZipName reports whether a file name looks like a ZIP archive
+func isZipName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".zip")
+}
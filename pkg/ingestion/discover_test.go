@@ -0,0 +1,154 @@
+package ingestion
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeExportTree writes files below dir, gzip-compressing those ending in
+// .gz
+func writeExportTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		data := []byte(content)
+		if filepath.Ext(name) == ".gz" {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			if _, err := gz.Write(data); err != nil {
+				t.Fatal(err)
+			}
+			if err := gz.Close(); err != nil {
+				t.Fatal(err)
+			}
+			data = buf.Bytes()
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const testMessagesHeader = "channel_id,text,ts,type,user,thread_ts\n"
+
+func TestDiscoverFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeExportTree(t, dir, map[string]string{
+		"2020/messages.csv":           testMessagesHeader + "C1,Hello,1599934232.150700,message,U1,\n",
+		"2020/threads.csv.gz":         testMessagesHeader + "C1,Reply,1599934240.150700,message,U2,1599934232.150700\n",
+		"meta/users.csv":              testUsersCSV,
+		"meta/channels.csv":           testChannelsCSV,
+		"meta/channel_members.csv":    testChannelMembersCSV,
+		"meta/users_channels.csv":     "name,user_id\ngeneral,U3\n",
+		"exported_stats.csv":          "date,total_membership\n2020-01-01,8\n",
+		"notes.txt":                   "not a table",
+		".cache/messages.csv":         testMessagesHeader,
+		"2021/renamed-export.csv":     testMessagesHeader + "C1,Later,1609459200.000100,message,U1,\n",
+		"2021/empty.csv":              "",
+		"2021/channel-members.csv":    "user_id,channel_id\nU1,C1\n",
+		"2021/people-directory.csv":   "id,name,real_name\nU1,alice,Alice\n",
+		"2021/threads-discussion.csv": testMessagesHeader + "C1,Not a thread table,1609459500.000100,message,U1,\n",
+		"2021/broken.csv.gz":          "not gzip",
+		"2021/broken.zip":             "not a zip",
+	})
+	archive := writeSlackZip(t, map[string]string{
+		"messages.csv":    testMessagesHeader + "C2,Zipped,1599934300.000100,message,U1,\n",
+		"sub/users.csv":   testUsersCSV,
+		"sub/readme.json": "{}",
+	})
+	if err := os.Rename(archive, filepath.Join(dir, "archive.zip")); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := DiscoverFiles(dir)
+	if err != nil {
+		t.Fatalf("DiscoverFiles() error = %v", err)
+	}
+	got := make(map[string]FileKind, len(files))
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file.Name())
+		got[rel] = file.Kind
+	}
+	want := map[string]FileKind{
+		"2020/messages.csv":           KindMessages,
+		"2020/threads.csv.gz":         KindThreads,
+		"2021/channel-members.csv":    KindChannelMembers,
+		"2021/empty.csv":              KindUnknown,
+		"2021/people-directory.csv":   KindUsers,
+		"2021/renamed-export.csv":     KindMessages,
+		"2021/threads-discussion.csv": KindMessages,
+		"2021/broken.csv.gz":          KindUnknown,
+		"2021/broken.zip":             KindUnknown,
+		"archive.zip/messages.csv":    KindMessages,
+		"archive.zip/sub/users.csv":   KindUsers,
+		"exported_stats.csv":          KindUnknown,
+		"meta/channel_members.csv":    KindChannelMembers,
+		"meta/channels.csv":           KindChannels,
+		"meta/users.csv":              KindUsers,
+		"meta/users_channels.csv":     KindUnknown,
+	}
+	if len(got) != len(want) {
+		t.Errorf("DiscoverFiles() found %v, want %v", got, want)
+	}
+	for name, kind := range want {
+		if got[name] != kind {
+			t.Errorf("%s: kind %q, want %q", name, got[name], kind)
+		}
+	}
+
+	// A Slack workspace export is returned whole
+	export := writeSlackZip(t, map[string]string{"channels.json": "[]", "users.json": "[]"})
+	files, err = DiscoverFiles(export)
+	if err != nil || len(files) != 1 || files[0].Kind != KindSlackExport {
+		t.Errorf("DiscoverFiles() of a Slack export = %+v, %v", files, err)
+	}
+}
+
+func TestIngestDirectory_Recursive(t *testing.T) {
+	dir := t.TempDir()
+	writeExportTree(t, dir, map[string]string{
+		"users.csv":           testUsersCSV,
+		"exported_stats.csv":  "date,total_membership\n2020-01-01,8\n",
+		"2020/corrupt.csv.gz": "not gzip",
+		"2020/messages.csv":   testMessagesHeader + "C1,Release plan?,1599934232.150700,message,U1,1599934232.150700\n",
+		"2020/threads.csv.gz": testMessagesHeader + "C1,Ship it Monday,1599934240.150700,message,U2,1599934232.150700\n",
+		"2021/messages.csv.gz": testMessagesHeader +
+			"C1,Happy new year,1609459200.000100,message,U1,\n" +
+			"C2,Standup moved,1609459300.000100,message,U2,\n",
+	})
+	archive := writeSlackZip(t, map[string]string{
+		"messages.csv": testMessagesHeader + "C3,From the archive,1609459400.000100,message,U1,\n",
+	})
+	if err := os.Rename(archive, filepath.Join(dir, "2021", "archive.zip")); err != nil {
+		t.Fatal(err)
+	}
+
+	processor := &userAwareMockProcessor{}
+	store := &mockVectorClient{}
+	cfg := DefaultServiceConfig()
+	cfg.FileWorkers = 3
+	stats, err := NewService(store, processor, cfg).IngestDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("IngestDirectory() error = %v", err)
+	}
+	if stats.ProcessedMessages != 5 || stats.TotalMessages != 5 || len(stats.Errors) != 0 {
+		t.Errorf("processed %d of %d messages with errors %v; want 5 of 5 and none", stats.ProcessedMessages, stats.TotalMessages, stats.Errors)
+	}
+	if store.storeCount != 5 {
+		t.Errorf("stored %d documents, want 5", store.storeCount)
+	}
+	if processor.users == nil || processor.users.Len() != 3 {
+		t.Error("processor was not given the user directory")
+	}
+
+	if _, err := NewService(store, processor).IngestDirectory(context.Background(), t.TempDir()); err == nil {
+		t.Error("IngestDirectory() of an empty directory succeeded")
+	}
+}
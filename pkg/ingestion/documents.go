@@ -0,0 +1,150 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// documentFiles returns path if it is a file, or the files under it with
+// one of the given extensions if it is a directory. Hidden directories,
+// node_modules and vendor are skipped. kind describes the files in errors.
+func documentFiles(path, kind string, extensions ...string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	wanted := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		wanted[ext] = true
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			name := entry.Name()
+			if file != path && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if wanted[strings.ToLower(filepath.Ext(file))] {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", path, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no %s files found in %s", kind, path)
+	}
+	return files, nil
+}
+
+// readDocumentFiles streams the records read from each file to fn. A file
+// that cannot be read is reported to fn as a *ParseError carrying its path.
+func readDocumentFiles(ctx context.Context, files []string, read func(file string) ([]models.Record, error), fn RecordFunc) error {
+	for i, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		records, err := read(file)
+		if err != nil {
+			parseErr := &ParseError{Record: i + 1, Fields: map[string]string{"path": file}, Err: fmt.Errorf("%s: %w", file, err)}
+			if fnErr := fn(models.Record{}, parseErr); fnErr != nil {
+				return fnErr
+			}
+			continue
+		}
+		for _, record := range records {
+			if err := fn(record, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sectionedDocument is a document split into heading sections, with the
+// fields its section records share
+type sectionedDocument struct {
+	source string
+	// url is the document's location and the base of its records' IDs
+	url         string
+	title       string
+	author      string
+	createdAt   time.Time
+	updatedAt   time.Time
+	permissions []string
+	tags        []string
+	metadata    map[string]string
+	sections    []markdownSection
+}
+
+// records turns every non-empty section into a record titled with its
+// heading path, or the document title for text before the first heading.
+// Record IDs are the source, the URL and the heading's anchor, e.g.
+// "markdown:docs/api.md#search".
+func (d sectionedDocument) records() []models.Record {
+	records := make([]models.Record, 0, len(d.sections))
+	slugs := make(map[string]int)
+	for _, section := range d.sections {
+		if section.body == "" {
+			continue
+		}
+
+		title := strings.Join(section.headings, " > ")
+		if title == "" {
+			title = d.title
+		}
+
+		id := d.source + ":" + d.url
+		if len(section.headings) > 0 {
+			slug := headingSlug(section.headings[len(section.headings)-1])
+			if n := slugs[slug]; n > 0 {
+				slugs[slug]++
+				slug = fmt.Sprintf("%s-%d", slug, n)
+			} else {
+				slugs[slug] = 1
+			}
+			id += "#" + slug
+		}
+
+		metadata := map[string]string{"path": d.url}
+		if len(section.headings) > 0 {
+			metadata["section"] = title
+		}
+		for key, value := range d.metadata {
+			metadata[key] = value
+		}
+
+		records = append(records, models.Record{
+			ID:          id,
+			Source:      d.source,
+			Title:       title,
+			Text:        title + "\n\n" + section.body,
+			Author:      d.author,
+			URL:         d.url,
+			CreatedAt:   d.createdAt,
+			UpdatedAt:   d.updatedAt,
+			Permissions: d.permissions,
+			Tags:        d.tags,
+			Metadata:    metadata,
+		})
+	}
+	return records
+}
@@ -0,0 +1,415 @@
+package ingestion
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// EmailConnectorName is the name of the built-in mail archive connector
+const EmailConnectorName = "email"
+
+// EmailConnector reads mailing-list archives: mbox files holding many
+// messages and EML files holding one. Messages are threaded by their
+// In-Reply-To and References headers, and quoted replies, signatures and
+// list footers are stripped so each record holds what its sender wrote.
+type EmailConnector struct {
+	// DefaultPermissions are given to every message
+	DefaultPermissions []string
+}
+
+// Name implements Connector
+func (EmailConnector) Name() string {
+	return EmailConnectorName
+}
+
+// Read implements Connector. A reply's thread is found through its
+// References header, or through the parents of In-Reply-To read earlier,
+// so archives are best read in date order. Messages that cannot be parsed
+// are reported to fn as errors.
+func (c EmailConnector) Read(ctx context.Context, path string, fn RecordFunc) error {
+	files, err := documentFiles(path, "mbox or EML", ".mbox", ".mbx", ".eml")
+	if err != nil {
+		return err
+	}
+
+	threads := newEmailThreads()
+	for i, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := c.readFile(ctx, file, threads, fn)
+		if err != nil {
+			parseErr := &ParseError{Record: i + 1, Fields: map[string]string{"path": file}, Err: fmt.Errorf("%s: %w", file, err)}
+			if fnErr := fn(models.Record{}, parseErr); fnErr != nil {
+				return fnErr
+			}
+		}
+	}
+	return nil
+}
+
+// readFile streams the messages of one mbox or EML file to fn
+func (c EmailConnector) readFile(ctx context.Context, path string, threads *emailThreads, fn RecordFunc) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	url := filepath.ToSlash(path)
+	emit := func(index int, raw []byte) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, err := c.parseMessage(raw, threads)
+		if err != nil {
+			return fn(models.Record{}, &ParseError{Record: index, Fields: map[string]string{"path": path}, Err: fmt.Errorf("%s message %d: %w", path, index, err)})
+		}
+		if record.ID == "" {
+			record.ID = fmt.Sprintf("%s:%s#%d", EmailConnectorName, url, index)
+		}
+		record.Metadata["path"] = url
+		return fn(record, nil)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".eml") {
+		raw, err := io.ReadAll(file)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		return emit(1, raw)
+	}
+	return splitMbox(file, emit)
+}
+
+// splitMbox calls fn with each message of an mbox file. Messages start at
+// "From " lines; lines quoted as ">From " by the mboxrd format are
+// unquoted.
+func splitMbox(r io.Reader, fn func(index int, raw []byte) error) error {
+	reader := bufio.NewReader(r)
+	var message bytes.Buffer
+	index := 0
+	started := false
+	previousBlank := true
+
+	flush := func() error {
+		if !started {
+			return nil
+		}
+		index++
+		err := fn(index, bytes.Clone(message.Bytes()))
+		message.Reset()
+		return err
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			trimmed := strings.TrimRight(line, "\r\n")
+			switch {
+			case strings.HasPrefix(line, "From ") && previousBlank:
+				if err := flush(); err != nil {
+					return err
+				}
+				started = true
+			case started:
+				if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") && strings.HasPrefix(line, ">") {
+					line = line[1:]
+				}
+				message.WriteString(line)
+			}
+			previousBlank = trimmed == ""
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read mbox: %w", err)
+		}
+	}
+	if !started {
+		return fmt.Errorf("not an mbox file")
+	}
+	return flush()
+}
+
+// emailThreads tracks the parent of every message read so far, to find
+// the thread of replies without a References header
+type emailThreads struct {
+	parents map[string]string
+}
+
+func newEmailThreads() *emailThreads {
+	return &emailThreads{parents: make(map[string]string)}
+}
+
+// root returns the ID of the first message of the thread a message with
+// the given parent and references belongs to
+func (t *emailThreads) root(id, parent string, references []string) string {
+	if len(references) > 0 {
+		return references[0]
+	}
+	root := id
+	for seen := map[string]bool{id: true}; parent != "" && !seen[parent]; parent = t.parents[parent] {
+		seen[parent] = true
+		root = parent
+	}
+	return root
+}
+
+// parseMessage turns a raw message into a record
+func (c EmailConnector) parseMessage(raw []byte, threads *emailThreads) (models.Record, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return models.Record{}, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	decoder := new(mime.WordDecoder)
+	decodeHeader := func(name string) string {
+		value := msg.Header.Get(name)
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			value = decoded
+		}
+		return strings.TrimSpace(collapseSpace(value))
+	}
+
+	messageID := firstMessageID(msg.Header.Get("Message-Id"))
+	inReplyTo := firstMessageID(msg.Header.Get("In-Reply-To"))
+	references := messageIDs(msg.Header.Get("References"))
+	if inReplyTo == "" && len(references) > 0 {
+		inReplyTo = references[len(references)-1]
+	}
+
+	body, err := emailBody(msg.Header, msg.Body)
+	if err != nil {
+		return models.Record{}, err
+	}
+
+	subject := decodeHeader("Subject")
+	record := models.Record{
+		Source:      EmailConnectorName,
+		Title:       subject,
+		Permissions: c.DefaultPermissions,
+		Tags:        []string{"email"},
+		Metadata:    make(map[string]string),
+	}
+	if text := stripEmailReply(body); text != "" {
+		record.Text = subject + "\n\n" + text
+	}
+
+	if from, err := mail.ParseAddress(decodeHeader("From")); err == nil {
+		record.Author = from.Name
+		record.AuthorID = strings.ToLower(from.Address)
+		if record.Author == "" {
+			record.Author = from.Address
+		}
+	} else {
+		record.Author = decodeHeader("From")
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		record.CreatedAt = date
+		record.UpdatedAt = date
+	}
+
+	if messageID != "" {
+		threads.parents[messageID] = inReplyTo
+		record.ID = EmailConnectorName + ":" + messageID
+		record.URL = "mid:" + messageID
+		record.ThreadID = EmailConnectorName + ":" + threads.root(messageID, inReplyTo, references)
+	}
+	if inReplyTo != "" {
+		record.ParentID = EmailConnectorName + ":" + inReplyTo
+	}
+	if list := listName(msg.Header.Get("List-Id")); list != "" {
+		record.Tags = append(record.Tags, list)
+	}
+	return record, nil
+}
+
+// emailBody returns the plain text of a message body, preferring the
+// text/plain alternative of multipart messages and falling back to text
+// extracted from HTML
+func emailBody(header map[string][]string, body io.Reader) (string, error) {
+	get := func(name string) string {
+		if values := header[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	mediaType, params, err := mime.ParseMediaType(get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		var plain, htmlText string
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("failed to read MIME part: %w", err)
+			}
+			if strings.HasPrefix(strings.ToLower(part.Header.Get("Content-Disposition")), "attachment") {
+				continue
+			}
+			text, err := emailBody(part.Header, part)
+			if err != nil {
+				return "", err
+			}
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			switch {
+			case partType == "text/html" && htmlText == "":
+				htmlText = text
+			case plain == "":
+				plain = text
+			}
+		}
+		if plain != "" {
+			return plain, nil
+		}
+		return htmlText, nil
+	}
+
+	if !strings.HasPrefix(mediaType, "text/") {
+		return "", nil
+	}
+
+	var reader io.Reader = body
+	switch strings.ToLower(strings.TrimSpace(get("Content-Transfer-Encoding"))) {
+	case "quoted-printable":
+		reader = quotedprintable.NewReader(reader)
+	case "base64":
+		reader = base64.NewDecoder(base64.StdEncoding, reader)
+	}
+	if label := params["charset"]; label != "" {
+		if decoded, err := charset.NewReaderLabel(label, reader); err == nil {
+			reader = decoded
+		}
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode message body: %w", err)
+	}
+	if mediaType == "text/html" {
+		page, err := parseHTMLPage(bytes.NewReader(content))
+		if err != nil {
+			return "", err
+		}
+		return page.text, nil
+	}
+	return string(content), nil
+}
+
+var (
+	messageIDPattern = regexp.MustCompile(`<([^<>\s]+)>`)
+	// replyAttribution matches lines introducing a quote, such as
+	// "On Tue, Mar 1, 2024 at 10:00 AM Alice <alice@example.com> wrote:"
+	replyAttribution = regexp.MustCompile(`(?i)^(on\b.*|.*\bwrote|.*\bschrieb)\s*:\s*$`)
+)
+
+// messageIDs returns the message IDs in a header such as References
+func messageIDs(header string) []string {
+	var ids []string
+	for _, match := range messageIDPattern.FindAllStringSubmatch(header, -1) {
+		ids = append(ids, match[1])
+	}
+	return ids
+}
+
+// firstMessageID returns the first message ID in a header, accepting IDs
+// written without angle brackets
+func firstMessageID(header string) string {
+	if ids := messageIDs(header); len(ids) > 0 {
+		return ids[0]
+	}
+	return strings.Trim(strings.TrimSpace(header), "<>")
+}
+
+// listName returns the list address of a List-Id header, e.g.
+// "dev.kafka.apache.org" for "<dev.kafka.apache.org>"
+func listName(header string) string {
+	if ids := messageIDs(header); len(ids) > 0 {
+		return ids[0]
+	}
+	return strings.TrimSpace(header)
+}
+
+// stripEmailReply removes quoted text, the lines introducing it,
+// forwarded originals, signatures and mailing-list footers
+func stripEmailReply(body string) string {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	kept := make([]string, 0, len(lines))
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		// Everything after these belongs to someone else
+		if line == "--" || lines[i] == "-- " ||
+			strings.HasPrefix(trimmed, "-----Original Message-----") ||
+			isListFooter(lines, i) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		if replyAttribution.MatchString(trimmed) && nextQuoted(lines, i+1) {
+			continue
+		}
+		// Attributions wrapped over two lines
+		if i+1 < len(lines) && replyAttribution.MatchString(trimmed+" "+strings.TrimSpace(lines[i+1])) && nextQuoted(lines, i+2) {
+			i++
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(kept, "\n"), "\n\n"))
+}
+
+// nextQuoted reports whether the first non-blank line from i on is quoted
+func nextQuoted(lines []string, i int) bool {
+	for ; i < len(lines); i++ {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed != "" {
+			return strings.HasPrefix(trimmed, ">")
+		}
+	}
+	return false
+}
+
+// isListFooter reports whether line i starts a mailing-list footer, such
+// as Apache's dashed rule followed by unsubscribe instructions
+func isListFooter(lines []string, i int) bool {
+	trimmed := strings.TrimSpace(lines[i])
+	if len(trimmed) < 20 || strings.Trim(trimmed, "-_") != "" {
+		return false
+	}
+	for _, next := range lines[i+1 : min(i+4, len(lines))] {
+		lower := strings.ToLower(next)
+		if strings.Contains(lower, "unsubscribe") || strings.Contains(lower, "mailing list") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// kafkaArchive is an excerpt of a mailing-list archive in mbox format
+const kafkaArchive = `From alice@example.com Fri Mar  1 10:00:00 2024
+From: Alice Smith <alice@example.com>
+To: dev@kafka.apache.org
+Subject: [DISCUSS] KIP-999 Tiered storage
+Date: Fri, 1 Mar 2024 10:00:00 +0000
+Message-ID: <kip999@example.com>
+List-Id: <dev.kafka.apache.org>
+
+Let's discuss moving old segments to object storage.
+>From the benchmarks, reads stay fast.
+
+From bob@example.com Fri Mar  1 11:00:00 2024
+From: =?UTF-8?Q?Bj=C3=B6rn?= <Bob@Example.com>
+Subject: Re: [DISCUSS] KIP-999 Tiered storage
+Date: Fri, 1 Mar 2024 11:00:00 +0000
+Message-ID: <reply1@example.com>
+In-Reply-To: <kip999@example.com>
+References: <kip999@example.com>
+MIME-Version: 1.0
+Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
++1, but we need a migration plan for existing top=
+ics.
+
+On Fri, Mar 1, 2024 at 10:00 AM Alice Smith <alice@example.com>
+wrote:
+> Let's discuss moving old segments to object storage.
+
+--=20
+Bj=C3=B6rn, Streaming team
+
+---------------------------------------------------------------------
+To unsubscribe, e-mail: dev-unsubscribe@kafka.apache.org
+--b1
+Content-Type: text/html; charset=utf-8
+
+<p>+1 in HTML</p>
+--b1--
+
+From carol@example.com Fri Mar  1 12:00:00 2024
+From: carol@example.com
+Subject: Re: [DISCUSS] KIP-999 Tiered storage
+Date: Fri, 1 Mar 2024 12:00:00 +0000
+Message-ID: <reply2@example.com>
+In-Reply-To: <reply1@example.com>
+
+Agreed on the migration plan.
+`
+
+func TestEmailConnector_Read(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "dev-2024-03.mbox"), []byte(kafkaArchive), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	eml := "From: Dave <dave@example.com>\r\nSubject: Standalone\r\nDate: Sat, 2 Mar 2024 09:00:00 +0000\r\n" +
+		"Content-Type: text/html\r\n\r\n<html><body><p>Hello <b>list</b></p></body></html>\r\n"
+	if err := os.WriteFile(filepath.Join(dir, "single.eml"), []byte(eml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.mbox"), []byte("not an archive\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	records := make(map[string]models.Record)
+	var readErrors []error
+	err := EmailConnector{DefaultPermissions: []string{"public"}}.Read(context.Background(), dir, func(record models.Record, err error) error {
+		if err != nil {
+			readErrors = append(readErrors, err)
+			return nil
+		}
+		records[record.ID] = record
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if len(records) != 4 {
+		t.Fatalf("read %d records, want 4: %+v", len(records), records)
+	}
+	var parseErr *ParseError
+	if len(readErrors) != 1 || !errors.As(readErrors[0], &parseErr) {
+		t.Errorf("unexpected read errors: %v", readErrors)
+	}
+
+	kip := records["email:kip999@example.com"]
+	if kip.Text != "[DISCUSS] KIP-999 Tiered storage\n\nLet's discuss moving old segments to object storage.\nFrom the benchmarks, reads stay fast." {
+		t.Errorf("unexpected text: %q", kip.Text)
+	}
+	if kip.Author != "Alice Smith" || kip.AuthorID != "alice@example.com" || kip.URL != "mid:kip999@example.com" {
+		t.Errorf("unexpected sender: %+v", kip)
+	}
+	if !kip.CreatedAt.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("CreatedAt = %v", kip.CreatedAt)
+	}
+	if !reflect.DeepEqual(kip.Tags, []string{"email", "dev.kafka.apache.org"}) || kip.ThreadID != "email:kip999@example.com" {
+		t.Errorf("unexpected tags or thread: %+v", kip)
+	}
+
+	reply := records["email:reply1@example.com"]
+	if reply.Text != "Re: [DISCUSS] KIP-999 Tiered storage\n\n+1, but we need a migration plan for existing topics." {
+		t.Errorf("quotes, signature or footer not stripped: %q", reply.Text)
+	}
+	if reply.Author != "Björn" || reply.AuthorID != "bob@example.com" {
+		t.Errorf("unexpected sender: %q <%s>", reply.Author, reply.AuthorID)
+	}
+	if reply.ThreadID != kip.ThreadID || reply.ParentID != kip.ID {
+		t.Errorf("reply not threaded: thread %q, parent %q", reply.ThreadID, reply.ParentID)
+	}
+
+	// Without References, the thread is found through earlier messages
+	nested := records["email:reply2@example.com"]
+	if nested.ThreadID != kip.ThreadID || nested.ParentID != reply.ID || nested.Author != "carol@example.com" {
+		t.Errorf("nested reply not threaded: %+v", nested)
+	}
+
+	single := records["email:"+filepath.ToSlash(filepath.Join(dir, "single.eml"))+"#1"]
+	if single.Text != "Standalone\n\nHello list" || single.Permissions[0] != "public" {
+		t.Errorf("unexpected EML record: %+v", single)
+	}
+}
+
+func TestStripEmailReply(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "plain",
+			body: "Sounds good.\n\nThanks",
+			want: "Sounds good.\n\nThanks",
+		},
+		{
+			name: "interleaved quotes",
+			body: "> Should we ship?\nYes.\n> And docs?\nLater.",
+			want: "Yes.\nLater.",
+		},
+		{
+			name: "attribution kept without a quote",
+			body: "Alice wrote:\nthe plan is fine",
+			want: "Alice wrote:\nthe plan is fine",
+		},
+		{
+			name: "outlook original",
+			body: "Done.\n\n-----Original Message-----\nFrom: Alice\nCan you fix it?",
+			want: "Done.",
+		},
+		{
+			name: "signature",
+			body: "Ship it.\n-- \nBob\nStaff Engineer",
+			want: "Ship it.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripEmailReply(tt.body); got != tt.want {
+				t.Errorf("stripEmailReply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package ingestion
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// UserAwareProcessor is implemented by processors that can resolve user IDs
+// to names using an export's user directory
+type UserAwareProcessor interface {
+	SetUserDirectory(users *UserDirectory)
+}
+
+// ChannelAwareProcessor is implemented by processors that can use channel
+// metadata and memberships from an export
+type ChannelAwareProcessor interface {
+	SetChannelDirectory(channels *ChannelDirectory)
+}
+
+// LoadUsers loads a users.csv file and hands it to the processor so that
+// authors and mentions are stored as names. It is a no-op for processors
+// that do not implement UserAwareProcessor.
+func (s *Service) LoadUsers(path string) error {
+	if _, ok := s.processor.(UserAwareProcessor); !ok {
+		return nil
+	}
+
+	users, err := LoadUsers(path)
+	if err != nil {
+		return err
+	}
+	s.setUsers(users)
+	log.Printf("Loaded %d users from %s", users.Len(), path)
+	return nil
+}
+
+// LoadChannels loads channels.csv and, if membersPath is not empty,
+// channel_members.csv and hands them to the processor so that documents get
+// channel names and membership-based permissions. It is a no-op for
+// processors that do not implement ChannelAwareProcessor.
+func (s *Service) LoadChannels(channelsPath, membersPath string) error {
+	if _, ok := s.processor.(ChannelAwareProcessor); !ok {
+		return nil
+	}
+
+	channels, err := LoadChannels(channelsPath, membersPath)
+	if err != nil {
+		return err
+	}
+	s.setChannels(channels)
+	log.Printf("Loaded %d channels from %s", channels.Len(), channelsPath)
+	return nil
+}
+
+// setUsers hands a user directory to the processor
+func (s *Service) setUsers(users *UserDirectory) {
+	if processor, ok := s.processor.(UserAwareProcessor); ok {
+		processor.SetUserDirectory(users)
+	}
+	s.mu.Lock()
+	s.users = users
+	s.mu.Unlock()
+}
+
+// setChannels hands a channel directory to the processor
+func (s *Service) setChannels(channels *ChannelDirectory) {
+	if processor, ok := s.processor.(ChannelAwareProcessor); ok {
+		processor.SetChannelDirectory(channels)
+	}
+	s.mu.Lock()
+	s.channels = channels
+	s.mu.Unlock()
+}
+
+// loadExportMetadata loads the users and channels tables found in dir,
+// unless already loaded
+func (s *Service) loadExportMetadata(dir string) []error {
+	s.mu.Lock()
+	haveUsers, haveChannels := s.users != nil, s.channels != nil
+	s.mu.Unlock()
+
+	var errs []error
+	if !haveUsers {
+		if path := filepath.Join(dir, UsersFile); fileExists(path) {
+			if err := s.LoadUsers(path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if !haveChannels {
+		if path := filepath.Join(dir, ChannelsFile); fileExists(path) {
+			membersPath := filepath.Join(dir, ChannelMembersFile)
+			if !fileExists(membersPath) {
+				membersPath = ""
+			}
+			if err := s.LoadChannels(path, membersPath); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// loadExportFiles loads the users, channels and channel members tables
+// among files found by DiscoverFiles, along with the users and channels of
+// Slack export ZIPs, replacing those loaded before. Tables of the same kind
+// are merged, so exports split over several directories resolve alike.
+func (s *Service) loadExportFiles(files []ExportFile) []error {
+	_, wantUsers := s.processor.(UserAwareProcessor)
+	_, wantChannels := s.processor.(ChannelAwareProcessor)
+
+	var (
+		users    *UserDirectory
+		channels *ChannelDirectory
+		members  []ExportFile
+		errs     []error
+	)
+	for _, file := range files {
+		switch {
+		case file.Kind == KindUsers && wantUsers:
+			err := readExportFile(file, func(r io.Reader) error {
+				dir, err := ParseUsers(r)
+				if err == nil {
+					users = users.merge(dir)
+				}
+				return err
+			})
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case file.Kind == KindChannels && wantChannels:
+			err := readExportFile(file, func(r io.Reader) error {
+				dir, err := ParseChannels(r)
+				if err == nil {
+					channels = channels.merge(dir)
+				}
+				return err
+			})
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case file.Kind == KindChannelMembers && wantChannels:
+			members = append(members, file)
+		case file.Kind == KindSlackExport && (wantUsers || wantChannels):
+			zipUsers, zipChannels, err := ReadSlackZipMetadata(file.Path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to read export metadata: %w", err))
+				continue
+			}
+			users = users.merge(zipUsers)
+			channels = channels.merge(zipChannels)
+		}
+	}
+
+	// Memberships only apply to known channels
+	if channels != nil {
+		for _, file := range members {
+			if err := readExportFile(file, channels.ParseMembers); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if users != nil && wantUsers {
+		s.setUsers(users)
+		log.Printf("Loaded %d users", users.Len())
+	}
+	if channels != nil && wantChannels {
+		s.setChannels(channels)
+		log.Printf("Loaded %d channels", channels.Len())
+	}
+	return errs
+}
+
+// readExportFile opens a file found by DiscoverFiles and reads it with fn
+func readExportFile(file ExportFile, fn func(r io.Reader) error) error {
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := fn(r); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", file.Name(), err)
+	}
+	return nil
+}
+
+// fileExists reports whether path is an existing regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
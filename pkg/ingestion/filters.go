@@ -0,0 +1,290 @@
+package ingestion
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// NotIncludedRule is the name messages and records are counted under in
+// IngestionStats.RuleMatches when include rules are configured and none of
+// them matched
+const NotIncludedRule = "not-included"
+
+// FilterRule matches messages and records by where they were posted, who
+// posted them and what they say. A rule matches when every condition it
+// sets matches; a condition listing several values matches any of them.
+type FilterRule struct {
+	// Name identifies the rule in IngestionStats.RuleMatches. Unnamed
+	// rules are called e.g. "exclude-2" after their position.
+	Name string `yaml:"name" json:"name,omitempty"`
+	// Channels are channel IDs or names, with or without the leading "#"
+	Channels []string `yaml:"channels" json:"channels,omitempty"`
+	// Users are user IDs, user names or real names
+	Users []string `yaml:"users" json:"users,omitempty"`
+	// Bots are bot IDs or bot names such as "GitHub"; "*" matches any bot
+	Bots []string `yaml:"bots" json:"bots,omitempty"`
+	// Subtypes are Slack message subtypes, e.g. "channel_join"
+	Subtypes []string `yaml:"subtypes" json:"subtypes,omitempty"`
+	// Archived matches messages in archived channels
+	Archived bool `yaml:"archived" json:"archived,omitempty"`
+	// After and Before bound the creation time, as "2006-01-02" or RFC
+	// 3339. After is inclusive and Before exclusive.
+	After  string `yaml:"after" json:"after,omitempty"`
+	Before string `yaml:"before" json:"before,omitempty"`
+	// Text is a regular expression the text must match, as written in the
+	// source, e.g. with Slack markup
+	Text string `yaml:"text" json:"text,omitempty"`
+}
+
+// FilterConfig decides which messages and records are ingested. Exclude
+// rules are tried first, in order; what none of them match is ingested if
+// there are no include rules or one of the include rules matches.
+type FilterConfig struct {
+	Include []FilterRule `yaml:"include" json:"include,omitempty"`
+	Exclude []FilterRule `yaml:"exclude" json:"exclude,omitempty"`
+}
+
+// LoadFilterConfig reads filter rules from a YAML or JSON file, e.g.
+//
+//	exclude:
+//	  - name: membership
+//	    subtypes: [channel_join, channel_leave]
+//	  - name: before-migration
+//	    before: 2020-01-01
+func LoadFilterConfig(path string) (FilterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FilterConfig{}, fmt.Errorf("failed to read filter rules: %w", err)
+	}
+	var config FilterConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return FilterConfig{}, fmt.Errorf("failed to parse filter rules %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Filter applies a FilterConfig. It is safe for concurrent use.
+type Filter struct {
+	include []filterRule
+	exclude []filterRule
+}
+
+// NewFilter compiles the rules of config, checking their dates and
+// regular expressions
+func NewFilter(config FilterConfig) (*Filter, error) {
+	f := &Filter{}
+	var err error
+	if f.include, err = compileRules("include", config.Include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compileRules("exclude", config.Exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// filterRule is a compiled FilterRule
+type filterRule struct {
+	name     string
+	channels map[string]bool
+	users    map[string]bool
+	bots     map[string]bool
+	anyBot   bool
+	subtypes map[string]bool
+	archived bool
+	after    time.Time
+	before   time.Time
+	text     *regexp.Regexp
+}
+
+// compileRules compiles the include or exclude rules of a FilterConfig
+func compileRules(kind string, rules []FilterRule) ([]filterRule, error) {
+	compiled := make([]filterRule, 0, len(rules))
+	for i, rule := range rules {
+		c := filterRule{
+			name:     rule.Name,
+			channels: foldedSet(rule.Channels, "#"),
+			users:    foldedSet(rule.Users, "@"),
+			bots:     foldedSet(rule.Bots, ""),
+			subtypes: foldedSet(rule.Subtypes, ""),
+			archived: rule.Archived,
+		}
+		if c.name == "" {
+			c.name = fmt.Sprintf("%s-%d", kind, i+1)
+		}
+		c.anyBot = c.bots["*"]
+
+		var err error
+		if c.after, err = parseFilterTime(rule.After); err != nil {
+			return nil, fmt.Errorf("invalid after in filter rule %s: %w", c.name, err)
+		}
+		if c.before, err = parseFilterTime(rule.Before); err != nil {
+			return nil, fmt.Errorf("invalid before in filter rule %s: %w", c.name, err)
+		}
+		if rule.Text != "" {
+			if c.text, err = regexp.Compile(rule.Text); err != nil {
+				return nil, fmt.Errorf("invalid text in filter rule %s: %w", c.name, err)
+			}
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// foldedSet returns the values lower-cased and without prefix, or nil if
+// there are none
+func foldedSet(values []string, prefix string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if prefix != "" {
+			value = strings.TrimPrefix(value, prefix)
+		}
+		if value != "" {
+			set[value] = true
+		}
+	}
+	return set
+}
+
+// parseFilterTime parses a date or an RFC 3339 time, "" being the zero time
+func parseFilterTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// filterSubject is what rules are matched against
+type filterSubject struct {
+	channelID string
+	channel   models.SlackChannel
+	userID    string
+	userNames []string
+	botID     string
+	botName   string
+	subtype   string
+	createdAt time.Time
+	text      string
+}
+
+// matches reports whether every condition of the rule matches s
+func (r filterRule) matches(s filterSubject) bool {
+	if r.channels != nil && !anyIn(r.channels, s.channelID, s.channel.Name) {
+		return false
+	}
+	if r.users != nil && !anyIn(r.users, append([]string{s.userID}, s.userNames...)...) {
+		return false
+	}
+	if r.bots != nil && (s.botID == "" || !r.anyBot && !anyIn(r.bots, s.botID, s.botName)) {
+		return false
+	}
+	if r.subtypes != nil && !anyIn(r.subtypes, s.subtype) {
+		return false
+	}
+	if r.archived && !s.channel.IsArchived {
+		return false
+	}
+	if !r.after.IsZero() && s.createdAt.Before(r.after) {
+		return false
+	}
+	if !r.before.IsZero() && !s.createdAt.Before(r.before) {
+		return false
+	}
+	if r.text != nil && !r.text.MatchString(s.text) {
+		return false
+	}
+	return true
+}
+
+// anyIn reports whether any non-empty value is in set, ignoring case
+func anyIn(set map[string]bool, values ...string) bool {
+	for _, value := range values {
+		if value != "" && set[strings.ToLower(value)] {
+			return true
+		}
+	}
+	return false
+}
+
+// decide reports whether s is ingested and the name of the rule that
+// decided it, if any
+func (f *Filter) decide(s filterSubject) (keep bool, rule string) {
+	for _, r := range f.exclude {
+		if r.matches(s) {
+			return false, r.name
+		}
+	}
+	if len(f.include) == 0 {
+		return true, ""
+	}
+	for _, r := range f.include {
+		if r.matches(s) {
+			return true, r.name
+		}
+	}
+	return false, NotIncludedRule
+}
+
+// filterSubject describes a record to the filter, looking up its channel
+// and author in the loaded export tables
+func (s *Service) filterSubject(record models.Record) filterSubject {
+	subject := filterSubject{
+		channelID: record.Metadata["channel"],
+		userID:    record.AuthorID,
+		botID:     record.Metadata["bot_id"],
+		botName:   record.Metadata["bot_name"],
+		subtype:   record.Metadata["subtype"],
+		createdAt: record.CreatedAt,
+		text:      record.Text,
+	}
+	if record.Author != "" {
+		subject.userNames = append(subject.userNames, record.Author)
+	}
+
+	s.mu.Lock()
+	users, channels := s.users, s.channels
+	s.mu.Unlock()
+	if channels != nil && subject.channelID != "" {
+		subject.channel, _ = channels.Lookup(subject.channelID)
+	}
+	if users != nil && subject.userID != "" {
+		if user, ok := users.Lookup(subject.userID); ok {
+			subject.userNames = append(subject.userNames, user.Name, user.RealName)
+		}
+	}
+	return subject
+}
+
+// keepRecord applies the service's filter to a record, counting the rule
+// that decided it in stats
+func (s *Service) keepRecord(record models.Record, stats *IngestionStats) bool {
+	if s.filter == nil {
+		return true
+	}
+	keep, rule := s.filter.decide(s.filterSubject(record))
+	stats.countFiltered(keep, rule)
+	return keep
+}
+
+// keepMessage applies the service's filter to a message, counting the rule
+// that decided it in stats
+func (s *Service) keepMessage(msg models.SlackMessage, stats *IngestionStats) bool {
+	if s.filter == nil {
+		return true
+	}
+	return s.keepRecord(msg.Record(), stats)
+}
@@ -0,0 +1,244 @@
+package ingestion
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+func TestFilter_Decide(t *testing.T) {
+	service := NewService(&mockVectorClient{}, &mockDocumentProcessor{})
+	service.setUsers(NewUserDirectory(models.SlackUser{ID: "U1", Name: "alice", RealName: "Alice Adams"}))
+	service.setChannels(NewChannelDirectory(
+		models.SlackChannel{ID: "C1", Name: "general"},
+		models.SlackChannel{ID: "C2", Name: "old-team", IsArchived: true},
+	))
+
+	at := func(day int) time.Time { return time.Date(2021, 1, day, 12, 0, 0, 0, time.UTC) }
+	message := models.SlackMessage{MessageID: "m", Channel: "C1", User: "U2", Content: "hello", Timestamp: at(10)}
+	with := func(change func(msg *models.SlackMessage)) models.SlackMessage {
+		msg := message
+		change(&msg)
+		return msg
+	}
+
+	tests := []struct {
+		name     string
+		config   FilterConfig
+		msg      models.SlackMessage
+		wantKeep bool
+		wantRule string
+	}{
+		{"no rules", FilterConfig{}, message, true, ""},
+		{
+			"subtype",
+			FilterConfig{Exclude: []FilterRule{{Name: "membership", Subtypes: []string{"channel_join", "channel_leave"}}}},
+			with(func(msg *models.SlackMessage) { msg.Subtype = "channel_leave" }),
+			false, "membership",
+		},
+		{
+			"other subtype",
+			FilterConfig{Exclude: []FilterRule{{Name: "membership", Subtypes: []string{"channel_join"}}}},
+			with(func(msg *models.SlackMessage) { msg.Subtype = "thread_broadcast" }),
+			true, "",
+		},
+		{
+			"channel by name",
+			FilterConfig{Exclude: []FilterRule{{Channels: []string{"#General"}}}},
+			message,
+			false, "exclude-1",
+		},
+		{
+			"user by real name",
+			FilterConfig{Exclude: []FilterRule{{Name: "alice", Users: []string{"Alice Adams"}}}},
+			with(func(msg *models.SlackMessage) { msg.User = "U1" }),
+			false, "alice",
+		},
+		{
+			"bot by name",
+			FilterConfig{Exclude: []FilterRule{{Name: "github", Bots: []string{"github"}}}},
+			with(func(msg *models.SlackMessage) { msg.BotID, msg.BotName = "B1", "GitHub" }),
+			false, "github",
+		},
+		{
+			"any bot spares people",
+			FilterConfig{Exclude: []FilterRule{{Name: "bots", Bots: []string{"*"}}}},
+			message,
+			true, "",
+		},
+		{
+			"archived channel",
+			FilterConfig{Exclude: []FilterRule{{Name: "archived", Archived: true}}},
+			with(func(msg *models.SlackMessage) { msg.Channel = "C2" }),
+			false, "archived",
+		},
+		{
+			"before cutoff",
+			FilterConfig{Exclude: []FilterRule{{Name: "old", Before: "2021-01-10"}}},
+			with(func(msg *models.SlackMessage) { msg.Timestamp = at(9) }),
+			false, "old",
+		},
+		{
+			"cutoff is exclusive",
+			FilterConfig{Exclude: []FilterRule{{Name: "old", Before: "2021-01-10T12:00:00Z"}}},
+			message,
+			true, "",
+		},
+		{
+			"all conditions must match",
+			FilterConfig{Exclude: []FilterRule{{Name: "standup", Channels: []string{"C1"}, Text: `(?i)^standup`}}},
+			message,
+			true, "",
+		},
+		{
+			"text",
+			FilterConfig{Exclude: []FilterRule{{Name: "greetings", Text: `^(hi|hello)$`}}},
+			message,
+			false, "greetings",
+		},
+		{
+			"include",
+			FilterConfig{Include: []FilterRule{{Name: "general", Channels: []string{"general"}}}},
+			message,
+			true, "general",
+		},
+		{
+			"not included",
+			FilterConfig{Include: []FilterRule{{Name: "random", Channels: []string{"random"}}}},
+			message,
+			false, NotIncludedRule,
+		},
+		{
+			"exclude wins over include",
+			FilterConfig{
+				Include: []FilterRule{{Name: "general", Channels: []string{"general"}}},
+				Exclude: []FilterRule{{Name: "greetings", Text: "hello"}},
+			},
+			message,
+			false, "greetings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.config)
+			if err != nil {
+				t.Fatalf("NewFilter() error = %v", err)
+			}
+			keep, rule := filter.decide(service.filterSubject(tt.msg.Record()))
+			if keep != tt.wantKeep || rule != tt.wantRule {
+				t.Errorf("decide() = %v, %q; want %v, %q", keep, rule, tt.wantKeep, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestNewFilter_Invalid(t *testing.T) {
+	for _, rule := range []FilterRule{
+		{Text: "(unclosed"},
+		{After: "last week"},
+		{Before: "2021-13-01"},
+	} {
+		if _, err := NewFilter(FilterConfig{Exclude: []FilterRule{rule}}); err == nil {
+			t.Errorf("NewFilter(%+v) succeeded", rule)
+		}
+	}
+}
+
+func TestLoadFilterConfig(t *testing.T) {
+	path := writeCSV(t, t.TempDir(), "filter.yaml", `exclude:
+  - name: membership
+    subtypes: [channel_join, channel_leave]
+  - name: before-migration
+    before: 2020-01-01
+include:
+  - channels: ["#eng", C123]
+`)
+
+	config, err := LoadFilterConfig(path)
+	if err != nil {
+		t.Fatalf("LoadFilterConfig() error = %v", err)
+	}
+	want := FilterConfig{
+		Include: []FilterRule{{Channels: []string{"#eng", "C123"}}},
+		Exclude: []FilterRule{
+			{Name: "membership", Subtypes: []string{"channel_join", "channel_leave"}},
+			{Name: "before-migration", Before: "2020-01-01"},
+		},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("LoadFilterConfig() = %+v, want %+v", config, want)
+	}
+
+	if _, err := LoadFilterConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadFilterConfig() of a missing file succeeded")
+	}
+}
+
+func TestService_IngestFiltered(t *testing.T) {
+	path := writeCSV(t, t.TempDir(), "messages.csv", `channel_id,text,ts,type,subtype,user,bot_id
+C1,Deploys happen on Tuesdays,1609502400.000100,message,,U1,
+C1,<@U2> has joined the channel,1609502460.000100,message,channel_join,U2,
+C1,Build passed,1609502520.000100,message,bot_message,,B2
+C1,Old news,1577880000.000100,message,,U1,
+`)
+
+	filter, err := NewFilter(FilterConfig{Exclude: []FilterRule{
+		{Name: "membership", Subtypes: []string{"channel_join", "channel_leave"}},
+		{Name: "ci", Bots: []string{"B2"}},
+		{Name: "before-2021", Before: "2021-01-01"},
+	}})
+	if err != nil {
+		t.Fatalf("NewFilter() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	var processed []string
+	processor := &mockDocumentProcessor{processFunc: func(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		processed = append(processed, msg.Content)
+		return []vector.Document{{ID: msg.MessageID}}, nil
+	}}
+	cfg := DefaultServiceConfig()
+	cfg.Filter = filter
+	service := NewService(&mockVectorClient{}, processor, cfg)
+
+	stats, err := service.IngestFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("IngestFile() error = %v", err)
+	}
+	sort.Strings(processed)
+	if !reflect.DeepEqual(processed, []string{"Deploys happen on Tuesdays"}) {
+		t.Errorf("processed %v", processed)
+	}
+	wantMatches := map[string]int{"membership": 1, "ci": 1, "before-2021": 1}
+	if stats.FilteredMessages != 3 || !reflect.DeepEqual(stats.RuleMatches, wantMatches) {
+		t.Errorf("FilteredMessages = %d, RuleMatches = %v; want 3, %v", stats.FilteredMessages, stats.RuleMatches, wantMatches)
+	}
+	if summary := stats.GetSummary(); summary["filtered_messages"] != 3 {
+		t.Errorf("summary = %v", summary)
+	}
+
+	// Records from other connectors are filtered the same way
+	connector := staticConnector{name: "notes", records: []*models.Record{
+		{ID: "notes:a", Source: "notes", Text: "Runbook", CreatedAt: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "notes:b", Source: "notes", Text: "Draft", CreatedAt: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}}
+	cfg.Connectors = NewRegistry(connector)
+	service = NewService(&mockVectorClient{}, &recordMockProcessor{}, cfg)
+	stats = &IngestionStats{}
+	if err := service.Ingest(context.Background(), IngestRequest{Type: "notes"}, stats); err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if stats.ProcessedMessages != 1 || stats.FilteredMessages != 1 || stats.RuleMatches["before-2021"] != 1 {
+		t.Errorf("unexpected stats: %+v", stats.GetSummary())
+	}
+}
@@ -0,0 +1,296 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// HTMLConnectorName is the name of the built-in HTML page connector
+const HTMLConnectorName = "html"
+
+// HTMLConnector reads HTML pages, such as a Confluence space export, from
+// a file or a directory tree. Navigation, headers, footers and scripts are
+// dropped; headings are kept to split each page into section records as
+// MarkdownConnector does, and links are kept as "text (href)".
+type HTMLConnector struct {
+	// DefaultPermissions are given to every page
+	DefaultPermissions []string
+}
+
+// Name implements Connector
+func (HTMLConnector) Name() string {
+	return HTMLConnectorName
+}
+
+// Read implements Connector. Pages that cannot be read are reported to fn
+// as errors.
+func (c HTMLConnector) Read(ctx context.Context, path string, fn RecordFunc) error {
+	files, err := documentFiles(path, "HTML", ".html", ".htm")
+	if err != nil {
+		return err
+	}
+	return readDocumentFiles(ctx, files, c.readFile, fn)
+}
+
+// readFile turns a page into one record per non-empty section
+func (c HTMLConnector) readFile(path string) ([]models.Record, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	page, err := parseHTMLPage(file)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := sectionedDocument{
+		source:      HTMLConnectorName,
+		url:         filepath.ToSlash(path),
+		title:       page.title,
+		author:      page.author,
+		createdAt:   info.ModTime(),
+		updatedAt:   info.ModTime(),
+		permissions: c.DefaultPermissions,
+		tags:        append([]string{"html"}, page.keywords...),
+		sections:    splitMarkdownSections(page.text),
+	}
+	if doc.title == "" {
+		doc.title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return doc.records(), nil
+}
+
+// htmlPage is the readable content of an HTML page
+type htmlPage struct {
+	title    string
+	author   string
+	keywords []string
+	// text is the main content rendered as Markdown: headings as "#"
+	// lines, list items as "- " lines and preformatted text as code blocks
+	text string
+}
+
+// htmlSkippedElements never hold page content
+var htmlSkippedElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"nav": true, "header": true, "footer": true, "aside": true,
+	"form": true, "button": true, "iframe": true, "svg": true,
+}
+
+// htmlBoilerplate are IDs and classes of navigation and page chrome, such
+// as the breadcrumbs and footer of Confluence exports
+var htmlBoilerplate = map[string]bool{
+	"breadcrumbs": true, "breadcrumb-section": true, "navigation": true, "nav": true,
+	"sidebar": true, "footer": true, "page-metadata": true, "toc": true, "skip-link": true,
+}
+
+// htmlContentRoots pick the element holding a page's main content, most
+// specific first; the body is used when none matches
+var htmlContentRoots = []func(n *html.Node) bool{
+	func(n *html.Node) bool { return htmlAttr(n, "id") == "main-content" },
+	func(n *html.Node) bool { return htmlHasClass(n, "wiki-content") },
+	func(n *html.Node) bool { return n.Data == "main" },
+	func(n *html.Node) bool { return n.Data == "article" },
+	func(n *html.Node) bool { return n.Data == "body" },
+}
+
+// parseHTMLPage extracts the title, metadata and main content of a page
+func parseHTMLPage(r io.Reader) (htmlPage, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return htmlPage{}, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	var page htmlPage
+	if title := findHTML(root, func(n *html.Node) bool { return n.Data == "title" }); title != nil {
+		page.title = collapseSpace(htmlText(title))
+	}
+	walkHTML(root, func(n *html.Node) {
+		if n.Data != "meta" {
+			return
+		}
+		content := strings.TrimSpace(htmlAttr(n, "content"))
+		switch strings.ToLower(htmlAttr(n, "name")) {
+		case "author":
+			page.author = content
+		case "keywords":
+			page.keywords = frontMatterList(content)
+		}
+	})
+
+	content := root
+	for _, isRoot := range htmlContentRoots {
+		if n := findHTML(root, isRoot); n != nil {
+			content = n
+			break
+		}
+	}
+
+	var sb strings.Builder
+	renderHTML(&sb, content)
+	page.text = normalizeRenderedText(sb.String())
+	return page, nil
+}
+
+// renderHTML writes the readable content of n to sb as Markdown
+func renderHTML(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(collapseSpace(n.Data))
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		renderHTMLChildren(sb, n)
+		return
+	default:
+		return
+	}
+
+	if htmlSkippedElements[n.Data] || htmlBoilerplate[htmlAttr(n, "id")] {
+		return
+	}
+	for _, class := range strings.Fields(htmlAttr(n, "class")) {
+		if htmlBoilerplate[class] {
+			return
+		}
+	}
+
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if text := collapseSpace(htmlText(n)); strings.TrimSpace(text) != "" {
+			level := int(n.Data[1] - '0')
+			fmt.Fprintf(sb, "\n\n%s %s\n\n", strings.Repeat("#", level), strings.TrimSpace(text))
+		}
+	case "a":
+		var inner strings.Builder
+		renderHTMLChildren(&inner, n)
+		text := strings.TrimSpace(inner.String())
+		href := strings.TrimSpace(htmlAttr(n, "href"))
+		sb.WriteString(text)
+		if href != "" && href != text && !strings.HasPrefix(href, "#") && !strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			if text == "" {
+				sb.WriteString(href)
+			} else {
+				fmt.Fprintf(sb, " (%s)", href)
+			}
+		}
+	case "pre":
+		fmt.Fprintf(sb, "\n\n```\n%s\n```\n\n", strings.Trim(htmlText(n), "\n"))
+	case "br":
+		sb.WriteString("\n")
+	case "li":
+		sb.WriteString("\n- ")
+		renderHTMLChildren(sb, n)
+		sb.WriteString("\n")
+	case "td", "th":
+		renderHTMLChildren(sb, n)
+		sb.WriteString(" | ")
+	case "p", "div", "section", "ul", "ol", "table", "tr", "blockquote", "dl", "dt", "dd", "hr":
+		sb.WriteString("\n\n")
+		renderHTMLChildren(sb, n)
+		sb.WriteString("\n\n")
+	default:
+		renderHTMLChildren(sb, n)
+	}
+}
+
+func renderHTMLChildren(sb *strings.Builder, n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		renderHTML(sb, child)
+	}
+}
+
+var (
+	whitespaceRun = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLines    = regexp.MustCompile(`\n{3,}`)
+)
+
+// collapseSpace turns runs of whitespace into single spaces
+func collapseSpace(s string) string {
+	return whitespaceRun.ReplaceAllString(s, " ")
+}
+
+// normalizeRenderedText trims the lines of rendered HTML and drops
+// repeated blank lines, leaving code blocks untouched
+func normalizeRenderedText(s string) string {
+	lines := strings.Split(s, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "```" {
+			inCode = !inCode
+			lines[i] = "```"
+			continue
+		}
+		if !inCode {
+			lines[i] = strings.TrimSpace(line)
+		}
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// htmlText returns all the text below n
+func htmlText(n *html.Node) string {
+	var sb strings.Builder
+	walkHTML(n, func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+	})
+	return sb.String()
+}
+
+// walkHTML calls fn for n and every node below it in document order
+func walkHTML(n *html.Node, fn func(n *html.Node)) {
+	fn(n)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walkHTML(child, fn)
+	}
+}
+
+// findHTML returns the first element at or below n matching match
+func findHTML(n *html.Node, match func(n *html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findHTML(child, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// htmlAttr returns the value of an attribute of n, or ""
+func htmlAttr(n *html.Node, name string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// htmlHasClass reports whether n has the given class
+func htmlHasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(htmlAttr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
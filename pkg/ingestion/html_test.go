@@ -0,0 +1,109 @@
+package ingestion
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// confluencePage mimics a page from a Confluence HTML space export
+const confluencePage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Platform : Deploy Guide</title>
+  <meta name="author" content="Alice Smith">
+  <meta name="keywords" content="deploy, ops">
+  <script>var tracking = true;</script>
+</head>
+<body>
+  <div id="page">
+    <div id="breadcrumb-section"><ol id="breadcrumbs"><li><a href="index.html">Platform</a></li></ol></div>
+    <div id="main-header"><h1 id="title-heading">Platform : Deploy Guide</h1></div>
+    <div id="content">
+      <div class="page-metadata">Created by Alice Smith, last modified on Mar 01, 2024</div>
+      <div id="main-content" class="wiki-content group">
+        <p>Deploys run from   the <strong>release</strong> branch.</p>
+        <h2>Rollback</h2>
+        <p>See <a href="Runbook_12345.html">the runbook</a> or <a href="#top">jump up</a>.</p>
+        <ul><li>Stop traffic</li><li>Revert</li></ul>
+        <pre>
+# not a heading
+kubectl rollout undo
+</pre>
+        <table><tr><th>Env</th><th>Owner</th></tr><tr><td>prod</td><td>SRE</td></tr></table>
+      </div>
+    </div>
+    <div id="footer"><p>Printed by Atlassian Confluence</p></div>
+  </div>
+</body>
+</html>`
+
+func TestParseHTMLPage(t *testing.T) {
+	page, err := parseHTMLPage(strings.NewReader(confluencePage))
+	if err != nil {
+		t.Fatalf("parseHTMLPage() error = %v", err)
+	}
+
+	if page.title != "Platform : Deploy Guide" || page.author != "Alice Smith" || !reflect.DeepEqual(page.keywords, []string{"deploy", "ops"}) {
+		t.Errorf("unexpected page metadata: %+v", page)
+	}
+
+	want := "Deploys run from the release branch.\n\n" +
+		"## Rollback\n\n" +
+		"See the runbook (Runbook_12345.html) or jump up.\n\n" +
+		"- Stop traffic\n\n" +
+		"- Revert\n\n" +
+		"```\n# not a heading\nkubectl rollout undo\n```\n\n" +
+		"Env | Owner |\n\nprod | SRE |"
+	if page.text != want {
+		t.Errorf("text =\n%s\nwant\n%s", page.text, want)
+	}
+	for _, boilerplate := range []string{"tracking", "breadcrumbs", "Created by", "Printed by"} {
+		if strings.Contains(page.text, boilerplate) {
+			t.Errorf("text contains boilerplate %q", boilerplate)
+		}
+	}
+}
+
+func TestHTMLConnector_Read(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Deploy-Guide_123.html"), []byte(confluencePage), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "styles.css"), []byte("body {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []models.Record
+	err := HTMLConnector{DefaultPermissions: []string{"public"}}.Read(context.Background(), dir, func(record models.Record, err error) error {
+		if err != nil {
+			t.Errorf("unexpected read error: %v", err)
+			return nil
+		}
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2: %+v", len(records), records)
+	}
+
+	url := filepath.ToSlash(filepath.Join(dir, "Deploy-Guide_123.html"))
+	intro, rollback := records[0], records[1]
+	if intro.ID != "html:"+url || intro.Title != "Platform : Deploy Guide" || intro.URL != url {
+		t.Errorf("unexpected intro record: %+v", intro)
+	}
+	if rollback.ID != "html:"+url+"#rollback" || rollback.Title != "Rollback" || rollback.Author != "Alice Smith" {
+		t.Errorf("unexpected section record: %+v", rollback)
+	}
+	if !reflect.DeepEqual(rollback.Tags, []string{"html", "deploy", "ops"}) || rollback.Permissions[0] != "public" {
+		t.Errorf("unexpected tags or permissions: %+v", rollback)
+	}
+}
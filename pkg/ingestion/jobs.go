@@ -0,0 +1,278 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobStatus is the state of an ingestion job
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Done reports whether a job with this status has finished
+func (s JobStatus) Done() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCancelled
+}
+
+// defaultJobHistory is how many finished jobs a JobManager remembers
+const defaultJobHistory = 100
+
+// snapshotErrors is how many of a job's most recent errors a snapshot
+// carries
+const snapshotErrors = 50
+
+// ErrJobNotFound is returned for unknown job IDs
+var ErrJobNotFound = errors.New("ingestion job not found")
+
+// ErrJobFinished is returned when cancelling a job that already finished
+var ErrJobFinished = errors.New("ingestion job already finished")
+
+// JobSnapshot is the state of an ingestion job at one point in time.
+// Errors holds only the most recent errors; ErrorCount counts all of them.
+type JobSnapshot struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Path       string                 `json:"path"`
+	Status     JobStatus              `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	Stats      map[string]interface{} `json:"stats"`
+	Errors     []string               `json:"errors,omitempty"`
+	ErrorCount int                    `json:"error_count"`
+	CreatedAt  time.Time              `json:"created_at"`
+	StartedAt  time.Time              `json:"started_at,omitempty"`
+	FinishedAt time.Time              `json:"finished_at,omitempty"`
+}
+
+// Done reports whether the job has finished
+func (j JobSnapshot) Done() bool {
+	return j.Status.Done()
+}
+
+// job is an ingestion running in the background
+type job struct {
+	id         string
+	req        IngestRequest
+	status     JobStatus
+	err        error
+	stats      *IngestionStats
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// JobManager runs ingestion requests in the background, one at a time,
+// since the service's parsers and loaded metadata are shared between runs.
+// It is safe for concurrent use.
+type JobManager struct {
+	service *Service
+	history int
+
+	jobs map[string]*job
+	// runMu serialises job execution
+	runMu sync.Mutex
+	mu    sync.RWMutex
+}
+
+// NewJobManager creates a job manager for service that remembers up to
+// history finished jobs, or a default number if history is not positive
+func NewJobManager(service *Service, history int) *JobManager {
+	if history <= 0 {
+		history = defaultJobHistory
+	}
+	return &JobManager{
+		service: service,
+		history: history,
+		jobs:    make(map[string]*job),
+	}
+}
+
+// Start queues an ingestion and returns its initial snapshot. The job runs
+// on its own context, so it outlives the request that started it.
+func (m *JobManager) Start(req IngestRequest) JobSnapshot {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		id:        uuid.New().String(),
+		req:       req,
+		status:    JobQueued,
+		stats:     &IngestionStats{},
+		createdAt: time.Now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.jobs[j.id] = j
+	m.pruneLocked()
+	snapshot := m.snapshotLocked(j)
+	m.mu.Unlock()
+
+	go m.run(ctx, j)
+	return snapshot
+}
+
+// run executes a job once no other job is running
+func (m *JobManager) run(ctx context.Context, j *job) {
+	defer close(j.done)
+	defer j.cancel()
+
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+
+	m.mu.Lock()
+	if ctx.Err() != nil {
+		// Cancelled while queued
+		j.finishedAt = time.Now()
+		m.mu.Unlock()
+		return
+	}
+	j.status = JobRunning
+	j.startedAt = time.Now()
+	m.mu.Unlock()
+
+	log.Printf("Starting ingestion job %s: %s %s", j.id, j.req.Type, j.req.Path)
+	err := m.execute(ctx, j)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.finishedAt = time.Now()
+	switch {
+	case ctx.Err() != nil:
+		j.status = JobCancelled
+		j.err = ctx.Err()
+	case err != nil:
+		j.status = JobFailed
+		j.err = err
+	default:
+		j.status = JobSucceeded
+	}
+	log.Printf("Ingestion job %s %s", j.id, j.status)
+}
+
+// execute resets state if requested and runs the ingestion
+func (m *JobManager) execute(ctx context.Context, j *job) error {
+	if j.req.ResetState {
+		if err := m.service.ResetState(); err != nil {
+			return fmt.Errorf("failed to reset ingestion state: %w", err)
+		}
+	}
+	return m.service.Ingest(ctx, j.req, j.stats)
+}
+
+// Get returns a snapshot of a job
+func (m *JobManager) Get(id string) (JobSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return JobSnapshot{}, ErrJobNotFound
+	}
+	return m.snapshotLocked(j), nil
+}
+
+// List returns snapshots of all remembered jobs, most recent first
+func (m *JobManager) List() []JobSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshots := make([]JobSnapshot, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		snapshots = append(snapshots, m.snapshotLocked(j))
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots
+}
+
+// Cancel stops a queued or running job. Documents stored before the
+// cancellation are kept.
+func (m *JobManager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if j.status.Done() {
+		return ErrJobFinished
+	}
+	j.cancel()
+	if j.status == JobQueued {
+		j.status = JobCancelled
+		j.err = context.Canceled
+	}
+	return nil
+}
+
+// Done returns a channel that is closed when the job finishes
+func (m *JobManager) Done(id string) (<-chan struct{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return j.done, nil
+}
+
+// snapshotLocked copies the state of a job. m.mu must be held.
+func (m *JobManager) snapshotLocked(j *job) JobSnapshot {
+	snapshot := JobSnapshot{
+		ID:         j.id,
+		Type:       j.req.Type,
+		Path:       j.req.Path,
+		Status:     j.status,
+		Stats:      j.stats.GetSummary(),
+		CreatedAt:  j.createdAt,
+		StartedAt:  j.startedAt,
+		FinishedAt: j.finishedAt,
+	}
+	if j.err != nil {
+		snapshot.Error = j.err.Error()
+	}
+
+	j.stats.mu.Lock()
+	snapshot.ErrorCount = len(j.stats.Errors)
+	for _, err := range j.stats.Errors[max(len(j.stats.Errors)-snapshotErrors, 0):] {
+		snapshot.Errors = append(snapshot.Errors, err.Error())
+	}
+	j.stats.mu.Unlock()
+
+	return snapshot
+}
+
+// pruneLocked forgets the oldest finished jobs beyond the history limit.
+// m.mu must be held.
+func (m *JobManager) pruneLocked() {
+	var finished []*job
+	for _, j := range m.jobs {
+		if j.status.Done() {
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= m.history {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].createdAt.Before(finished[j].createdAt)
+	})
+	for _, j := range finished[:len(finished)-m.history] {
+		delete(m.jobs, j.id)
+	}
+}
@@ -0,0 +1,149 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+const jobTestCSV = `channel_id,text,ts,type,user
+C1,Hello,1599934232.000100,message,U1
+C1,World,1599934233.000100,message,U2
+`
+
+func waitForJob(t *testing.T, m *JobManager, id string) JobSnapshot {
+	t.Helper()
+	done, err := m.Done(id)
+	if err != nil {
+		t.Fatalf("Done() error = %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job %s did not finish", id)
+	}
+	job, err := m.Get(id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	return job
+}
+
+func TestJobManager_Run(t *testing.T) {
+	file := writeCSV(t, t.TempDir(), "messages.csv", jobTestCSV)
+	manager := NewJobManager(NewService(&mockVectorClient{}, &threadMockProcessor{}), 0)
+
+	job := manager.Start(IngestRequest{Type: "file", Path: file})
+	if job.ID == "" || job.Done() {
+		t.Fatalf("unexpected initial snapshot: %+v", job)
+	}
+
+	job = waitForJob(t, manager, job.ID)
+	if job.Status != JobSucceeded || job.Stats["processed_messages"] != 2 || job.Stats["total_messages"] != 2 {
+		t.Errorf("unexpected finished job: %+v", job)
+	}
+	if job.StartedAt.IsZero() || job.FinishedAt.Before(job.StartedAt) {
+		t.Errorf("unexpected job times: %+v", job)
+	}
+
+	failed := waitForJob(t, manager, manager.Start(IngestRequest{Type: "file", Path: filepath.Join(t.TempDir(), "missing.csv")}).ID)
+	if failed.Status != JobFailed || failed.Error == "" {
+		t.Errorf("unexpected failed job: %+v", failed)
+	}
+
+	jobs := manager.List()
+	if len(jobs) != 2 || jobs[0].ID != failed.ID {
+		t.Errorf("List() = %+v, want most recent first", jobs)
+	}
+	if err := manager.Cancel(job.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel() of a finished job error = %v", err)
+	}
+	if _, err := manager.Get("unknown"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Get() of an unknown job error = %v", err)
+	}
+}
+
+func TestJobManager_Cancel(t *testing.T) {
+	file := writeCSV(t, t.TempDir(), "messages.csv", jobTestCSV)
+
+	started := make(chan struct{}, 1)
+	processor := &mockDocumentProcessor{processFunc: func(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	cfg := DefaultServiceConfig()
+	cfg.MaxConcurrency = 1
+	manager := NewJobManager(NewService(&mockVectorClient{}, processor, cfg), 0)
+
+	first := manager.Start(IngestRequest{Type: "file", Path: file})
+	second := manager.Start(IngestRequest{Type: "file", Path: file})
+	<-started
+
+	// Jobs run one at a time, in whichever order they get to
+	running, queued := first, second
+	if job, _ := manager.Get(first.ID); job.Status == JobQueued {
+		running, queued = second, first
+	}
+	if job, _ := manager.Get(queued.ID); job.Status != JobQueued {
+		t.Fatalf("waiting job status = %s, want queued", job.Status)
+	}
+	if err := manager.Cancel(queued.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if err := manager.Cancel(running.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	for _, id := range []string{running.ID, queued.ID} {
+		if job := waitForJob(t, manager, id); job.Status != JobCancelled {
+			t.Errorf("job %s status = %s, want cancelled", id, job.Status)
+		}
+	}
+}
+
+func TestJobManager_History(t *testing.T) {
+	file := writeCSV(t, t.TempDir(), "messages.csv", jobTestCSV)
+	manager := NewJobManager(NewService(&mockVectorClient{}, &threadMockProcessor{}), 2)
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		job := manager.Start(IngestRequest{Type: "file", Path: file})
+		waitForJob(t, manager, job.ID)
+		ids = append(ids, job.ID)
+	}
+
+	// The oldest finished job is forgotten when a new one starts
+	if _, err := manager.Get(ids[0]); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("oldest job still listed: %v", err)
+	}
+	if got := len(manager.List()); got != 3 {
+		t.Errorf("len(List()) = %d, want 3", got)
+	}
+}
+
+func TestJobManager_ErrorsCapped(t *testing.T) {
+	content := "channel_id,text,ts,type,user\n"
+	for i := 0; i < snapshotErrors+10; i++ {
+		content += fmt.Sprintf("C1,Message %d,1599934%03d.000100,message,U1\n", i, i)
+	}
+	file := writeCSV(t, t.TempDir(), "messages.csv", content)
+	processor := &mockDocumentProcessor{processFunc: func(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
+		return nil, errors.New("embedding failed")
+	}}
+	manager := NewJobManager(NewService(&mockVectorClient{}, processor), 0)
+
+	job := waitForJob(t, manager, manager.Start(IngestRequest{Type: "file", Path: file}).ID)
+	if job.ErrorCount != snapshotErrors+10 || len(job.Errors) != snapshotErrors {
+		t.Errorf("ErrorCount = %d with %d errors, want %d with %d", job.ErrorCount, len(job.Errors), snapshotErrors+10, snapshotErrors)
+	}
+}
@@ -0,0 +1,306 @@
+package ingestion
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// MarkdownConnectorName is the name of the built-in documentation connector
+const MarkdownConnectorName = "markdown"
+
+// markdownExtensions are the file extensions read by MarkdownConnector,
+// mapped to whether the file is Markdown rather than plain text
+var markdownExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".txt":      false,
+}
+
+// MarkdownConnector reads Markdown and plain-text files, such as a repo's
+// README.md and docs/*.md, from a file or a directory tree. Each section
+// of a Markdown file becomes a record titled with its heading path, so
+// search results point at the part of a document that matched.
+type MarkdownConnector struct {
+	// DefaultPermissions are given to documents whose front matter lists
+	// no permissions
+	DefaultPermissions []string
+}
+
+// Name implements Connector
+func (MarkdownConnector) Name() string {
+	return MarkdownConnectorName
+}
+
+// Read implements Connector. Hidden directories, node_modules and vendor
+// are skipped. Files that cannot be read are reported to fn as errors.
+func (c MarkdownConnector) Read(ctx context.Context, path string, fn RecordFunc) error {
+	files, err := documentFiles(path, "Markdown or text", ".md", ".markdown", ".txt")
+	if err != nil {
+		return err
+	}
+	return readDocumentFiles(ctx, files, c.readFile, fn)
+}
+
+// readFile turns a file into one record per non-empty section
+func (c MarkdownConnector) readFile(path string) ([]models.Record, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	isMarkdown := markdownExtensions[strings.ToLower(filepath.Ext(path))]
+	var front frontMatter
+	var sections []markdownSection
+	if isMarkdown {
+		front, content, err = parseFrontMatter(content)
+		if err != nil {
+			return nil, err
+		}
+		sections = splitMarkdownSections(string(content))
+	} else {
+		sections = []markdownSection{{body: strings.TrimSpace(string(content))}}
+	}
+
+	doc := sectionedDocument{
+		source:      MarkdownConnectorName,
+		url:         filepath.ToSlash(path),
+		title:       front.Title,
+		author:      front.Author,
+		createdAt:   front.Date,
+		updatedAt:   info.ModTime(),
+		permissions: front.Permissions,
+		tags:        append([]string{"markdown"}, front.Tags...),
+		metadata:    front.Extra,
+		sections:    sections,
+	}
+	if doc.title == "" {
+		doc.title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if doc.createdAt.IsZero() {
+		doc.createdAt = info.ModTime()
+	}
+	if len(doc.permissions) == 0 {
+		doc.permissions = c.DefaultPermissions
+	}
+	if !isMarkdown {
+		doc.tags[0] = "text"
+	}
+	return doc.records(), nil
+}
+
+// frontMatter holds the fields of a Markdown file's YAML front matter
+type frontMatter struct {
+	Title       string
+	Author      string
+	Date        time.Time
+	Tags        []string
+	Permissions []string
+	// Extra holds the other scalar fields
+	Extra map[string]string
+}
+
+// parseFrontMatter splits YAML front matter, delimited by "---" lines at
+// the very start of a file, from the content that follows it
+func parseFrontMatter(content []byte) (frontMatter, []byte, error) {
+	var front frontMatter
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+	if !bytes.HasPrefix(content, []byte("---\n")) && !bytes.HasPrefix(content, []byte("---\r\n")) {
+		return front, content, nil
+	}
+
+	rest := content[bytes.IndexByte(content, '\n')+1:]
+	var yamlBlock []byte
+	found := false
+	for offset := 0; offset < len(rest); {
+		end := bytes.IndexByte(rest[offset:], '\n')
+		next := len(rest)
+		if end >= 0 {
+			next = offset + end + 1
+		}
+		if line := bytes.TrimRight(rest[offset:next], "\r\n"); string(line) == "---" || string(line) == "..." {
+			yamlBlock = rest[:offset]
+			rest = rest[next:]
+			found = true
+			break
+		}
+		offset = next
+	}
+	if !found {
+		// A thematic break rather than front matter
+		return front, content, nil
+	}
+
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(yamlBlock, &fields); err != nil {
+		return front, nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	for key, value := range fields {
+		switch strings.ToLower(key) {
+		case "title":
+			front.Title = fmt.Sprint(value)
+		case "author":
+			front.Author = fmt.Sprint(value)
+		case "date":
+			front.Date = frontMatterTime(value)
+		case "tags":
+			front.Tags = frontMatterList(value)
+		case "permissions":
+			front.Permissions = frontMatterList(value)
+		default:
+			switch value.(type) {
+			case string, int, float64, bool, time.Time:
+				if front.Extra == nil {
+					front.Extra = make(map[string]string)
+				}
+				front.Extra[key] = fmt.Sprint(value)
+			}
+		}
+	}
+	return front, rest, nil
+}
+
+// frontMatterList reads a YAML list, or a comma-separated string, of values
+func frontMatterList(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+	case string:
+		items = strings.Split(v, ",")
+	}
+
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// frontMatterTime reads a YAML timestamp or a date string
+func frontMatterTime(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// markdownSection is the text below a heading, up to the next heading
+type markdownSection struct {
+	// headings is the path of headings leading to the section, outermost
+	// first; it is empty for text before the first heading
+	headings []string
+	body     string
+}
+
+var (
+	// atxHeading matches headings such as "## Getting Started ##"
+	atxHeading = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	// codeFence matches the opening or closing line of a fenced code block
+	codeFence = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+)
+
+// splitMarkdownSections splits Markdown into sections at ATX headings.
+// Lines inside fenced code blocks are never headings, so shell comments in
+// examples do not split a section.
+func splitMarkdownSections(content string) []markdownSection {
+	type heading struct {
+		level int
+		text  string
+	}
+	var (
+		sections []markdownSection
+		stack    []heading
+		body     strings.Builder
+		fence    string
+	)
+
+	flush := func() {
+		headings := make([]string, len(stack))
+		for i, h := range stack {
+			headings[i] = h.text
+		}
+		sections = append(sections, markdownSection{headings: headings, body: strings.TrimSpace(body.String())})
+		body.Reset()
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if match := codeFence.FindStringSubmatch(line); match != nil {
+			switch {
+			case fence == "":
+				fence = match[1]
+			case closesFence(line, fence):
+				fence = ""
+			}
+		} else if fence == "" {
+			if match := atxHeading.FindStringSubmatch(line); match != nil {
+				flush()
+				level := len(match[1])
+				for len(stack) > 0 && stack[len(stack)-1].level >= level {
+					stack = stack[:len(stack)-1]
+				}
+				stack = append(stack, heading{level: level, text: strings.TrimSpace(match[2])})
+				continue
+			}
+		}
+
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	flush()
+	return sections
+}
+
+// closesFence reports whether line closes a code block opened with fence:
+// a run of at least as many of the same character, and nothing else
+func closesFence(line, fence string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= len(fence) && strings.Trim(line, fence[:1]) == ""
+}
+
+// headingSlug turns a heading into an anchor the way GitHub does, e.g.
+// "1. Clone the repository" becomes "1-clone-the-repository"
+func headingSlug(heading string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteByte('-')
+		}
+	}
+	return sb.String()
+}
+
@@ -0,0 +1,206 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+func TestSplitMarkdownSections(t *testing.T) {
+	content := "Intro text\n" +
+		"# Project\n" +
+		"Overview\n" +
+		"## Setup ##\n" +
+		"```bash\n" +
+		"# Install dependencies\n" +
+		"make setup\n" +
+		"```\n" +
+		"### Linux\n" +
+		"apt install\n" +
+		"## Usage\n" +
+		"~~~~\n" +
+		"## not a heading\n" +
+		"~~~\n" +
+		"still code\n" +
+		"~~~~\n" +
+		"#hashtag is text\n"
+
+	sections := splitMarkdownSections(content)
+	want := []markdownSection{
+		{headings: []string{}, body: "Intro text"},
+		{headings: []string{"Project"}, body: "Overview"},
+		{headings: []string{"Project", "Setup"}, body: "```bash\n# Install dependencies\nmake setup\n```"},
+		{headings: []string{"Project", "Setup", "Linux"}, body: "apt install"},
+		{headings: []string{"Project", "Usage"}, body: "~~~~\n## not a heading\n~~~\nstill code\n~~~~\n#hashtag is text"},
+	}
+	if !reflect.DeepEqual(sections, want) {
+		t.Errorf("splitMarkdownSections() =\n%#v\nwant\n%#v", sections, want)
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		want     frontMatter
+		wantBody string
+		wantErr  bool
+	}{
+		{
+			name:     "no front matter",
+			content:  "# Title\n",
+			wantBody: "# Title\n",
+		},
+		{
+			name: "fields",
+			content: "---\ntitle: Runbook\nauthor: alice\ndate: 2024-03-01\n" +
+				"tags: [ops, oncall]\npermissions: team-ops, alice\nowner: sre\nlinks: [a]\n---\n# Body\n",
+			want: frontMatter{
+				Title:       "Runbook",
+				Author:      "alice",
+				Date:        time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				Tags:        []string{"ops", "oncall"},
+				Permissions: []string{"team-ops", "alice"},
+				Extra:       map[string]string{"owner": "sre"},
+			},
+			wantBody: "# Body\n",
+		},
+		{
+			name:     "unterminated",
+			content:  "---\nJust a rule above\n",
+			wantBody: "---\nJust a rule above\n",
+		},
+		{
+			name:    "invalid YAML",
+			content: "---\ntitle: [unclosed\n---\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			front, body, err := parseFrontMatter([]byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFrontMatter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(front, tt.want) {
+				t.Errorf("front matter = %+v, want %+v", front, tt.want)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestMarkdownConnector_Read(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"README.md": "# Guide\nWelcome\n## FAQ\nFirst\n## FAQ\nSecond\n",
+		"docs/runbook.md": "---\ntitle: Runbook\nauthor: alice\npermissions: [team-ops]\ntags: ops\n---\n" +
+			"Restart the service when it hangs.\n",
+		"docs/notes.txt":      "Plain notes",
+		"docs/broken.md":      "---\ntags: [unclosed\n---\n",
+		"docs/image.png":      "not text",
+		".git/HEAD.md":        "# Hidden",
+		"node_modules/pkg.md": "# Dependency",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	connector := MarkdownConnector{DefaultPermissions: []string{"public"}}
+	records := make(map[string]models.Record)
+	var readErrors []error
+	err := connector.Read(context.Background(), root, func(record models.Record, err error) error {
+		if err != nil {
+			readErrors = append(readErrors, err)
+			return nil
+		}
+		records[record.ID] = record
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	readme := filepath.ToSlash(filepath.Join(root, "README.md"))
+	runbook := filepath.ToSlash(filepath.Join(root, "docs/runbook.md"))
+	notes := filepath.ToSlash(filepath.Join(root, "docs/notes.txt"))
+	wantIDs := []string{
+		"markdown:" + readme + "#guide",
+		"markdown:" + readme + "#faq",
+		"markdown:" + readme + "#faq-1",
+		"markdown:" + runbook,
+		"markdown:" + notes,
+	}
+	if len(records) != len(wantIDs) {
+		t.Errorf("read %d records, want %d: %v", len(records), len(wantIDs), records)
+	}
+	for _, id := range wantIDs {
+		if _, ok := records[id]; !ok {
+			t.Errorf("missing record %s", id)
+		}
+	}
+
+	faq := records["markdown:"+readme+"#faq-1"]
+	if faq.Title != "Guide > FAQ" || faq.Text != "Guide > FAQ\n\nSecond" || faq.URL != readme {
+		t.Errorf("unexpected section record: %+v", faq)
+	}
+	if !reflect.DeepEqual(faq.Permissions, []string{"public"}) || faq.Metadata["section"] != "Guide > FAQ" {
+		t.Errorf("unexpected section metadata: %+v", faq)
+	}
+
+	rb := records["markdown:"+runbook]
+	if rb.Title != "Runbook" || rb.Author != "alice" || !reflect.DeepEqual(rb.Permissions, []string{"team-ops"}) {
+		t.Errorf("front matter not applied: %+v", rb)
+	}
+	if !reflect.DeepEqual(rb.Tags, []string{"markdown", "ops"}) {
+		t.Errorf("Tags = %v", rb.Tags)
+	}
+	if nt := records["markdown:"+notes]; nt.Title != "notes" || nt.Tags[0] != "text" || !strings.HasSuffix(nt.Text, "Plain notes") {
+		t.Errorf("unexpected text record: %+v", nt)
+	}
+
+	var parseErr *ParseError
+	if len(readErrors) != 1 || !errors.As(readErrors[0], &parseErr) || !strings.HasSuffix(parseErr.Fields["path"], "broken.md") {
+		t.Errorf("unexpected read errors: %v", readErrors)
+	}
+}
+
+func TestService_IngestMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "setup.md"), []byte("# Setup\nRun make setup\n## Empty\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	processor := &recordMockProcessor{}
+	store := &mockVectorClient{}
+	stats := &IngestionStats{}
+	err := NewService(store, processor).Ingest(context.Background(), IngestRequest{Type: "markdown", Path: dir}, stats)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if stats.ProcessedMessages != 1 || store.storeCount != 1 {
+		t.Errorf("processed %d sections, stored %d documents; want 1 and 1", stats.ProcessedMessages, store.storeCount)
+	}
+	if len(processor.records) != 1 || processor.records[0].Permissions[0] != "public" {
+		t.Errorf("unexpected records: %+v", processor.records)
+	}
+}
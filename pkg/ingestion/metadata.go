@@ -0,0 +1,62 @@
+package ingestion
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// readTable reads a CSV export table, calling fn with a field accessor for
+// every record. Columns missing from the file read as empty strings.
+func readTable(r io.Reader, required []string, fn func(field func(name string) string) error) error {
+	reader := csv.NewReader(r)
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columnMap := make(map[string]int, len(header))
+	for i, col := range header {
+		columnMap[strings.TrimSpace(col)] = i
+	}
+	for _, col := range required {
+		if _, ok := columnMap[col]; !ok {
+			return fmt.Errorf("required column %s not found in CSV", col)
+		}
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read record %d: %w", line, err)
+		}
+
+		field := func(name string) string {
+			if idx, ok := columnMap[name]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+		if err := fn(field); err != nil {
+			return err
+		}
+	}
+}
+
+// parseBool interprets the boolean spellings found in Slack CSV exports
+func parseBool(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "1", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,268 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+// work is a message, record or thread moving through an ingestPipeline.
+// Exactly one of message, record and thread is set.
+type work struct {
+	message *models.SlackMessage
+	record  *models.Record
+	thread  *models.SlackThread
+	// docs are the documents made by the embed stage
+	docs []vector.Document
+	// batch is told when the work is done, or is nil
+	batch *batchTracker
+}
+
+// batchTracker follows the messages of one parsed batch through the
+// pipeline, checkpointing the batch once all of them are stored. A batch
+// with any failure is never checkpointed as stored.
+type batchTracker struct {
+	num     int
+	size    int
+	cp      *fileCheckpoint
+	pending int
+	failed  bool
+	mu      sync.Mutex
+}
+
+// newBatchTracker tracks a batch of size messages as read, pending of
+// which are sent through the pipeline
+func newBatchTracker(cp *fileCheckpoint, num, size, pending int) *batchTracker {
+	return &batchTracker{num: num, size: size, cp: cp, pending: pending}
+}
+
+// done records that one message of the batch is finished
+func (b *batchTracker) done(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.failed = b.failed || failed
+	b.pending--
+	complete := b.pending == 0 && !b.failed
+	b.mu.Unlock()
+	if complete {
+		b.cp.batchDone(b.num, b.size)
+	}
+}
+
+// ingestPipeline processes and stores work in two bounded stages, each
+// with its own worker pool, so embedding never waits on storage and the
+// parser is held back when both stages are busy:
+//
+//	parse (caller) -> embed workers -> store workers
+//
+// Parsing stays in the caller's goroutine, since checkpoints depend on
+// the order messages are read in.
+type ingestPipeline struct {
+	service *Service
+	stats   *IngestionStats
+	ctx     context.Context
+	// replay keeps work dropped on cancellation as dead letters, since
+	// replayed records have no checkpoint to resume from
+	replay bool
+
+	embed   chan *work
+	store   chan *work
+	embedWG sync.WaitGroup
+	storeWG sync.WaitGroup
+}
+
+// startPipeline starts the embed and store workers of a pipeline adding
+// to stats. Close must be called once all work is submitted.
+func (s *Service) startPipeline(ctx context.Context, stats *IngestionStats) *ingestPipeline {
+	embedWorkers, storeWorkers, queueSize := s.pipelineSize()
+	p := &ingestPipeline{
+		service: s,
+		stats:   stats,
+		ctx:     ctx,
+		embed:   make(chan *work, queueSize),
+		store:   make(chan *work, queueSize),
+	}
+	for i := 0; i < embedWorkers; i++ {
+		p.embedWG.Add(1)
+		go func() {
+			defer p.embedWG.Done()
+			for w := range p.embed {
+				p.process(w)
+			}
+		}()
+	}
+	for i := 0; i < storeWorkers; i++ {
+		p.storeWG.Add(1)
+		go func() {
+			defer p.storeWG.Done()
+			for w := range p.store {
+				p.storeDocuments(w)
+			}
+		}()
+	}
+	return p
+}
+
+// pipelineSize returns the configured worker pool and queue sizes,
+// defaulting unset ones as described in ServiceConfig
+func (s *Service) pipelineSize() (embedWorkers, storeWorkers, queueSize int) {
+	embedWorkers, storeWorkers, queueSize = s.embedWorkers, s.storeWorkers, s.queueSize
+	if embedWorkers <= 0 {
+		embedWorkers = max(s.maxConcurrency, 1)
+	}
+	if storeWorkers <= 0 {
+		storeWorkers = max(s.maxConcurrency, 1)
+	}
+	if queueSize <= 0 {
+		queueSize = 2 * embedWorkers
+	}
+	return embedWorkers, storeWorkers, queueSize
+}
+
+// submit queues work, blocking while the embed stage is full
+func (p *ingestPipeline) submit(w *work) error {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case p.embed <- w:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+// close waits for the submitted work to be processed and stored. Once it
+// returns, no worker touches the stats anymore.
+func (p *ingestPipeline) close() {
+	close(p.embed)
+	p.embedWG.Wait()
+	close(p.store)
+	p.storeWG.Wait()
+}
+
+// process runs the embed stage: it turns work into documents with the
+// service's processor and hands them to the store stage
+func (p *ingestPipeline) process(w *work) {
+	s := p.service
+	if p.ctx.Err() != nil {
+		p.drop(w)
+		return
+	}
+	var err error
+	switch {
+	case w.message != nil:
+		msg := w.message
+		// Skip empty messages if configured
+		if s.skipEmptyContent && msg.Content == "" && msg.Blocks == "" && len(msg.FileIDs) == 0 {
+			p.stats.UpdateStats(0, 1, 0, 0, 0, 0)
+			w.batch.done(false)
+			return
+		}
+		if w.docs, err = s.processor.ProcessMessage(p.ctx, *msg); err != nil {
+			err = fmt.Errorf("failed to process message %s: %w", msg.MessageID, err)
+			p.fail(w, DeadLetter{Stage: StageProcess, Error: err.Error(), Message: msg}, err)
+			return
+		}
+	case w.record != nil:
+		record := w.record
+		if s.skipEmptyContent && record.Text == "" {
+			p.stats.UpdateStats(0, 1, 0, 0, 0, 0)
+			return
+		}
+		if w.docs, err = s.processor.(RecordProcessor).ProcessRecord(p.ctx, *record); err != nil {
+			err = fmt.Errorf("failed to process record %s: %w", record.ID, err)
+			p.fail(w, DeadLetter{Stage: StageProcess, Error: err.Error(), Item: record}, err)
+			return
+		}
+	case w.thread != nil:
+		if w.docs, err = s.processor.(ThreadProcessor).ProcessThread(p.ctx, *w.thread); err != nil {
+			if p.ctx.Err() != nil {
+				p.drop(w)
+				return
+			}
+			err = fmt.Errorf("failed to process thread %s: %w", w.thread.ID(), err)
+			p.stats.AddError(err)
+			s.deadLetter(p.stats, DeadLetter{Stage: StageThread, Error: err.Error(), Thread: w.thread})
+			return
+		}
+	}
+
+	if len(w.docs) == 0 {
+		p.finish(w, 0)
+		return
+	}
+	select {
+	case p.store <- w:
+	case <-p.ctx.Done():
+		p.drop(w)
+	}
+}
+
+// fail records a message or record that could not be processed
+func (p *ingestPipeline) fail(w *work, record DeadLetter, err error) {
+	if p.ctx.Err() != nil {
+		p.drop(w)
+		return
+	}
+	p.stats.UpdateStats(0, 0, 1, 0, 0, 0)
+	p.stats.AddError(err)
+	p.service.deadLetter(p.stats, record)
+	w.batch.done(true)
+}
+
+// storeDocuments runs the store stage. Work with any unstored document is
+// dead-lettered whole, since storing is an upsert.
+func (p *ingestPipeline) storeDocuments(w *work) {
+	storedCount := 0
+	var storeErr error
+	for _, doc := range w.docs {
+		if err := p.service.vectorStore.Store(p.ctx, doc); err != nil {
+			storeErr = fmt.Errorf("failed to store document %s: %w", doc.ID, err)
+			p.stats.AddError(storeErr)
+		} else {
+			storedCount++
+		}
+	}
+	// Work cut short by cancellation is resumed, not dead-lettered
+	if storeErr != nil && (p.replay || p.ctx.Err() == nil) {
+		record := DeadLetter{Stage: StageStore, Error: storeErr.Error(), Message: w.message, Item: w.record, Thread: w.thread}
+		if w.thread != nil {
+			record.Stage = StageThread
+		}
+		p.service.deadLetter(p.stats, record)
+	}
+	p.finish(w, storedCount)
+}
+
+// drop abandons work because the run was cancelled. It is not a failure:
+// ingestion resumes it from the checkpoint, so it is only dead-lettered
+// when replaying.
+func (p *ingestPipeline) drop(w *work) {
+	w.batch.done(true)
+	if !p.replay {
+		return
+	}
+	record := DeadLetter{Stage: StageProcess, Error: p.ctx.Err().Error(), Message: w.message, Item: w.record, Thread: w.thread}
+	if w.thread != nil {
+		record.Stage = StageThread
+	}
+	p.service.deadLetter(p.stats, record)
+}
+
+// finish counts work whose documents were processed and stored
+func (p *ingestPipeline) finish(w *work, storedCount int) {
+	documents := len(w.docs)
+	if w.thread != nil {
+		p.stats.UpdateStats(0, 0, 0, documents, storedCount, documents-storedCount)
+		p.stats.countThread()
+		return
+	}
+	p.stats.UpdateStats(1, 0, 0, documents, storedCount, documents-storedCount)
+	w.batch.done(storedCount < documents)
+}
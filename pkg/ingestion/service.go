@@ -17,6 +17,12 @@ type DocumentProcessor interface {
 	ProcessMessage(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error)
 }
 
+// ThreadProcessor is implemented by processors that can turn a reconstructed
+// thread into thread-level documents
+type ThreadProcessor interface {
+	ProcessThread(ctx context.Context, thread models.SlackThread) ([]vector.Document, error)
+}
+
 // Service handles the complete ingestion pipeline
 type Service struct {
 	parser      *CSVParser
@@ -24,24 +30,71 @@ type Service struct {
 	vectorStore vector.Client
 
 	// Ingestion configuration
-	batchSize        int
-	maxConcurrency   int
-	skipEmptyContent bool
+	batchSize          int
+	maxConcurrency     int
+	embedWorkers       int
+	storeWorkers       int
+	queueSize          int
+	fileWorkers        int
+	skipEmptyContent   bool
+	reconstructThreads bool
+
+	// state records checkpoints for incremental ingestion, or is nil
+	state *StateStore
+	// deadLetters receives records that failed ingestion, or is nil
+	deadLetters *DeadLetterWriter
+	connectors  *Registry
+	// filter decides which messages and records are ingested, or is nil
+	filter *Filter
+
+	users    *UserDirectory
+	channels *ChannelDirectory
+	mu       sync.Mutex
 }
 
 // ServiceConfig contains configuration for the ingestion service
 type ServiceConfig struct {
-	BatchSize        int
-	MaxConcurrency   int
+	BatchSize      int
+	MaxConcurrency int
+	// EmbedWorkers is the number of messages processed and embedded at
+	// once, and StoreWorkers the number stored at once. Zero uses
+	// MaxConcurrency.
+	EmbedWorkers int
+	StoreWorkers int
+	// QueueSize bounds the messages waiting for each stage; the parser
+	// waits while the queue is full. Zero uses twice the embed workers.
+	QueueSize int
+	// FileWorkers is the number of files IngestDirectory parses at once.
+	// Zero uses MaxConcurrency.
+	FileWorkers      int
 	SkipEmptyContent bool
+	// ReconstructThreads links replies to their parents and emits one
+	// document per thread when the processor implements ThreadProcessor
+	ReconstructThreads bool
+	// State enables incremental ingestion: interrupted runs resume from
+	// their last checkpoint and later exports only ingest new or edited
+	// messages. Nil ingests everything every time.
+	State *StateStore
+	// DeadLetters receives every record that fails to parse, process or
+	// store, so it can be replayed with ReplayDeadLetters. Nil only
+	// reports failures in IngestionStats.Errors.
+	DeadLetters *DeadLetterWriter
+	// Connectors holds the sources the service can ingest, selected by
+	// IngestRequest.Type. Nil uses DefaultRegistry.
+	Connectors *Registry
+	// Filter excludes messages and records by their channel, author,
+	// subtype, date or text before they are processed. Nil ingests
+	// everything.
+	Filter *Filter
 }
 
 // DefaultServiceConfig returns default service configuration
 func DefaultServiceConfig() ServiceConfig {
 	return ServiceConfig{
-		BatchSize:        100,
-		MaxConcurrency:   5,
-		SkipEmptyContent: true,
+		BatchSize:          100,
+		MaxConcurrency:     5,
+		SkipEmptyContent:   true,
+		ReconstructThreads: true,
 	}
 }
 
@@ -58,14 +111,37 @@ func NewService(vectorStore vector.Client, processor DocumentProcessor, config .
 		ValidateRecords: true,
 	})
 
+	connectors := cfg.Connectors
+	if connectors == nil {
+		connectors = DefaultRegistry()
+	}
+
 	return &Service{
-		parser:           parser,
-		processor:        processor,
-		vectorStore:      vectorStore,
-		batchSize:        cfg.BatchSize,
-		maxConcurrency:   cfg.MaxConcurrency,
-		skipEmptyContent: cfg.SkipEmptyContent,
+		parser:             parser,
+		processor:          processor,
+		vectorStore:        vectorStore,
+		batchSize:          cfg.BatchSize,
+		maxConcurrency:     cfg.MaxConcurrency,
+		embedWorkers:       cfg.EmbedWorkers,
+		storeWorkers:       cfg.StoreWorkers,
+		queueSize:          cfg.QueueSize,
+		fileWorkers:        cfg.FileWorkers,
+		skipEmptyContent:   cfg.SkipEmptyContent,
+		reconstructThreads: cfg.ReconstructThreads,
+		state:              cfg.State,
+		deadLetters:        cfg.DeadLetters,
+		connectors:         connectors,
+		filter:             cfg.Filter,
+	}
+}
+
+// ResetState discards recorded checkpoints so the next run ingests
+// everything again. It does nothing if checkpointing is disabled.
+func (s *Service) ResetState() error {
+	if s.state == nil {
+		return nil
 	}
+	return s.state.Reset()
 }
 
 // IngestionStats tracks ingestion progress and statistics
@@ -74,13 +150,22 @@ type IngestionStats struct {
 	ProcessedMessages int
 	SkippedMessages   int
 	FailedMessages    int
+	// UnchangedMessages were stored by an earlier checkpointed run
+	UnchangedMessages int
 	TotalDocuments    int
 	StoredDocuments   int
 	FailedDocuments   int
-	Errors            []error
-	StartTime         time.Time
-	EndTime           time.Time
-	mu                sync.Mutex
+	Threads           int
+	// DeadLetters counts the records written to the dead-letter file
+	DeadLetters int
+	// FilteredMessages were excluded by the filter rules, and RuleMatches
+	// counts the messages each rule decided, by rule name
+	FilteredMessages int
+	RuleMatches      map[string]int
+	Errors           []error
+	StartTime        time.Time
+	EndTime          time.Time
+	mu               sync.Mutex
 }
 
 // UpdateStats safely updates ingestion statistics
@@ -95,6 +180,103 @@ func (s *IngestionStats) UpdateStats(processed, skipped, failed, documents, stor
 	s.FailedDocuments += failedDocs
 }
 
+// failures counts the errors, failed messages and failed documents so far
+func (s *IngestionStats) failures() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.Errors) + s.FailedMessages + s.FailedDocuments
+}
+
+// start records the start time unless the stats were already started
+func (s *IngestionStats) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.StartTime.IsZero() {
+		s.StartTime = time.Now()
+	}
+}
+
+// finish records the end time
+func (s *IngestionStats) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.EndTime = time.Now()
+}
+
+// merge adds the counts and errors of other to s
+func (s *IngestionStats) merge(other *IngestionStats) {
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	s.UpdateStats(other.ProcessedMessages, other.SkippedMessages, other.FailedMessages,
+		other.TotalDocuments, other.StoredDocuments, other.FailedDocuments)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.UnchangedMessages += other.UnchangedMessages
+	s.Threads += other.Threads
+	s.DeadLetters += other.DeadLetters
+	s.FilteredMessages += other.FilteredMessages
+	for rule, count := range other.RuleMatches {
+		s.addRuleMatches(rule, count)
+	}
+	s.Errors = append(s.Errors, other.Errors...)
+}
+
+// setTotal sets the number of messages read
+func (s *IngestionStats) setTotal(total int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.TotalMessages = total
+}
+
+// addTotal adds to the number of messages read
+func (s *IngestionStats) addTotal(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.TotalMessages += n
+}
+
+// total returns the number of messages read
+func (s *IngestionStats) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.TotalMessages
+}
+
+// countUnchanged adds messages stored by an earlier checkpointed run
+func (s *IngestionStats) countUnchanged(unchanged int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.UnchangedMessages += unchanged
+}
+
+// countThread counts a thread whose documents were built
+func (s *IngestionStats) countThread() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Threads++
+}
+
+// countFiltered records a filter decision and the rule that made it
+func (s *IngestionStats) countFiltered(keep bool, rule string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !keep {
+		s.FilteredMessages++
+	}
+	if rule != "" {
+		s.addRuleMatches(rule, 1)
+	}
+}
+
+// addRuleMatches adds to a rule's count; the caller holds s.mu
+func (s *IngestionStats) addRuleMatches(rule string, count int) {
+	if s.RuleMatches == nil {
+		s.RuleMatches = make(map[string]int)
+	}
+	s.RuleMatches[rule] += count
+}
+
 // AddError adds an error to the stats
 func (s *IngestionStats) AddError(err error) {
 	s.mu.Lock()
@@ -111,169 +293,496 @@ func (s *IngestionStats) GetSummary() map[string]interface{} {
 	if s.EndTime.IsZero() {
 		duration = time.Since(s.StartTime)
 	}
+	if s.StartTime.IsZero() {
+		// Not started yet
+		duration = 0
+	}
+	messagesPerSecond := 0.0
+	if duration > 0 {
+		messagesPerSecond = float64(s.ProcessedMessages) / duration.Seconds()
+	}
+	ruleMatches := make(map[string]int, len(s.RuleMatches))
+	for rule, count := range s.RuleMatches {
+		ruleMatches[rule] = count
+	}
 
 	return map[string]interface{}{
 		"total_messages":      s.TotalMessages,
 		"processed_messages":  s.ProcessedMessages,
 		"skipped_messages":    s.SkippedMessages,
 		"failed_messages":     s.FailedMessages,
+		"unchanged_messages":  s.UnchangedMessages,
 		"total_documents":     s.TotalDocuments,
 		"stored_documents":    s.StoredDocuments,
 		"failed_documents":    s.FailedDocuments,
+		"threads":             s.Threads,
+		"dead_letters":        s.DeadLetters,
+		"filtered_messages":   s.FilteredMessages,
+		"rule_matches":        ruleMatches,
 		"error_count":         len(s.Errors),
 		"duration_seconds":    duration.Seconds(),
-		"messages_per_second": float64(s.ProcessedMessages) / duration.Seconds(),
+		"messages_per_second": messagesPerSecond,
+	}
+}
+
+// threadState carries thread reconstruction through an ingestion run
+type threadState struct {
+	index     *ThreadIndex
+	collector *ThreadCollector
+}
+
+// batchJob is a batch handed to the processing workers. size is the number
+// of messages in the batch as parsed, before duplicates and unchanged
+// messages were dropped.
+type batchJob struct {
+	batchNum int
+	size     int
+	messages []models.SlackMessage
+}
+
+// messageSource is an input that can be streamed in batches. errors returns
+// every error the source's parser has recorded so far.
+type messageSource struct {
+	name   string
+	parse  func(batchCallback BatchCallback, progressCallback ProgressCallback) error
+	errors func() []error
+}
+
+// csvSource reads a CSV file, or a CSV within a ZIP archive, with parser
+func csvSource(parser *CSVParser, file ExportFile) messageSource {
+	return messageSource{name: file.Name(), parse: func(batchCallback BatchCallback, progressCallback ProgressCallback) error {
+		return parser.ParseExportFile(file, batchCallback, progressCallback)
+	}, errors: parser.GetErrors}
+}
+
+// slackZipSource reads a Slack export ZIP with parser
+func slackZipSource(parser *SlackZipParser, path string) messageSource {
+	return messageSource{name: path, parse: func(batchCallback BatchCallback, progressCallback ProgressCallback) error {
+		return parser.ParseFile(path, batchCallback, progressCallback)
+	}, errors: parser.GetErrors}
+}
+
+// exportSource reads a message file found by DiscoverFiles with a parser of
+// its own, so sources can be read at once
+func exportSource(file ExportFile, config ParserConfig) messageSource {
+	if file.Kind == KindSlackExport {
+		return slackZipSource(NewSlackZipParser(config), file.Path)
+	}
+	return csvSource(NewCSVParser(config), file)
+}
+
+// parserConfig returns the configuration for parsers created by the service
+func (s *Service) parserConfig() ParserConfig {
+	return ParserConfig{
+		BatchSize:       s.batchSize,
+		SkipErrors:      true,
+		ValidateRecords: true,
 	}
 }
 
-// IngestFile ingests a single CSV file
-func (s *Service) IngestFile(ctx context.Context, filepath string) (*IngestionStats, error) {
-	stats := &IngestionStats{
-		StartTime: time.Now(),
+// newThreadState indexes sources so replies can be linked to their parents
+// while the sources are streamed. It returns nil if thread reconstruction is
+// disabled or the processor cannot build thread documents.
+func (s *Service) newThreadState(sources ...messageSource) *threadState {
+	if !s.reconstructThreads {
+		return nil
+	}
+	if _, ok := s.processor.(ThreadProcessor); !ok {
+		return nil
 	}
 
-	// Create a worker pool for concurrent processing
-	workerCount := s.maxConcurrency
-	messageChan := make(chan []models.SlackMessage, workerCount)
-	errorChan := make(chan error, workerCount)
+	index := NewThreadIndex()
+	for _, source := range sources {
+		err := source.parse(func(messages []models.SlackMessage, batchNum int) error {
+			index.Add(messages...)
+			return nil
+		}, nil)
+		if err != nil {
+			log.Printf("Failed to index threads in %s: %v", source.name, err)
+		}
+	}
+	index.Link()
 
-	// Worker goroutines
-	var wg sync.WaitGroup
-	for i := 0; i < workerCount; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for messages := range messageChan {
-				if err := s.processBatch(ctx, messages, stats); err != nil {
-					errorChan <- err
-				}
+	return &threadState{
+		index:     index,
+		collector: NewThreadCollector(index),
+	}
+}
+
+// prepareBatch annotates a batch with thread links and drops the messages
+// that need no ingestion: those keep rejects, copies of messages already
+// read from another file, and messages stored by an earlier checkpointed
+// run. Messages from earlier runs are still collected so their threads stay
+// complete; rejected messages are left out of threads too, but still count
+// towards the checkpoint, whose offset is in messages read from the file.
+func prepareBatch(messages []models.SlackMessage, threads *threadState, cp *fileCheckpoint, keep func(models.SlackMessage) bool) (kept []models.SlackMessage, duplicates, unchanged int) {
+	kept = make([]models.SlackMessage, 0, len(messages))
+	for _, msg := range messages {
+		stored := cp.alreadyStored(msg)
+		if keep != nil && !keep(msg) {
+			continue
+		}
+		if threads != nil {
+			msg = threads.index.Annotate(msg)
+			if !threads.collector.Add(msg) {
+				duplicates++
+				continue
 			}
-		}()
+			if !stored {
+				threads.collector.MarkChanged(msg)
+			}
+		}
+		if stored {
+			unchanged++
+			continue
+		}
+		kept = append(kept, msg)
 	}
+	return kept, duplicates, unchanged
+}
 
-	// Error collector
-	go func() {
-		for err := range errorChan {
-			stats.AddError(err)
+// IngestFile ingests a single CSV file. Export metadata files such as
+// users.csv in the same directory are loaded first unless already loaded.
+func (s *Service) IngestFile(ctx context.Context, path string) (*IngestionStats, error) {
+	return s.run(ctx, func(stats *IngestionStats) error {
+		return s.ingestFile(ctx, path, stats)
+	})
+}
+
+// IngestSlackZip ingests a Slack workspace export ZIP. The export's users
+// and channels are loaded first, replacing any loaded before.
+func (s *Service) IngestSlackZip(ctx context.Context, path string) (*IngestionStats, error) {
+	return s.run(ctx, func(stats *IngestionStats) error {
+		return s.ingestSlackZip(ctx, path, stats)
+	})
+}
+
+// IngestDirectory ingests the message tables DiscoverFiles finds in a
+// directory tree, including .csv.gz files and ZIP archives of CSVs. Users
+// and channels tables are loaded rather than ingested as messages, and
+// tables of other kinds are skipped.
+func (s *Service) IngestDirectory(ctx context.Context, dirPath string) (*IngestionStats, error) {
+	return s.run(ctx, func(stats *IngestionStats) error {
+		return s.ingestDirectory(ctx, dirPath, stats)
+	})
+}
+
+// Ingest runs the ingestion described by req with the connector its type
+// selects, updating stats as it goes so that other goroutines can follow
+// its progress with GetSummary
+func (s *Service) Ingest(ctx context.Context, req IngestRequest, stats *IngestionStats) error {
+	stats.start()
+	defer stats.finish()
+
+	connector, ok := s.Connector(req.Type)
+	if !ok {
+		return fmt.Errorf("unknown ingestion type: %s", req.Type)
+	}
+	return s.ingestConnector(ctx, connector, req.Path, stats)
+}
+
+// run times an ingestion into fresh stats
+func (s *Service) run(ctx context.Context, ingest func(stats *IngestionStats) error) (*IngestionStats, error) {
+	stats := &IngestionStats{}
+	stats.start()
+	err := ingest(stats)
+	stats.finish()
+	return stats, err
+}
+
+func (s *Service) ingestFile(ctx context.Context, path string, stats *IngestionStats) error {
+	for _, err := range s.loadExportMetadata(filepath.Dir(path)) {
+		log.Printf("Failed to load export metadata: %v", err)
+	}
+
+	file := ExportFile{Path: path, Kind: KindMessages}
+	threads := s.newThreadState(csvSource(NewCSVParser(s.parserConfig()), file))
+	return s.ingestRun(ctx, threads, csvSource(s.parser, file), stats)
+}
+
+func (s *Service) ingestSlackZip(ctx context.Context, path string, stats *IngestionStats) error {
+	users, channels, err := ReadSlackZipMetadata(path)
+	if err != nil {
+		return fmt.Errorf("failed to read export metadata: %w", err)
+	}
+	s.setUsers(users)
+	s.setChannels(channels)
+	log.Printf("Loaded %d users and %d channels from %s", users.Len(), channels.Len(), path)
+
+	threads := s.newThreadState(slackZipSource(NewSlackZipParser(s.parserConfig()), path))
+	return s.ingestRun(ctx, threads, slackZipSource(NewSlackZipParser(s.parserConfig()), path), stats)
+}
+
+// ingestRun ingests a single source followed by its threads, checkpointing
+// the run when state is configured
+func (s *Service) ingestRun(ctx context.Context, threads *threadState, source messageSource, stats *IngestionStats) error {
+	run := newRunCheckpoint(s.state)
+	cp, err := run.startFile(source.name)
+	if err != nil {
+		return fmt.Errorf("failed to start checkpoint: %w", err)
+	}
+
+	pipeline := s.startPipeline(ctx, stats)
+	err = s.ingestSource(pipeline, source, threads, cp, stats)
+	if err == nil {
+		s.ingestThreads(pipeline, threads, stats)
+	}
+	// Wait for everything submitted to be stored, and keep the progress of
+	// an interrupted run for the next one
+	pipeline.close()
+	if saveErr := cp.save(); saveErr != nil {
+		stats.AddError(saveErr)
+	}
+
+	if err != nil {
+		return err
+	}
+	if stats.failures() == 0 {
+		return completeRun(run, cp)
+	}
+	return nil
+}
+
+// completeRun marks the files of a successful run as done and raises the
+// channel high-water marks. Runs with errors are not completed and keep
+// their file offsets, so the next run retries what was not stored.
+func completeRun(run *runCheckpoint, files ...*fileCheckpoint) error {
+	if run == nil {
+		return nil
+	}
+	for _, cp := range files {
+		if err := cp.finish(); err != nil {
+			return fmt.Errorf("failed to save checkpoint: %w", err)
 		}
-	}()
+	}
+	if err := run.complete(); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
 
-	// Parse file and send batches to workers
-	err := s.parser.ParseFile(filepath, func(messages []models.SlackMessage, batchNum int) error {
-		select {
-		case messageChan <- messages:
+// ingestSource streams a message source into pipeline, adding to stats.
+// When cp is set, messages stored by earlier runs are skipped and progress
+// is recorded as batches are stored. Sources may be ingested at once into
+// the same pipeline; the caller closes it and saves cp.
+func (s *Service) ingestSource(pipeline *ingestPipeline, source messageSource, threads *threadState, cp *fileCheckpoint, stats *IngestionStats) error {
+	// Sources ingested into the same stats add to its total
+	counted := 0
+
+	// Parsers keep their errors across files, so only new ones are
+	// dead-lettered
+	parseErrorsBefore := len(source.errors())
+
+	// Parse file and send messages to the pipeline
+	err := source.parse(func(messages []models.SlackMessage, batchNum int) error {
+		size := len(messages)
+		kept, duplicates, unchanged := prepareBatch(messages, threads, cp, func(msg models.SlackMessage) bool {
+			return s.keepMessage(msg, stats)
+		})
+		if duplicates > 0 {
+			stats.UpdateStats(0, duplicates, 0, 0, 0, 0)
+		}
+		if unchanged > 0 {
+			stats.countUnchanged(unchanged)
+		}
+		if len(kept) == 0 {
+			cp.batchDone(batchNum, size)
 			return nil
-		case <-ctx.Done():
-			return ctx.Err()
 		}
+
+		batch := newBatchTracker(cp, batchNum, size, len(kept))
+		for i := range kept {
+			if err := pipeline.submit(&work{message: &kept[i], batch: batch}); err != nil {
+				return err
+			}
+		}
+		return nil
 	}, func(processed, total, errors int) {
-		stats.TotalMessages = total
+		stats.addTotal(total - counted)
+		counted = total
 		if processed%1000 == 0 {
 			log.Printf("Progress: %d/%d messages processed, %d errors", processed, total, errors)
 		}
 	})
 
-	// Close channels and wait for workers
-	close(messageChan)
-	wg.Wait()
-	close(errorChan)
-
-	stats.EndTime = time.Now()
+	for _, record := range parseDeadLetters(source.name, source.errors()[parseErrorsBefore:]) {
+		s.deadLetter(stats, record)
+	}
 
 	if err != nil {
-		return stats, fmt.Errorf("failed to parse file: %w", err)
+		return fmt.Errorf("failed to parse file: %w", err)
 	}
 
-	return stats, nil
+	return nil
 }
 
-// IngestDirectory ingests all CSV files in a directory
-func (s *Service) IngestDirectory(ctx context.Context, dirPath string) (*IngestionStats, error) {
-	totalStats := &IngestionStats{
-		StartTime: time.Now(),
-	}
-
-	// Find all CSV files
-	files, err := filepath.Glob(filepath.Join(dirPath, "*.csv"))
+// ingestDirectory ingests the message files below a directory, with the
+// users and channels tables among them loaded first. Files are parsed
+// FileWorkers at a time into one pipeline.
+func (s *Service) ingestDirectory(ctx context.Context, dirPath string, stats *IngestionStats) error {
+	discovered, err := DiscoverFiles(dirPath)
 	if err != nil {
-		return totalStats, fmt.Errorf("failed to list CSV files: %w", err)
+		return err
 	}
 
+	// Message tables go before thread tables, so messages listed in both
+	// are ingested from the message table, which has more columns
+	var messageFiles, threadFiles []ExportFile
+	for _, file := range discovered {
+		switch {
+		case file.Kind == KindThreads:
+			threadFiles = append(threadFiles, file)
+		case file.IsMessages():
+			messageFiles = append(messageFiles, file)
+		case file.Kind == KindUnknown:
+			log.Printf("Skipping %s: not a message, thread, user or channel table", file.Name())
+		}
+	}
+	files := append(append([]ExportFile(nil), messageFiles...), threadFiles...)
 	if len(files) == 0 {
-		return totalStats, fmt.Errorf("no CSV files found in %s", dirPath)
+		return fmt.Errorf("no message files found in %s", dirPath)
+	}
+
+	for _, err := range s.loadExportFiles(discovered) {
+		stats.AddError(err)
 	}
 
-	log.Printf("Found %d CSV files to process", len(files))
+	log.Printf("Found %d message files to process", len(files))
 
-	// Process each file
+	// Thread replies and their parents may live in different files, such as
+	// messages.csv and threads.csv, so the whole directory is indexed first
+	sources := make([]messageSource, len(files))
 	for i, file := range files {
-		log.Printf("Processing file %d/%d: %s", i+1, len(files), filepath.Base(file))
+		sources[i] = exportSource(file, s.parserConfig())
+	}
+	threads := s.newThreadState(sources...)
+
+	// All files form one checkpointed run
+	run := newRunCheckpoint(s.state)
+	failuresBefore := stats.failures()
+	var (
+		checkpoints []*fileCheckpoint
+		incomplete  bool
+		started     int
+		mu          sync.Mutex
+	)
+
+	pipeline := s.startPipeline(ctx, stats)
+	ingest := func(file ExportFile) {
+		mu.Lock()
+		started++
+		log.Printf("Processing file %d/%d: %s", started, len(files), file.Name())
+		mu.Unlock()
+
+		cp, err := run.startExportFile(file)
+		if err == nil {
+			// Parsers are not safe for concurrent use, so each file gets
+			// its own
+			err = s.ingestSource(pipeline, exportSource(file, s.parserConfig()), threads, cp, stats)
+			if err != nil {
+				err = fmt.Errorf("failed to ingest %s: %w", file.Name(), err)
+			}
+		} else {
+			err = fmt.Errorf("failed to start checkpoint for %s: %w", file.Name(), err)
+		}
 
-		fileStats, err := s.IngestFile(ctx, file)
+		mu.Lock()
+		defer mu.Unlock()
+		if cp != nil {
+			checkpoints = append(checkpoints, cp)
+		}
 		if err != nil {
-			totalStats.AddError(fmt.Errorf("failed to ingest %s: %w", file, err))
-			continue
+			stats.AddError(err)
+			incomplete = true
+		}
+	}
+	s.forEachFile(messageFiles, ingest)
+	s.forEachFile(threadFiles, ingest)
+
+	s.ingestThreads(pipeline, threads, stats)
+	pipeline.close()
+
+	// Keep the progress of an interrupted run for the next one
+	for _, cp := range checkpoints {
+		if err := cp.save(); err != nil {
+			stats.AddError(err)
+		}
+	}
+	if !incomplete && stats.failures() == failuresBefore {
+		if err := completeRun(run, checkpoints...); err != nil {
+			stats.AddError(err)
 		}
+	}
+
+	return nil
+}
 
-		// Merge stats
-		totalStats.UpdateStats(
-			fileStats.ProcessedMessages,
-			fileStats.SkippedMessages,
-			fileStats.FailedMessages,
-			fileStats.TotalDocuments,
-			fileStats.StoredDocuments,
-			fileStats.FailedDocuments,
-		)
+// forEachFile calls fn for every file, running FileWorkers calls at once,
+// and returns once all have returned
+func (s *Service) forEachFile(files []ExportFile, fn func(file ExportFile)) {
+	workers := s.fileWorkers
+	if workers <= 0 {
+		workers = max(s.maxConcurrency, 1)
 	}
 
-	totalStats.EndTime = time.Now()
-	return totalStats, nil
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for _, file := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(file ExportFile) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(file)
+		}(file)
+	}
+	wg.Wait()
 }
 
-// processBatch processes a batch of messages
-func (s *Service) processBatch(ctx context.Context, messages []models.SlackMessage, stats *IngestionStats) error {
-	processed := 0
-	skipped := 0
-	failed := 0
+// ingestThreads submits a thread-level document for every reconstructed
+// thread to pipeline
+func (s *Service) ingestThreads(pipeline *ingestPipeline, threads *threadState, stats *IngestionStats) {
+	if threads == nil {
+		return
+	}
 
-	for _, msg := range messages {
-		// Skip empty messages if configured
-		if s.skipEmptyContent && msg.Content == "" && len(msg.FileIDs) == 0 {
-			skipped++
-			continue
-		}
+	// Without checkpoints every message is ingested, so every thread has
+	// changed
+	collected := threads.collector.ChangedThreads()
+	if len(collected) > 0 {
+		log.Printf("Building documents for %d threads", len(collected))
+	}
 
-		// Process message into documents
-		docs, err := s.processor.ProcessMessage(ctx, msg)
-		if err != nil {
-			failed++
-			stats.AddError(fmt.Errorf("failed to process message %s: %w", msg.MessageID, err))
-			continue
+	for i := range collected {
+		if err := pipeline.submit(&work{thread: &collected[i]}); err != nil {
+			stats.AddError(err)
+			return
 		}
+	}
+}
 
-		// Store documents
-		storedCount := 0
-		for _, doc := range docs {
-			if err := s.vectorStore.Store(ctx, doc); err != nil {
-				stats.AddError(fmt.Errorf("failed to store document %s: %w", doc.ID, err))
-			} else {
-				storedCount++
-			}
+// processBatch processes and stores a batch of messages through the
+// ingestion pipeline
+func (s *Service) processBatch(ctx context.Context, messages []models.SlackMessage, stats *IngestionStats) error {
+	pipeline := s.startPipeline(ctx, stats)
+	defer pipeline.close()
+	for i := range messages {
+		if err := pipeline.submit(&work{message: &messages[i]}); err != nil {
+			return err
 		}
-
-		stats.UpdateStats(0, 0, 0, len(docs), storedCount, len(docs)-storedCount)
-		processed++
 	}
-
-	stats.UpdateStats(processed, skipped, failed, 0, 0, 0)
 	return nil
 }
 
 // IngestRequest represents a request to ingest data
 type IngestRequest struct {
-	Type      string `json:"type"` // "file" or "directory"
-	Path      string `json:"path"` // Path to file or directory
+	Type      string `json:"type"` // Connector name, or "file" or "directory" for Slack CSVs
+	Path      string `json:"path"` // Path to the file, directory or archive to ingest
 	BatchSize int    `json:"batch_size,omitempty"`
+	// ResetState discards checkpoints before ingesting
+	ResetState bool `json:"reset_state,omitempty"`
+	// Wait makes the API respond only once ingestion has finished
+	Wait bool `json:"wait,omitempty"`
 }
 
 // IngestResponse represents the response from an ingestion operation
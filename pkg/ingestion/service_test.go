@@ -3,6 +3,7 @@ package ingestion
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,10 +15,13 @@ import (
 type mockDocumentProcessor struct {
 	processFunc func(context.Context, models.SlackMessage) ([]vector.Document, error)
 	callCount   int
+	mu          sync.Mutex
 }
 
 func (m *mockDocumentProcessor) ProcessMessage(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
+	m.mu.Lock()
 	m.callCount++
+	m.mu.Unlock()
 	if m.processFunc != nil {
 		return m.processFunc(ctx, msg)
 	}
@@ -42,6 +46,7 @@ type mockVectorClient struct {
 	storeFunc   func(context.Context, vector.Document) error
 	storeCount  int
 	storeErrors []error
+	mu          sync.Mutex
 }
 
 func (m *mockVectorClient) Initialize(ctx context.Context) error {
@@ -49,12 +54,15 @@ func (m *mockVectorClient) Initialize(ctx context.Context) error {
 }
 
 func (m *mockVectorClient) Store(ctx context.Context, doc vector.Document) error {
+	m.mu.Lock()
 	m.storeCount++
+	count := m.storeCount
+	m.mu.Unlock()
 	if m.storeFunc != nil {
 		return m.storeFunc(ctx, doc)
 	}
-	if len(m.storeErrors) > 0 && m.storeCount <= len(m.storeErrors) {
-		return m.storeErrors[m.storeCount-1]
+	if len(m.storeErrors) > 0 && count <= len(m.storeErrors) {
+		return m.storeErrors[count-1]
 	}
 	return nil
 }
@@ -326,3 +334,57 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func TestPipeline_EmbedsWhileStoring(t *testing.T) {
+	release := make(chan struct{})
+	mockProcessor := &mockDocumentProcessor{}
+	mockVector := &mockVectorClient{storeFunc: func(ctx context.Context, doc vector.Document) error {
+		<-release
+		return nil
+	}}
+	service := &Service{
+		processor:    mockProcessor,
+		vectorStore:  mockVector,
+		embedWorkers: 1,
+		storeWorkers: 1,
+		queueSize:    4,
+	}
+	messages := []models.SlackMessage{
+		{MessageID: "1", Content: "one"},
+		{MessageID: "2", Content: "two"},
+		{MessageID: "3", Content: "three"},
+		{MessageID: "4", Content: "four"},
+	}
+
+	stats := &IngestionStats{}
+	done := make(chan error, 1)
+	go func() {
+		done <- service.processBatch(context.Background(), messages, stats)
+	}()
+
+	// Every message is embedded while the first store is still blocked
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mockProcessor.mu.Lock()
+		calls := mockProcessor.callCount
+		mockProcessor.mu.Unlock()
+		if calls == len(messages) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("embedded %d messages while storing, want %d", calls, len(messages))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if summary := stats.GetSummary(); summary["stored_documents"] != 0 {
+		t.Errorf("stored_documents = %v before the store was released", summary["stored_documents"])
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("processBatch() error = %v", err)
+	}
+	if stats.ProcessedMessages != 4 || stats.StoredDocuments != 4 {
+		t.Errorf("processed %d messages, stored %d documents; want 4 and 4", stats.ProcessedMessages, stats.StoredDocuments)
+	}
+}
@@ -0,0 +1,114 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// Names of the built-in Slack connectors
+const (
+	SlackCSVConnectorName = "slack-csv"
+	SlackZipConnectorName = "slack-zip"
+)
+
+// SlackCSVConnector reads Slack messages from a CSV file, or from every
+// message table DiscoverFiles finds in a directory tree or ZIP archive
+type SlackCSVConnector struct{}
+
+// Name implements Connector
+func (SlackCSVConnector) Name() string {
+	return SlackCSVConnectorName
+}
+
+// Read implements Connector
+func (SlackCSVConnector) Read(ctx context.Context, path string, fn RecordFunc) error {
+	files, err := slackMessageFiles(path)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := readMessages(ctx, exportSource(file, DefaultParserConfig()), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingestMessages implements messageConnector
+func (SlackCSVConnector) ingestMessages(ctx context.Context, s *Service, path string, stats *IngestionStats) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if info.IsDir() || isZipName(path) {
+		return s.ingestDirectory(ctx, path, stats)
+	}
+	return s.ingestFile(ctx, path, stats)
+}
+
+// SlackZipConnector reads the messages of a Slack workspace export ZIP
+type SlackZipConnector struct{}
+
+// Name implements Connector
+func (SlackZipConnector) Name() string {
+	return SlackZipConnectorName
+}
+
+// Read implements Connector
+func (SlackZipConnector) Read(ctx context.Context, path string, fn RecordFunc) error {
+	return readMessages(ctx, slackZipSource(NewSlackZipParser(DefaultParserConfig()), path), fn)
+}
+
+// ingestMessages implements messageConnector
+func (SlackZipConnector) ingestMessages(ctx context.Context, s *Service, path string, stats *IngestionStats) error {
+	return s.ingestSlackZip(ctx, path, stats)
+}
+
+// slackMessageFiles returns the message files DiscoverFiles finds at path.
+// Export metadata such as users.csv is left out.
+func slackMessageFiles(path string) ([]ExportFile, error) {
+	discovered, err := DiscoverFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []ExportFile
+	for _, file := range discovered {
+		if file.IsMessages() {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no message files found in %s", path)
+	}
+	return files, nil
+}
+
+// readMessages streams the messages of a source to fn as records, followed
+// by the records that failed to parse
+func readMessages(ctx context.Context, source messageSource, fn RecordFunc) error {
+	errorsBefore := len(source.errors())
+	err := source.parse(func(messages []models.SlackMessage, batchNum int) error {
+		for _, msg := range messages {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(msg.Record(), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil)
+
+	for _, parseErr := range source.errors()[errorsBefore:] {
+		if fnErr := fn(models.Record{}, parseErr); fnErr != nil {
+			return fnErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", source.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+package ingestion
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// Metadata files at the root of a Slack workspace export
+const (
+	zipUsersFile    = "users.json"
+	zipChannelsFile = "channels.json"
+	zipGroupsFile   = "groups.json"
+	zipMPIMsFile    = "mpims.json"
+	zipDMsFile      = "dms.json"
+)
+
+// zipUser is a user in users.json
+type zipUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RealName string `json:"real_name"`
+	TZ       string `json:"tz"`
+	IsBot    bool   `json:"is_bot"`
+	Deleted  bool   `json:"deleted"`
+	Profile  struct {
+		RealName string `json:"real_name"`
+	} `json:"profile"`
+}
+
+// zipChannel is a conversation in channels.json, groups.json, mpims.json
+// or dms.json
+type zipChannel struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	IsArchived bool     `json:"is_archived"`
+	IsGeneral  bool     `json:"is_general"`
+	Members    []string `json:"members"`
+	Purpose    struct {
+		Value string `json:"value"`
+	} `json:"purpose"`
+	Topic struct {
+		Value string `json:"value"`
+	} `json:"topic"`
+}
+
+// zipMessage is a message in a <channel>/<date>.json file
+type zipMessage struct {
+	Type       string `json:"type"`
+	Subtype    string `json:"subtype"`
+	TS         string `json:"ts"`
+	User       string `json:"user"`
+	BotID      string `json:"bot_id"`
+	BotProfile *struct {
+		AppID string `json:"app_id"`
+		Name  string `json:"name"`
+	} `json:"bot_profile"`
+	Username        string          `json:"username"`
+	Text            string          `json:"text"`
+	Blocks          json.RawMessage `json:"blocks"`
+	ClientMsgID     string          `json:"client_msg_id"`
+	ThreadTS        string          `json:"thread_ts"`
+	ReplyCount      int             `json:"reply_count"`
+	ReplyUsers      []string        `json:"reply_users"`
+	ReplyUsersCount int             `json:"reply_users_count"`
+	LatestReply     string          `json:"latest_reply"`
+	ParentUserID    string          `json:"parent_user_id"`
+	Reactions       json.RawMessage `json:"reactions"`
+	PinnedTo        []string        `json:"pinned_to"`
+	Edited          *struct {
+		TS string `json:"ts"`
+	} `json:"edited"`
+	Files []struct {
+		ID string `json:"id"`
+	} `json:"files"`
+}
+
+// SlackZipParser reads the ZIP produced by Slack's workspace export, with
+// users.json, channels.json and one <channel>/<date>.json file per channel
+// and day. Files are streamed from the archive without extracting it.
+type SlackZipParser struct {
+	config           ParserConfig
+	totalRecords     int
+	processedRecords int
+	errorCount       int
+	errors           []error
+}
+
+// NewSlackZipParser creates a new Slack export parser instance. The zero
+// config uses DefaultParserConfig.
+func NewSlackZipParser(config ParserConfig) *SlackZipParser {
+	if config == (ParserConfig{}) {
+		config = DefaultParserConfig()
+	}
+
+	return &SlackZipParser{
+		config: config,
+		errors: make([]error, 0),
+	}
+}
+
+// ReadSlackZipMetadata loads the user and channel directories of a Slack
+// export. Private channels come from groups.json.
+func ReadSlackZipMetadata(filename string) (*UserDirectory, *ChannelDirectory, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer archive.Close()
+
+	var users []zipUser
+	if err := readZipJSON(&archive.Reader, zipUsersFile, &users); err != nil {
+		return nil, nil, err
+	}
+	userDir := NewUserDirectory()
+	for _, u := range users {
+		realName := u.RealName
+		if realName == "" {
+			realName = u.Profile.RealName
+		}
+		userDir.users[u.ID] = models.SlackUser{
+			ID:       u.ID,
+			Name:     u.Name,
+			RealName: realName,
+			TZ:       u.TZ,
+			IsBot:    u.IsBot,
+			Deleted:  u.Deleted,
+		}
+	}
+
+	channelDir := NewChannelDirectory()
+	for _, source := range []struct {
+		file    string
+		private bool
+	}{
+		{zipChannelsFile, false},
+		{zipGroupsFile, true},
+		{zipMPIMsFile, true},
+		{zipDMsFile, true},
+	} {
+		var channels []zipChannel
+		if err := readZipJSON(&archive.Reader, source.file, &channels); err != nil {
+			return nil, nil, err
+		}
+		for _, c := range channels {
+			channelDir.channels[c.ID] = models.SlackChannel{
+				ID:         c.ID,
+				Name:       c.Name,
+				IsPrivate:  source.private,
+				IsArchived: c.IsArchived,
+				IsGeneral:  c.IsGeneral,
+				Purpose:    c.Purpose.Value,
+				Topic:      c.Topic.Value,
+				Members:    c.Members,
+			}
+		}
+	}
+
+	return userDir, channelDir, nil
+}
+
+// ParseFile parses a Slack export ZIP with batch processing and progress
+// tracking. Channels are read in name order and each channel's days in date
+// order. The total passed to progressCallback grows as files are read.
+func (p *SlackZipParser) ParseFile(filename string, batchCallback BatchCallback, progressCallback ProgressCallback) error {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open export: %w", err)
+	}
+	defer archive.Close()
+
+	// Channel folders are named after the channel, or its ID for DMs
+	channelIDs := make(map[string]string)
+	for _, file := range []string{zipChannelsFile, zipGroupsFile, zipMPIMsFile, zipDMsFile} {
+		var channels []zipChannel
+		if err := readZipJSON(&archive.Reader, file, &channels); err != nil {
+			return err
+		}
+		for _, c := range channels {
+			if c.Name != "" {
+				channelIDs[c.Name] = c.ID
+			}
+			channelIDs[c.ID] = c.ID
+		}
+	}
+
+	var dayFiles []*zip.File
+	for _, f := range archive.File {
+		dir, name := path.Split(f.Name)
+		if dir != "" && strings.Count(dir, "/") == 1 && strings.HasSuffix(name, ".json") {
+			dayFiles = append(dayFiles, f)
+		}
+	}
+	sort.Slice(dayFiles, func(i, j int) bool {
+		return dayFiles[i].Name < dayFiles[j].Name
+	})
+
+	p.totalRecords = 0
+	p.processedRecords = 0
+	p.errorCount = 0
+
+	batch := make([]models.SlackMessage, 0, p.config.BatchSize)
+	batchNum := 0
+	for _, f := range dayFiles {
+		folder := path.Dir(f.Name)
+		channelID, ok := channelIDs[folder]
+		if !ok {
+			channelID = folder
+		}
+
+		var messages []zipMessage
+		if err := decodeZipFile(f, &messages); err != nil {
+			if p.config.SkipErrors {
+				p.recordError(fmt.Errorf("failed to read %s: %w", f.Name, err))
+				continue
+			}
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+
+		for _, raw := range messages {
+			p.totalRecords++
+
+			msg, err := raw.toSlackMessage(channelID)
+			if err == nil && p.config.ValidateRecords {
+				err = validateSlackMessage(msg)
+			}
+			if err != nil {
+				if p.config.SkipErrors {
+					p.recordError(&ParseError{
+						Record: p.totalRecords,
+						Fields: raw.fields(channelID),
+						Err:    fmt.Errorf("invalid message %s in %s: %w", raw.TS, f.Name, err),
+					})
+					continue
+				}
+				return fmt.Errorf("invalid message %s in %s: %w", raw.TS, f.Name, err)
+			}
+
+			batch = append(batch, msg)
+			p.processedRecords++
+
+			if len(batch) >= p.config.BatchSize {
+				if err := batchCallback(batch, batchNum); err != nil {
+					return fmt.Errorf("batch callback error: %w", err)
+				}
+				batch = make([]models.SlackMessage, 0, p.config.BatchSize)
+				batchNum++
+			}
+		}
+
+		if progressCallback != nil {
+			progressCallback(p.processedRecords, p.totalRecords, p.errorCount)
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := batchCallback(batch, batchNum); err != nil {
+			return fmt.Errorf("batch callback error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// toSlackMessage maps an exported message to a SlackMessage
+func (m zipMessage) toSlackMessage(channelID string) (models.SlackMessage, error) {
+	msg := models.SlackMessage{
+		MessageID:       m.ClientMsgID,
+		Channel:         channelID,
+		User:            m.User,
+		Content:         m.Text,
+		TS:              m.TS,
+		ThreadTS:        m.ThreadTS,
+		Type:            m.Type,
+		Subtype:         m.Subtype,
+		ReplyCount:      m.ReplyCount,
+		ReplyUsers:      m.ReplyUsers,
+		ReplyUsersCount: m.ReplyUsersCount,
+		LatestReply:     m.LatestReply,
+		PinnedTo:        m.PinnedTo,
+		ParentUserID:    m.ParentUserID,
+		BotID:           m.BotID,
+	}
+	if msg.MessageID == "" {
+		msg.MessageID = m.TS
+	}
+
+	ts, err := parseSlackTimestamp(m.TS)
+	if err != nil {
+		return msg, fmt.Errorf("failed to parse timestamp %s: %w", m.TS, err)
+	}
+	msg.Timestamp = ts
+
+	if m.Edited != nil {
+		msg.Edited = m.Edited.TS
+	}
+	if m.BotProfile != nil {
+		msg.BotAppID = m.BotProfile.AppID
+		msg.BotName = m.BotProfile.Name
+	}
+	if msg.BotName == "" && msg.BotID != "" {
+		msg.BotName = m.Username
+	}
+	if len(m.Blocks) > 0 && string(m.Blocks) != "null" {
+		msg.Blocks = string(m.Blocks)
+	}
+	// Malformed reactions only cost the engagement signal, not the message
+	msg.Reactions, _ = models.ParseSlackReactions(string(m.Reactions))
+	for _, file := range m.Files {
+		if file.ID != "" {
+			msg.FileIDs = append(msg.FileIDs, file.ID)
+		}
+	}
+
+	return msg, nil
+}
+
+// fields returns the message's core values under the CSV export's column
+// names, so a failed message can be replayed through CSVParser.ParseFields
+func (m zipMessage) fields(channelID string) map[string]string {
+	fields := map[string]string{
+		"channel_id":     channelID,
+		"text":           m.Text,
+		"ts":             m.TS,
+		"type":           m.Type,
+		"subtype":        m.Subtype,
+		"user":           m.User,
+		"bot_id":         m.BotID,
+		"client_msg_id":  m.ClientMsgID,
+		"thread_ts":      m.ThreadTS,
+		"parent_user_id": m.ParentUserID,
+		"username":       m.Username,
+	}
+	if len(m.Blocks) > 0 {
+		fields["blocks"] = string(m.Blocks)
+	}
+	if len(m.Reactions) > 0 {
+		fields["reactions"] = string(m.Reactions)
+	}
+	if m.BotProfile != nil {
+		fields["bot_profile__app_id"] = m.BotProfile.AppID
+		fields["bot_profile__name"] = m.BotProfile.Name
+	}
+	return fields
+}
+
+// readZipJSON decodes a file at the root of the archive into v. Missing
+// files are not an error, since exports omit conversation types they do
+// not include.
+func readZipJSON(archive *zip.Reader, name string, v interface{}) error {
+	for _, f := range archive.File {
+		if f.Name == name {
+			if err := decodeZipFile(f, v); err != nil {
+				return fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// decodeZipFile streams a JSON file out of the archive into v
+func decodeZipFile(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := json.NewDecoder(rc).Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// recordError records a parsing error
+func (p *SlackZipParser) recordError(err error) {
+	p.errorCount++
+	p.errors = append(p.errors, err)
+}
+
+// GetErrors returns all parsing errors
+func (p *SlackZipParser) GetErrors() []error {
+	return p.errors
+}
+
+// GetStats returns parsing statistics
+func (p *SlackZipParser) GetStats() (total, processed, errors int) {
+	return p.totalRecords, p.processedRecords, p.errorCount
+}
@@ -0,0 +1,188 @@
+package ingestion
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// writeSlackZip writes a Slack workspace export with the given files
+func writeSlackZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "export.zip")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(out)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var testSlackExport = map[string]string{
+	"users.json": `[
+		{"id": "U1", "name": "alice", "real_name": "Alice Adams", "tz": "Europe/London"},
+		{"id": "U2", "name": "bob", "profile": {"real_name": "Bob Brown"}},
+		{"id": "B1", "name": "ci", "is_bot": true, "deleted": true}
+	]`,
+	"channels.json": `[
+		{"id": "C1", "name": "general", "is_general": true, "members": ["U1", "U2"],
+		 "purpose": {"value": "Announcements"}, "topic": {"value": "Hello"}},
+		{"id": "C2", "name": "old", "is_archived": true}
+	]`,
+	"groups.json": `[{"id": "G1", "name": "secret", "members": ["U1"]}]`,
+	"general/2020-09-13.json": `[
+		{"type": "message", "ts": "1599984000.000100", "user": "U2", "text": "Monday it is",
+		 "thread_ts": "1599934232.150700", "parent_user_id": "U1"}
+	]`,
+	"general/2020-09-12.json": `[
+		{"type": "message", "ts": "1599934232.150700", "user": "U1", "text": "When do we ship?",
+		 "client_msg_id": "abc-123", "thread_ts": "1599934232.150700", "reply_count": 1,
+		 "reply_users": ["U2"], "latest_reply": "1599984000.000100",
+		 "reply_users_count": 1, "pinned_to": ["C1"],
+		 "reactions": [{"name": "+1", "users": ["U2"], "count": 1}]},
+		{"type": "message", "subtype": "channel_join", "ts": "1599934300.000100", "user": "U2",
+		 "text": "<@U2> has joined the channel"}
+	]`,
+	"secret/2020-09-12.json": `[
+		{"type": "message", "ts": "1599934400.000100", "user": "U1", "text": "Quarterly numbers",
+		 "files": [{"id": "F1"}, {"id": "F2"}]},
+		{"type": "message", "ts": "not-a-timestamp", "user": "U1", "text": "broken"}
+	]`,
+}
+
+func TestReadSlackZipMetadata(t *testing.T) {
+	path := writeSlackZip(t, testSlackExport)
+
+	users, channels, err := ReadSlackZipMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadSlackZipMetadata() error = %v", err)
+	}
+
+	if users.Len() != 3 {
+		t.Errorf("users.Len() = %d, want 3", users.Len())
+	}
+	if bob, _ := users.Lookup("U2"); bob.DisplayName() != "Bob Brown" {
+		t.Errorf("profile real name not used: %+v", bob)
+	}
+	if ci, _ := users.Lookup("B1"); !ci.IsBot || !ci.Deleted {
+		t.Errorf("unexpected bot user: %+v", ci)
+	}
+
+	if channels.Len() != 3 {
+		t.Errorf("channels.Len() = %d, want 3", channels.Len())
+	}
+	general, _ := channels.Lookup("C1")
+	if general.Name != "general" || general.IsPrivate || general.Purpose != "Announcements" || len(general.Members) != 2 {
+		t.Errorf("unexpected general channel: %+v", general)
+	}
+	if secret, _ := channels.Lookup("G1"); !secret.IsPrivate {
+		t.Errorf("channel from groups.json should be private: %+v", secret)
+	}
+	if old, _ := channels.Lookup("C2"); !old.IsArchived {
+		t.Errorf("unexpected archived channel: %+v", old)
+	}
+}
+
+func TestSlackZipParser_ParseFile(t *testing.T) {
+	path := writeSlackZip(t, testSlackExport)
+
+	parser := NewSlackZipParser(ParserConfig{BatchSize: 2, SkipErrors: true, ValidateRecords: true})
+	var messages []models.SlackMessage
+	batches := 0
+	err := parser.ParseFile(path, func(batch []models.SlackMessage, batchNum int) error {
+		batches++
+		messages = append(messages, batch...)
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if len(messages) != 4 || batches != 2 {
+		t.Fatalf("got %d messages in %d batches, want 4 in 2", len(messages), batches)
+	}
+	total, processed, errors := parser.GetStats()
+	if total != 5 || processed != 4 || errors != 1 {
+		t.Errorf("GetStats() = %d, %d, %d; want 5, 4, 1", total, processed, errors)
+	}
+
+	// Channels in name order, days in date order
+	var order []string
+	for _, msg := range messages {
+		order = append(order, msg.TS)
+	}
+	want := []string{"1599934232.150700", "1599934300.000100", "1599984000.000100", "1599934400.000100"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("message order = %v, want %v", order, want)
+	}
+
+	parent := messages[0]
+	if parent.MessageID != "abc-123" || parent.Channel != "C1" || parent.ReplyCount != 1 ||
+		parent.LatestReply != "1599984000.000100" || !reflect.DeepEqual(parent.ReplyUsers, []string{"U2"}) {
+		t.Errorf("unexpected parent: %+v", parent)
+	}
+	wantReactions := models.SlackReactions{{Name: "+1", Count: 1, Users: []string{"U2"}}}
+	if !reflect.DeepEqual(parent.Reactions, wantReactions) || parent.Timestamp.IsZero() {
+		t.Errorf("reactions or timestamp missing: %+v", parent)
+	}
+	if !parent.IsPinned() || parent.ReplyUsersCount != 1 {
+		t.Errorf("pin or reply users count missing: %+v", parent)
+	}
+
+	reply := messages[2]
+	if reply.MessageID != reply.TS || !reply.IsThreadReply() || reply.ParentUserID != "U1" {
+		t.Errorf("unexpected reply: %+v", reply)
+	}
+
+	file := messages[3]
+	if file.Channel != "G1" || !reflect.DeepEqual(file.FileIDs, []string{"F1", "F2"}) {
+		t.Errorf("unexpected file message: %+v", file)
+	}
+}
+
+func TestNewSlackZipParser_ZeroConfig(t *testing.T) {
+	if got := NewSlackZipParser(ParserConfig{}).config; got != DefaultParserConfig() {
+		t.Errorf("zero config = %+v, want %+v", got, DefaultParserConfig())
+	}
+}
+
+func TestIngestSlackZip(t *testing.T) {
+	path := writeSlackZip(t, testSlackExport)
+
+	processor := &threadMockProcessor{}
+	stats, err := NewService(&mockVectorClient{}, processor).IngestSlackZip(context.Background(), path)
+	if err != nil {
+		t.Fatalf("IngestSlackZip() error = %v", err)
+	}
+
+	if stats.ProcessedMessages != 4 || stats.Threads != 1 {
+		t.Errorf("processed %d messages and %d threads, want 4 and 1", stats.ProcessedMessages, stats.Threads)
+	}
+	if len(processor.threads) != 1 || processor.threads[0].Parent.MessageID != "abc-123" {
+		t.Errorf("unexpected threads: %+v", processor.threads)
+	}
+
+	if _, err := NewService(&mockVectorClient{}, processor).IngestSlackZip(context.Background(), filepath.Join(t.TempDir(), "missing.zip")); err == nil {
+		t.Error("expected error for missing export")
+	}
+}
@@ -0,0 +1,336 @@
+package ingestion
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// threadKey identifies a message within a channel by its Slack timestamp
+type threadKey struct {
+	channel string
+	ts      string
+}
+
+// threadEntry is the subset of a message needed to link threads. Content is
+// not kept so the index stays small for large exports.
+type threadEntry struct {
+	key         threadKey
+	messageID   string
+	user        string
+	subtype     string
+	timestamp   time.Time
+	threadTS    string
+	replyCount  int
+	replyUsers  []string
+	latestReply string
+}
+
+// ThreadIndex links thread replies to their parent messages across an
+// export. Replies are linked by thread_ts when it points at another message.
+// Exports that flatten thread_ts onto every message are linked using the
+// parent's latest_reply, reply_users and reply_count instead: the replies
+// are the messages by those users between the parent and its latest reply.
+type ThreadIndex struct {
+	entries map[threadKey]*threadEntry
+	parents map[threadKey]threadKey
+	roots   map[threadKey]bool
+	linked  bool
+}
+
+// NewThreadIndex creates an empty thread index
+func NewThreadIndex() *ThreadIndex {
+	return &ThreadIndex{
+		entries: make(map[threadKey]*threadEntry),
+		parents: make(map[threadKey]threadKey),
+		roots:   make(map[threadKey]bool),
+	}
+}
+
+// Add records messages in the index. Messages without a Slack timestamp
+// cannot be linked and are ignored. When the same message appears in more
+// than one file, the copy carrying reply metadata wins.
+func (idx *ThreadIndex) Add(messages ...models.SlackMessage) {
+	for _, msg := range messages {
+		if msg.TS == "" {
+			continue
+		}
+		key := threadKey{channel: msg.Channel, ts: msg.TS}
+		if existing, ok := idx.entries[key]; ok && existing.replyCount >= msg.ReplyCount {
+			continue
+		}
+		idx.entries[key] = &threadEntry{
+			key:         key,
+			messageID:   msg.MessageID,
+			user:        msg.User,
+			subtype:     msg.Subtype,
+			timestamp:   msg.Timestamp,
+			threadTS:    msg.ThreadTS,
+			replyCount:  msg.ReplyCount,
+			replyUsers:  msg.ReplyUsers,
+			latestReply: msg.LatestReply,
+		}
+	}
+	idx.linked = false
+}
+
+// Link resolves the parent of every reply. It must be called after all
+// messages have been added and before Annotate.
+func (idx *ThreadIndex) Link() {
+	idx.parents = make(map[threadKey]threadKey)
+
+	byChannel := make(map[string][]*threadEntry)
+	for _, entry := range idx.entries {
+		byChannel[entry.key.channel] = append(byChannel[entry.key.channel], entry)
+	}
+
+	for channel, entries := range byChannel {
+		sort.Slice(entries, func(i, j int) bool {
+			if !entries[i].timestamp.Equal(entries[j].timestamp) {
+				return entries[i].timestamp.Before(entries[j].timestamp)
+			}
+			return entries[i].key.ts < entries[j].key.ts
+		})
+
+		// Explicit replies point at their parent through thread_ts
+		explicit := make(map[threadKey]int)
+		for _, entry := range entries {
+			if entry.threadTS == "" || entry.threadTS == entry.key.ts {
+				continue
+			}
+			parent := threadKey{channel: channel, ts: entry.threadTS}
+			idx.parents[entry.key] = parent
+			explicit[parent]++
+		}
+
+		// Infer the remaining replies for parents whose replies were not
+		// linked explicitly
+		for i, root := range entries {
+			missing := root.replyCount - explicit[root.key]
+			if missing <= 0 || root.latestReply == "" {
+				continue
+			}
+			idx.inferReplies(root, entries[i+1:], missing)
+		}
+	}
+
+	idx.roots = make(map[threadKey]bool)
+	for _, parent := range idx.parents {
+		idx.roots[parent] = true
+	}
+	idx.linked = true
+}
+
+// inferReplies assigns up to limit unlinked messages following root as its
+// replies. The message at latest_reply is always included when present.
+func (idx *ThreadIndex) inferReplies(root *threadEntry, following []*threadEntry, limit int) {
+	latest, err := parseSlackTimestamp(root.latestReply)
+	if err != nil {
+		return
+	}
+
+	users := make(map[string]bool, len(root.replyUsers))
+	for _, user := range root.replyUsers {
+		users[user] = true
+	}
+
+	var candidates []*threadEntry
+	var latestEntry *threadEntry
+	for _, entry := range following {
+		if entry.timestamp.After(latest) {
+			break
+		}
+		if _, linked := idx.parents[entry.key]; linked || entry.replyCount > 0 {
+			continue
+		}
+		if entry.subtype == "channel_join" || entry.subtype == "channel_leave" {
+			continue
+		}
+		if len(users) > 0 && !users[entry.user] {
+			continue
+		}
+		if entry.key.ts == root.latestReply {
+			latestEntry = entry
+			continue
+		}
+		candidates = append(candidates, entry)
+	}
+
+	if latestEntry != nil {
+		idx.parents[latestEntry.key] = root.key
+		limit--
+	}
+	for _, entry := range candidates {
+		if limit <= 0 {
+			break
+		}
+		idx.parents[entry.key] = root.key
+		limit--
+	}
+}
+
+// Annotate links a reply to its parent by setting ThreadTS,
+// ParentMessageID and ParentUserID. Thread parents get ThreadTS set to
+// their own timestamp; other messages are returned unchanged.
+func (idx *ThreadIndex) Annotate(msg models.SlackMessage) models.SlackMessage {
+	if !idx.linked || msg.TS == "" {
+		return msg
+	}
+
+	key := threadKey{channel: msg.Channel, ts: msg.TS}
+	if idx.roots[key] {
+		msg.ThreadTS = msg.TS
+		return msg
+	}
+	parentKey, ok := idx.parents[key]
+	if !ok {
+		return msg
+	}
+
+	msg.ThreadTS = parentKey.ts
+	if parent, ok := idx.entries[parentKey]; ok {
+		msg.ParentMessageID = parent.messageID
+		if msg.ParentUserID == "" {
+			msg.ParentUserID = parent.user
+		}
+	}
+	return msg
+}
+
+// ThreadCount returns the number of messages with at least one linked reply
+func (idx *ThreadIndex) ThreadCount() int {
+	return len(idx.roots)
+}
+
+// ThreadCollector gathers the full messages of each linked thread while an
+// export is streamed, so thread-level documents can be built afterwards.
+// It is safe for concurrent use.
+type ThreadCollector struct {
+	roots   map[threadKey]bool
+	threads map[threadKey]*models.SlackThread
+	seen    map[threadKey]bool
+	changed map[threadKey]bool
+	mu      sync.Mutex
+}
+
+// NewThreadCollector creates a collector for the threads in a linked index
+func NewThreadCollector(index *ThreadIndex) *ThreadCollector {
+	return &ThreadCollector{
+		roots:   index.roots,
+		threads: make(map[threadKey]*models.SlackThread),
+		seen:    make(map[threadKey]bool),
+		changed: make(map[threadKey]bool),
+	}
+}
+
+// Add records an annotated message. It returns false if the message was
+// already seen, which happens when an export lists thread messages both in
+// messages.csv and threads.csv.
+func (c *ThreadCollector) Add(msg models.SlackMessage) bool {
+	if msg.TS == "" {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := threadKey{channel: msg.Channel, ts: msg.TS}
+	if c.seen[key] {
+		return false
+	}
+	c.seen[key] = true
+
+	switch {
+	case c.roots[key]:
+		c.thread(key).Parent = msg
+	case msg.IsThreadReply():
+		parentKey := threadKey{channel: msg.Channel, ts: msg.ThreadTS}
+		if c.roots[parentKey] {
+			thread := c.thread(parentKey)
+			thread.Replies = append(thread.Replies, msg)
+		}
+	}
+	return true
+}
+
+// MarkChanged records that an annotated message is being ingested, so the
+// thread it belongs to needs its thread-level document rebuilt
+func (c *ThreadCollector) MarkChanged(msg models.SlackMessage) {
+	if root, ok := c.rootOf(msg); ok {
+		c.mu.Lock()
+		c.changed[root] = true
+		c.mu.Unlock()
+	}
+}
+
+// rootOf returns the key of the thread a message belongs to
+func (c *ThreadCollector) rootOf(msg models.SlackMessage) (threadKey, bool) {
+	key := threadKey{channel: msg.Channel, ts: msg.TS}
+	if c.roots[key] {
+		return key, true
+	}
+	if msg.IsThreadReply() {
+		parentKey := threadKey{channel: msg.Channel, ts: msg.ThreadTS}
+		if c.roots[parentKey] {
+			return parentKey, true
+		}
+	}
+	return threadKey{}, false
+}
+
+// thread returns the thread rooted at key, creating it if needed
+func (c *ThreadCollector) thread(key threadKey) *models.SlackThread {
+	thread, ok := c.threads[key]
+	if !ok {
+		thread = &models.SlackThread{Channel: key.channel, ThreadTS: key.ts}
+		c.threads[key] = thread
+	}
+	return thread
+}
+
+// Threads returns the collected threads that have both a parent and at
+// least one reply, ordered by channel and start time, with replies in
+// posting order
+func (c *ThreadCollector) Threads() []models.SlackThread {
+	return c.collect(false)
+}
+
+// ChangedThreads returns the threads Threads would, limited to those with a
+// message passed to MarkChanged
+func (c *ThreadCollector) ChangedThreads() []models.SlackThread {
+	return c.collect(true)
+}
+
+// collect returns the complete threads, optionally only the changed ones
+func (c *ThreadCollector) collect(changedOnly bool) []models.SlackThread {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	threads := make([]models.SlackThread, 0, len(c.threads))
+	for key, thread := range c.threads {
+		if thread.Parent.MessageID == "" || len(thread.Replies) == 0 {
+			continue
+		}
+		if changedOnly && !c.changed[key] {
+			continue
+		}
+		replies := make([]models.SlackMessage, len(thread.Replies))
+		copy(replies, thread.Replies)
+		sort.SliceStable(replies, func(i, j int) bool {
+			return replies[i].Timestamp.Before(replies[j].Timestamp)
+		})
+		t := *thread
+		t.Replies = replies
+		threads = append(threads, t)
+	}
+
+	sort.Slice(threads, func(i, j int) bool {
+		if threads[i].Channel != threads[j].Channel {
+			return threads[i].Channel < threads[j].Channel
+		}
+		return threads[i].Parent.Timestamp.Before(threads[j].Parent.Timestamp)
+	})
+	return threads
+}
@@ -0,0 +1,221 @@
+package ingestion
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+// threadMockProcessor adds ProcessThread to mockDocumentProcessor
+type threadMockProcessor struct {
+	mockDocumentProcessor
+	threads []models.SlackThread
+	mu      sync.Mutex
+}
+
+func (m *threadMockProcessor) ProcessMessage(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mockDocumentProcessor.ProcessMessage(ctx, msg)
+}
+
+func (m *threadMockProcessor) ProcessThread(ctx context.Context, thread models.SlackThread) ([]vector.Document, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.threads = append(m.threads, thread)
+	return []vector.Document{{ID: "thread:" + thread.ID(), SourceID: thread.ID()}}, nil
+}
+
+func parseThreadCSV(t *testing.T, csv string) []models.SlackMessage {
+	t.Helper()
+	messages, err := NewCSVParser().Parse(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return messages
+}
+
+func TestMessageTS(t *testing.T) {
+	tests := []struct {
+		name     string
+		ts       string
+		threadTS string
+		want     string
+	}{
+		{"slack ts", "1599934232.150700", "", "1599934232.150700"},
+		{"slack ts in a thread", "1599934240.150700", "1599934232.150700", "1599934240.150700"},
+		{"datetime with own ts in thread_ts", "2020-09-12 18:10:32", "1599934232.150700", "1599934232.150700"},
+		{"datetime with other ts in thread_ts", "2020-09-12 18:10:40", "1599934232.150700", ""},
+		{"datetime only", "2020-09-12 18:10:32", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp, err := parseSlackTimestamp(tt.ts)
+			if err != nil {
+				t.Fatalf("parseSlackTimestamp() error = %v", err)
+			}
+			if got := messageTS(tt.ts, tt.threadTS, timestamp); got != tt.want {
+				t.Errorf("messageTS() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreadIndex_ExplicitReplies(t *testing.T) {
+	messages := parseThreadCSV(t, `channel_id,text,ts,type,user,thread_ts,reply_count,reply_users
+C1,Should we ship on Friday?,1599934232.150700,message,U1,1599934232.150700,2,"[""U2""]"
+C1,Unrelated,1599934235.000100,message,U3,,0,[]
+C1,No wait for QA,1599934240.150700,message,U2,1599934232.150700,0,[]
+C1,Agreed,1599934250.150700,message,U1,1599934232.150700,0,[]`)
+
+	index := NewThreadIndex()
+	index.Add(messages...)
+	index.Link()
+
+	if got := index.ThreadCount(); got != 1 {
+		t.Fatalf("ThreadCount() = %d, want 1", got)
+	}
+
+	parent := index.Annotate(messages[0])
+	if parent.ThreadID() != "C1:1599934232.150700" || parent.IsThreadReply() {
+		t.Errorf("parent annotated as %+v", parent)
+	}
+	if other := index.Annotate(messages[1]); other.ThreadID() != "" {
+		t.Errorf("unrelated message got thread ID %q", other.ThreadID())
+	}
+
+	reply := index.Annotate(messages[2])
+	if !reply.IsThreadReply() || reply.ParentMessageID != "1599934232.150700" || reply.ParentUserID != "U1" {
+		t.Errorf("reply annotated as %+v", reply)
+	}
+}
+
+func TestThreadIndex_InferredReplies(t *testing.T) {
+	// Exports that flatten thread_ts onto every message only carry the
+	// parent's reply metadata
+	messages := parseThreadCSV(t, `channel_id,text,ts,type,user,thread_ts,reply_count,reply_users,latest_reply,subtype
+C1,Which database should we use?,2020-09-12 18:10:32,message,U1,1599934232.150700,2,"[""U2"",""U3""]",1599934300.000200,
+C1,<@U4> has joined the channel,2020-09-12 18:10:40,message,U4,1599934240.000100,0,[],,channel_join
+C1,Postgres,2020-09-12 18:10:50,message,U2,1599934250.000100,0,[],,
+C1,Off topic,2020-09-12 18:11:00,message,U5,1599934260.000100,0,[],,
+C1,Postgres it is,2020-09-12 18:11:40,message,U3,1599934300.000200,0,[],,
+C1,Later message,2020-09-12 18:12:00,message,U2,1599934320.000100,0,[],,
+C2,Other channel,2020-09-12 18:10:55,message,U2,1599934255.000100,0,[],,`)
+
+	index := NewThreadIndex()
+	index.Add(messages...)
+	index.Link()
+
+	var replies []string
+	for _, msg := range messages {
+		annotated := index.Annotate(msg)
+		if annotated.IsThreadReply() {
+			if annotated.ThreadTS != "1599934232.150700" {
+				t.Errorf("reply %q linked to %q", annotated.Content, annotated.ThreadTS)
+			}
+			replies = append(replies, annotated.Content)
+		}
+	}
+
+	want := []string{"Postgres", "Postgres it is"}
+	if !reflect.DeepEqual(replies, want) {
+		t.Errorf("replies = %v, want %v", replies, want)
+	}
+}
+
+func TestThreadCollector(t *testing.T) {
+	messages := parseThreadCSV(t, `channel_id,text,ts,type,user,thread_ts,reply_count,reply_users
+C1,Parent,1599934232.150700,message,U1,1599934232.150700,2,"[""U2""]"
+C1,Second reply,1599934250.150700,message,U2,1599934232.150700,0,[]
+C1,First reply,1599934240.150700,message,U2,1599934232.150700,0,[]
+C1,Lonely parent,1599934260.150700,message,U1,1599934260.150700,1,"[""U2""]"`)
+
+	index := NewThreadIndex()
+	index.Add(messages...)
+	index.Link()
+	collector := NewThreadCollector(index)
+
+	for _, msg := range messages {
+		if !collector.Add(index.Annotate(msg)) {
+			t.Errorf("message %q reported as duplicate", msg.Content)
+		}
+	}
+	if collector.Add(index.Annotate(messages[1])) {
+		t.Error("duplicate message was accepted")
+	}
+
+	threads := collector.Threads()
+	if len(threads) != 1 {
+		t.Fatalf("Threads() returned %d threads, want 1", len(threads))
+	}
+	thread := threads[0]
+	if thread.Parent.Content != "Parent" || len(thread.Replies) != 2 {
+		t.Fatalf("unexpected thread: %+v", thread)
+	}
+	if thread.Replies[0].Content != "First reply" || thread.Replies[1].Content != "Second reply" {
+		t.Errorf("replies out of order: %q, %q", thread.Replies[0].Content, thread.Replies[1].Content)
+	}
+	if got := thread.Participants(); !reflect.DeepEqual(got, []string{"U1", "U2"}) {
+		t.Errorf("Participants() = %v", got)
+	}
+}
+
+func TestIngestDirectory_Threads(t *testing.T) {
+	dir := t.TempDir()
+	header := "channel_id,text,ts,type,user,thread_ts,reply_count,reply_users\n"
+	files := map[string]string{
+		"messages.csv": header +
+			"C1,Release plan?,1599934232.150700,message,U1,1599934232.150700,1,\"[\"\"U2\"\"]\"\n" +
+			"C1,Ship it Monday,1599934240.150700,message,U2,1599934232.150700,0,[]\n",
+		// threads.csv repeats the reply and adds nothing else
+		"threads.csv": header +
+			"C1,Ship it Monday,1599934240.150700,message,U2,1599934232.150700,0,[]\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	processor := &threadMockProcessor{}
+	store := &mockVectorClient{}
+	service := NewService(store, processor)
+
+	stats, err := service.IngestDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("IngestDirectory() error = %v", err)
+	}
+
+	if stats.ProcessedMessages != 2 || stats.SkippedMessages != 1 {
+		t.Errorf("processed %d, skipped %d; want 2 and 1", stats.ProcessedMessages, stats.SkippedMessages)
+	}
+	if stats.Threads != 1 || stats.StoredDocuments != 3 {
+		t.Errorf("threads %d, stored %d; want 1 and 3", stats.Threads, stats.StoredDocuments)
+	}
+	if len(processor.threads) != 1 || len(processor.threads[0].Replies) != 1 {
+		t.Fatalf("unexpected threads: %+v", processor.threads)
+	}
+	if reply := processor.threads[0].Replies[0]; reply.ParentMessageID != "1599934232.150700" {
+		t.Errorf("reply ParentMessageID = %q", reply.ParentMessageID)
+	}
+
+	// Thread reconstruction can be turned off
+	processor = &threadMockProcessor{}
+	cfg := DefaultServiceConfig()
+	cfg.ReconstructThreads = false
+	stats, err = NewService(&mockVectorClient{}, processor, cfg).IngestDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("IngestDirectory() error = %v", err)
+	}
+	if stats.Threads != 0 || stats.ProcessedMessages != 3 || len(processor.threads) != 0 {
+		t.Errorf("with reconstruction disabled: threads %d, processed %d", stats.Threads, stats.ProcessedMessages)
+	}
+}
@@ -0,0 +1,89 @@
+package ingestion
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// UsersFile is the name of the user directory in a Slack export
+const UsersFile = "users.csv"
+
+// UserDirectory maps Slack user IDs to workspace members
+type UserDirectory struct {
+	users map[string]models.SlackUser
+}
+
+// NewUserDirectory creates a directory holding the given users
+func NewUserDirectory(users ...models.SlackUser) *UserDirectory {
+	dir := &UserDirectory{users: make(map[string]models.SlackUser, len(users))}
+	for _, user := range users {
+		dir.users[user.ID] = user
+	}
+	return dir
+}
+
+// LoadUsers reads a users.csv file from a Slack export
+func LoadUsers(path string) (*UserDirectory, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open users file: %w", err)
+	}
+	defer file.Close()
+
+	dir, err := ParseUsers(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return dir, nil
+}
+
+// ParseUsers reads users from CSV data with at least an id column
+func ParseUsers(r io.Reader) (*UserDirectory, error) {
+	dir := NewUserDirectory()
+	err := readTable(r, []string{"id"}, func(field func(string) string) error {
+		id := field("id")
+		if id == "" {
+			return nil
+		}
+		dir.users[id] = models.SlackUser{
+			ID:       id,
+			Name:     field("name"),
+			RealName: field("real_name"),
+			TZ:       field("tz"),
+			IsBot:    parseBool(field("is_bot")),
+			Deleted:  parseBool(field("deleted")),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dir, nil
+}
+
+// merge returns d with the users of other added, creating d if it is nil
+func (d *UserDirectory) merge(other *UserDirectory) *UserDirectory {
+	if d == nil {
+		d = NewUserDirectory()
+	}
+	if other != nil {
+		for id, user := range other.users {
+			d.users[id] = user
+		}
+	}
+	return d
+}
+
+// Lookup returns the user with the given ID
+func (d *UserDirectory) Lookup(id string) (models.SlackUser, bool) {
+	user, ok := d.users[id]
+	return user, ok
+}
+
+// Len returns the number of users in the directory
+func (d *UserDirectory) Len() int {
+	return len(d.users)
+}
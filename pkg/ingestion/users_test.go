@@ -0,0 +1,99 @@
+package ingestion
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// userAwareMockProcessor records the user directory it was given
+type userAwareMockProcessor struct {
+	mockDocumentProcessor
+	users *UserDirectory
+}
+
+func (m *userAwareMockProcessor) SetUserDirectory(users *UserDirectory) {
+	m.users = users
+}
+
+const testUsersCSV = `color,deleted,id,is_bot,name,real_name,tz
+,TRUE,U010VFYCJG3,,christian,Christian Croghan,
+d1707d,,UFP6V5W4E,,senaida,Senaida Sallis,Europe/Brussels
+,,U0BOT,True,github,,
+,,,,,nobody,`
+
+func TestParseUsers(t *testing.T) {
+	users, err := ParseUsers(strings.NewReader(testUsersCSV))
+	if err != nil {
+		t.Fatalf("ParseUsers() error = %v", err)
+	}
+	if users.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", users.Len())
+	}
+
+	tests := []struct {
+		id          string
+		wantName    string
+		wantBot     bool
+		wantDeleted bool
+	}{
+		{"U010VFYCJG3", "Christian Croghan", false, true},
+		{"UFP6V5W4E", "Senaida Sallis", false, false},
+		{"U0BOT", "github", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			user, ok := users.Lookup(tt.id)
+			if !ok {
+				t.Fatalf("Lookup(%s) found nothing", tt.id)
+			}
+			if user.DisplayName() != tt.wantName || user.IsBot != tt.wantBot || user.Deleted != tt.wantDeleted {
+				t.Errorf("got %+v", user)
+			}
+		})
+	}
+
+	if _, ok := users.Lookup("UNKNOWN"); ok {
+		t.Error("Lookup() found an unknown user")
+	}
+	if _, err := ParseUsers(strings.NewReader("name,real_name\nalice,Alice")); err == nil {
+		t.Error("expected error for file without an id column")
+	}
+}
+
+func TestIngestDirectory_LoadsUsers(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		UsersFile: testUsersCSV,
+		"messages.csv": "channel_id,text,ts,type,user\n" +
+			"C1,Hello <@UFP6V5W4E>,1599934232.150700,message,U010VFYCJG3\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	processor := &userAwareMockProcessor{}
+	stats, err := NewService(&mockVectorClient{}, processor).IngestDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("IngestDirectory() error = %v", err)
+	}
+	if processor.users == nil || processor.users.Len() != 3 {
+		t.Fatalf("processor was not given the user directory")
+	}
+	if stats.ProcessedMessages != 1 || len(stats.Errors) != 0 {
+		t.Errorf("processed %d messages with errors %v; users.csv should not be ingested", stats.ProcessedMessages, stats.Errors)
+	}
+
+	// A single file picks up the users.csv next to it
+	processor = &userAwareMockProcessor{}
+	if _, err := NewService(&mockVectorClient{}, processor).IngestFile(context.Background(), filepath.Join(dir, "messages.csv")); err != nil {
+		t.Fatalf("IngestFile() error = %v", err)
+	}
+	if processor.users == nil {
+		t.Error("IngestFile did not load the sibling users.csv")
+	}
+}
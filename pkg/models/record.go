@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// Record is a source-agnostic item read by an ingestion connector, such as
+// a chat message, a wiki page or an email
+type Record struct {
+	// ID identifies the record across all sources and stays the same when
+	// the source is read again, so re-ingesting updates rather than
+	// duplicates its documents. Connectors other than Slack prefix it with
+	// their source, e.g. "markdown:docs/setup.md".
+	ID string `json:"id"`
+	// Source names the kind of system the record came from, e.g. "slack"
+	Source string `json:"source"`
+	Title  string `json:"title,omitempty"`
+	Text   string `json:"text"`
+	// RawText is the text as written in the source, e.g. with Slack
+	// markup, when Text was rendered from it. It is kept for display.
+	RawText string `json:"raw_text,omitempty"`
+	// Author is the author's display name and AuthorID the source system's
+	// ID for them
+	Author    string    `json:"author,omitempty"`
+	AuthorID  string    `json:"author_id,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Permissions lists who can read the record, as stored on its
+	// documents; see vector.PublicPermission
+	Permissions []string `json:"permissions,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// ThreadID groups records of the same conversation, and ParentID is
+	// the ID of the record this one answers
+	ThreadID string `json:"thread_id,omitempty"`
+	ParentID string `json:"parent_id,omitempty"`
+	// Links, LinkedChannels and Code are the URLs, channel IDs and code
+	// spans referenced by the text, if the source marks them up
+	Links          []string `json:"links,omitempty"`
+	LinkedChannels []string `json:"linked_channels,omitempty"`
+	Code           []string `json:"code,omitempty"`
+	// Engagement is how readers responded to the record, if the source
+	// tracks it
+	Engagement Engagement `json:"engagement,omitempty"`
+	// Metadata holds source-specific fields that have no place above
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Engagement counts the signals that a record was found useful
+type Engagement struct {
+	// Reactions is the number of emoji reactions
+	Reactions int `json:"reactions,omitempty"`
+	// Replies is the number of replies in the record's thread
+	Replies int  `json:"replies,omitempty"`
+	Pinned  bool `json:"pinned,omitempty"`
+}
+
+// Add returns the sum of both engagements, e.g. across a thread
+func (e Engagement) Add(other Engagement) Engagement {
+	return Engagement{
+		Reactions: e.Reactions + other.Reactions,
+		Replies:   e.Replies + other.Replies,
+		Pinned:    e.Pinned || other.Pinned,
+	}
+}
+
+// Record converts the message to a record. Fields that need the export's
+// user and channel tables, such as the author's name and the permissions,
+// are left for the document processor to fill in.
+func (m SlackMessage) Record() Record {
+	metadata := map[string]string{"channel": m.Channel}
+	for key, value := range map[string]string{
+		"ts":        m.TS,
+		"thread_ts": m.ThreadTS,
+		"type":      m.Type,
+		"subtype":   m.Subtype,
+		"bot_id":    m.BotID,
+		"bot_name":  m.BotName,
+		"edited":    m.Edited,
+		"reactions": m.Reactions.String(),
+	} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+
+	return Record{
+		ID:        m.MessageID,
+		Source:    "slack",
+		Text:      m.Content,
+		AuthorID:  m.User,
+		CreatedAt: m.Timestamp,
+		UpdatedAt: m.Timestamp,
+		ThreadID:  m.ThreadID(),
+		ParentID:  m.ParentMessageID,
+		Engagement: Engagement{
+			Reactions: m.Reactions.Total(),
+			Replies:   m.ReplyCount,
+			Pinned:    m.IsPinned(),
+		},
+		Metadata: metadata,
+	}
+}
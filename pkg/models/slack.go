@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // SlackMessage represents a message from Slack export
 type SlackMessage struct {
@@ -12,11 +18,201 @@ type SlackMessage struct {
 	ThreadTS  string    `json:"thread_ts"`
 	Type      string    `json:"type"`
 	Subtype   string    `json:"subtype"`
+	// TS is the message's own Slack timestamp (e.g. "1599934232.150700"),
+	// which identifies it within its channel
+	TS string `json:"ts,omitempty"`
+	// Blocks is the message's Block Kit layout as JSON, if exported
+	Blocks string `json:"blocks,omitempty"`
 	// Additional fields for richer data
-	ReplyCount   int      `json:"reply_count,omitempty"`
-	ReplyUsers   []string `json:"reply_users,omitempty"`
-	Reactions    string   `json:"reactions,omitempty"`
+	ReplyCount      int      `json:"reply_count,omitempty"`
+	ReplyUsers      []string `json:"reply_users,omitempty"`
+	ReplyUsersCount int      `json:"reply_users_count,omitempty"`
+	LatestReply     string   `json:"latest_reply,omitempty"`
+	// Reactions are the emoji reactions on the message
+	Reactions SlackReactions `json:"reactions,omitempty"`
+	// PinnedTo lists the channels the message is pinned in
+	PinnedTo     []string `json:"pinned_to,omitempty"`
 	ParentUserID string   `json:"parent_user_id,omitempty"`
 	BotID        string   `json:"bot_id,omitempty"`
-	FileIDs      []string `json:"file_ids,omitempty"`
+	// BotName and BotAppID come from the posting bot's profile, e.g.
+	// "GitHub". Legacy integrations only set the username they post as.
+	BotName  string   `json:"bot_name,omitempty"`
+	BotAppID string   `json:"bot_app_id,omitempty"`
+	FileIDs  []string `json:"file_ids,omitempty"`
+	// Edited is the Slack timestamp of the last edit, if the message was edited
+	Edited string `json:"edited,omitempty"`
+	// ParentMessageID is set on thread replies once threads are reconstructed
+	ParentMessageID string `json:"parent_message_id,omitempty"`
+}
+
+// IsPinned reports whether the message is pinned in any channel
+func (m SlackMessage) IsPinned() bool {
+	return len(m.PinnedTo) > 0
+}
+
+// IsThreadReply reports whether the message is a reply within a thread
+func (m SlackMessage) IsThreadReply() bool {
+	return m.ThreadTS != "" && m.TS != "" && m.ThreadTS != m.TS
+}
+
+// ThreadID returns the ID of the thread the message starts or replies to,
+// or "" if it is not part of a thread
+func (m SlackMessage) ThreadID() string {
+	switch {
+	case m.IsThreadReply():
+		return SlackThreadID(m.Channel, m.ThreadTS)
+	case m.ReplyCount > 0 && m.TS != "":
+		return SlackThreadID(m.Channel, m.TS)
+	default:
+		return ""
+	}
+}
+
+// SlackReaction is one emoji reaction on a message and who reacted with it
+type SlackReaction struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Users []string `json:"users,omitempty"`
+}
+
+// SlackReactions are the reactions on a message, as exported by Slack
+type SlackReactions []SlackReaction
+
+// ParseSlackReactions parses the reactions JSON of a Slack export, e.g.
+// [{"name": "wave", "count": 2, "users": ["U01", "U02"]}]
+func ParseSlackReactions(s string) (SlackReactions, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "null" {
+		return nil, nil
+	}
+	var reactions SlackReactions
+	if err := json.Unmarshal([]byte(s), &reactions); err != nil {
+		return nil, fmt.Errorf("failed to parse reactions: %w", err)
+	}
+	return reactions, nil
+}
+
+// UnmarshalJSON accepts the reactions array, or the array encoded as a
+// JSON string as older dead-letter files stored it
+func (r *SlackReactions) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		reactions, err := ParseSlackReactions(s)
+		if err != nil {
+			return err
+		}
+		*r = reactions
+		return nil
+	}
+	var reactions []SlackReaction
+	if err := json.Unmarshal(data, &reactions); err != nil {
+		return err
+	}
+	*r = reactions
+	return nil
+}
+
+// total returns the reaction's count, falling back to its users when the
+// export leaves the count out
+func (r SlackReaction) total() int {
+	if r.Count == 0 {
+		return len(r.Users)
+	}
+	return r.Count
+}
+
+// Total returns the number of reactions across all emoji
+func (r SlackReactions) Total() int {
+	total := 0
+	for _, reaction := range r {
+		total += reaction.total()
+	}
+	return total
+}
+
+// String lists the reactions as "name:count", e.g. "wave:2,+1:1"
+func (r SlackReactions) String() string {
+	parts := make([]string, 0, len(r))
+	for _, reaction := range r {
+		parts = append(parts, reaction.Name+":"+strconv.Itoa(reaction.total()))
+	}
+	return strings.Join(parts, ",")
+}
+
+// SlackThreadID builds a thread ID from a channel and the parent's timestamp
+func SlackThreadID(channel, threadTS string) string {
+	return channel + ":" + threadTS
+}
+
+// SlackThread is a thread parent together with its replies in posting order
+type SlackThread struct {
+	Channel  string         `json:"channel"`
+	ThreadTS string         `json:"thread_ts"`
+	Parent   SlackMessage   `json:"parent"`
+	Replies  []SlackMessage `json:"replies"`
+}
+
+// ID returns the thread's ID
+func (t SlackThread) ID() string {
+	return SlackThreadID(t.Channel, t.ThreadTS)
+}
+
+// Participants returns the distinct users in the thread, parent author first
+func (t SlackThread) Participants() []string {
+	seen := make(map[string]bool)
+	var users []string
+	for _, msg := range append([]SlackMessage{t.Parent}, t.Replies...) {
+		if msg.User != "" && !seen[msg.User] {
+			seen[msg.User] = true
+			users = append(users, msg.User)
+		}
+	}
+	return users
+}
+
+// SlackUser represents a workspace member from a Slack export's users.csv
+type SlackUser struct {
+	ID       string
+	Name     string
+	RealName string
+	TZ       string
+	IsBot    bool
+	Deleted  bool
+}
+
+// DisplayName returns the most readable name available for the user
+func (u SlackUser) DisplayName() string {
+	switch {
+	case u.RealName != "":
+		return u.RealName
+	case u.Name != "":
+		return u.Name
+	default:
+		return u.ID
+	}
+}
+
+// SlackChannel represents a channel from a Slack export's channels.csv
+type SlackChannel struct {
+	ID         string
+	Name       string
+	IsPrivate  bool
+	IsArchived bool
+	IsGeneral  bool
+	Purpose    string
+	Topic      string
+	// Members holds the IDs of the channel's members, if known
+	Members []string
+}
+
+// DisplayName returns the channel name prefixed with #, or the ID if the
+// name is unknown
+func (c SlackChannel) DisplayName() string {
+	if c.Name == "" {
+		return c.ID
+	}
+	return "#" + c.Name
 }
@@ -14,16 +14,39 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	timeouts   Timeouts
 }
 
-// NewClient creates a new Ollama client
-func NewClient(baseURL string) *Client {
-	return &Client{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+// NewClient creates a new Ollama client. Timeouts default to DefaultTimeouts
+// and can be overridden per request through ChatRequest.Timeouts.
+func NewClient(baseURL string, timeouts ...Timeouts) *Client {
+	t := DefaultTimeouts()
+	if len(timeouts) > 0 {
+		t = timeouts[0]
 	}
+
+	c := &Client{
+		baseURL:  baseURL,
+		timeouts: t,
+	}
+
+	// No overall http.Client timeout: it would also bound the time spent
+	// reading a streamed body. Stage timeouts are enforced per request.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = c.dialContext
+	c.httpClient = &http.Client{Transport: transport}
+
+	return c
+}
+
+// BaseURL returns the Ollama server URL the client talks to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Timeouts returns the client's default request timeouts
+func (c *Client) Timeouts() Timeouts {
+	return c.timeouts
 }
 
 // ChatRequest represents a chat completion request
@@ -32,6 +55,15 @@ type ChatRequest struct {
 	Messages []Message `json:"messages"`
 	Stream   bool      `json:"stream"`
 	Options  *Options  `json:"options,omitempty"`
+
+	// KeepAlive controls how long the model stays loaded after this
+	// request; see KeepAliveForever and KeepAliveUnload. Empty uses the
+	// server default.
+	KeepAlive string `json:"keep_alive,omitempty"`
+
+	// Timeouts overrides the client's timeouts for this request. Zero fields
+	// fall back to the client defaults.
+	Timeouts *Timeouts `json:"-"`
 }
 
 // Message represents a chat message
@@ -49,20 +81,36 @@ type Options struct {
 	NumCtx      int     `json:"num_ctx,omitempty"`
 }
 
+// Metrics holds the timings and token counts Ollama reports on the final
+// response of a request
+type Metrics struct {
+	TotalDuration      time.Duration `json:"total_duration,omitempty"`
+	LoadDuration       time.Duration `json:"load_duration,omitempty"`
+	PromptEvalCount    int           `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration time.Duration `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int           `json:"eval_count,omitempty"`
+	EvalDuration       time.Duration `json:"eval_duration,omitempty"`
+}
+
 // ChatResponse represents a chat completion response
 type ChatResponse struct {
-	Model     string    `json:"model"`
-	CreatedAt time.Time `json:"created_at"`
-	Message   Message   `json:"message"`
-	Done      bool      `json:"done"`
+	Model      string    `json:"model"`
+	CreatedAt  time.Time `json:"created_at"`
+	Message    Message   `json:"message"`
+	Done       bool      `json:"done"`
+	DoneReason string    `json:"done_reason,omitempty"`
+	Metrics
 }
 
-// StreamResponse represents a streaming response chunk
+// StreamResponse represents a streaming response chunk. Metrics are only
+// populated on the final chunk.
 type StreamResponse struct {
-	Model     string    `json:"model"`
-	CreatedAt time.Time `json:"created_at"`
-	Message   Message   `json:"message"`
-	Done      bool      `json:"done"`
+	Model      string    `json:"model"`
+	CreatedAt  time.Time `json:"created_at"`
+	Message    Message   `json:"message"`
+	Done       bool      `json:"done"`
+	DoneReason string    `json:"done_reason,omitempty"`
+	Metrics
 }
 
 // ModelInfo represents information about a model
@@ -87,6 +135,14 @@ func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, erro
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	// Non-streaming responses arrive in one piece, so the first-token
+	// timeout bounds the whole generation
+	timeouts := c.timeouts.merge(req.Timeouts)
+	ctx, cancel := context.WithCancel(withTimeouts(ctx, timeouts))
+	defer cancel()
+	wd := newWatchdog(cancel, TimeoutStageFirstToken, timeouts.FirstToken)
+	defer wd.stop()
+
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/chat", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -95,24 +151,25 @@ func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, erro
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", wd.check(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return nil, newStatusError(resp.StatusCode, body)
 	}
 
 	var chatResp ChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to decode response: %w", wd.check(err))
 	}
 
 	return &chatResp, nil
 }
 
-// ChatStream sends a streaming chat completion request
+// ChatStream sends a streaming chat completion request. If a stage timeout
+// is exceeded, a *TimeoutError is sent on the error channel.
 func (c *Client) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamResponse, <-chan error) {
 	req.Stream = true
 	respChan := make(chan StreamResponse)
@@ -128,7 +185,13 @@ func (c *Client) ChatStream(ctx context.Context, req ChatRequest) (<-chan Stream
 			return
 		}
 
-		httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/chat", bytes.NewReader(body))
+		timeouts := c.timeouts.merge(req.Timeouts)
+		reqCtx, cancel := context.WithCancel(withTimeouts(ctx, timeouts))
+		defer cancel()
+		wd := newWatchdog(cancel, TimeoutStageFirstToken, timeouts.FirstToken)
+		defer wd.stop()
+
+		httpReq, err := http.NewRequestWithContext(reqCtx, "POST", c.baseURL+"/api/chat", bytes.NewReader(body))
 		if err != nil {
 			errChan <- fmt.Errorf("failed to create request: %w", err)
 			return
@@ -137,14 +200,14 @@ func (c *Client) ChatStream(ctx context.Context, req ChatRequest) (<-chan Stream
 
 		resp, err := c.httpClient.Do(httpReq)
 		if err != nil {
-			errChan <- fmt.Errorf("failed to send request: %w", err)
+			errChan <- fmt.Errorf("failed to send request: %w", wd.check(err))
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			body, _ := io.ReadAll(resp.Body)
-			errChan <- fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+			errChan <- newStatusError(resp.StatusCode, body)
 			return
 		}
 
@@ -155,10 +218,13 @@ func (c *Client) ChatStream(ctx context.Context, req ChatRequest) (<-chan Stream
 				if err == io.EOF {
 					break
 				}
-				errChan <- fmt.Errorf("failed to decode stream response: %w", err)
+				errChan <- fmt.Errorf("failed to decode stream response: %w", wd.check(err))
 				return
 			}
 
+			// Don't count time spent waiting on a slow consumer as idle time
+			wd.stop()
+
 			select {
 			case respChan <- streamResp:
 			case <-ctx.Done():
@@ -168,6 +234,8 @@ func (c *Client) ChatStream(ctx context.Context, req ChatRequest) (<-chan Stream
 			if streamResp.Done {
 				break
 			}
+
+			wd.reset(TimeoutStageIdle, timeouts.Idle)
 		}
 	}()
 
@@ -176,25 +244,9 @@ func (c *Client) ChatStream(ctx context.Context, req ChatRequest) (<-chan Stream
 
 // ListModels lists all available models
 func (c *Client) ListModels(ctx context.Context) ([]ModelInfo, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/tags", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
-	}
-
 	var listResp ListModelsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := c.doJSON(ctx, "GET", "/api/tags", nil, &listResp); err != nil {
+		return nil, err
 	}
 
 	return listResp.Models, nil
@@ -202,71 +254,56 @@ func (c *Client) ListModels(ctx context.Context) ([]ModelInfo, error) {
 
 // PullModel pulls a model from the Ollama library
 func (c *Client) PullModel(ctx context.Context, modelName string) error {
-	req := map[string]string{
-		"name": modelName,
-	}
+	return c.PullModelWithProgress(ctx, modelName, nil)
+}
 
-	body, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+// Ping checks if the Ollama server is responsive
+func (c *Client) Ping(ctx context.Context) error {
+	return c.doJSON(ctx, "GET", "/api/tags", nil, nil)
+}
+
+// doJSON sends a JSON request and decodes the JSON response into out.
+// A nil body sends no request body and a nil out discards the response.
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/pull", bytes.NewReader(body))
+	ctx, cancel := context.WithCancel(withTimeouts(ctx, c.timeouts))
+	defer cancel()
+	wd := newWatchdog(cancel, TimeoutStageFirstToken, c.timeouts.FirstToken)
+	defer wd.stop()
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	// Use a longer timeout for model pulling
-	client := &http.Client{
-		Timeout: 30 * time.Minute,
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
 	}
 
-	resp, err := client.Do(httpReq)
+	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return fmt.Errorf("failed to send request: %w", wd.check(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return newStatusError(resp.StatusCode, body)
 	}
 
-	// Read the streaming response (model pulling is a streaming operation)
-	decoder := json.NewDecoder(resp.Body)
-	for {
-		var status map[string]interface{}
-		if err := decoder.Decode(&status); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("failed to decode status: %w", err)
-		}
-		// The pull is complete when we receive a status with "status": "success"
-		if s, ok := status["status"].(string); ok && s == "success" {
-			break
-		}
+	if out == nil {
+		return nil
 	}
 
-	return nil
-}
-
-// Ping checks if the Ollama server is responsive
-func (c *Client) Ping(ctx context.Context) error {
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/tags", nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", wd.check(err))
 	}
 
 	return nil
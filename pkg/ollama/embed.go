@@ -0,0 +1,33 @@
+package ollama
+
+import (
+	"context"
+	"time"
+)
+
+// EmbedRequest represents a request to the embed API
+type EmbedRequest struct {
+	Model string `json:"model"`
+	// Input is a single string or a slice of strings
+	Input interface{} `json:"input"`
+	// KeepAlive controls how long the model stays loaded after this request
+	KeepAlive string `json:"keep_alive,omitempty"`
+}
+
+// EmbedResponse represents the response from the embed API
+type EmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+
+	TotalDuration time.Duration `json:"total_duration,omitempty"`
+	LoadDuration  time.Duration `json:"load_duration,omitempty"`
+}
+
+// Embed generates embeddings for the request input
+func (c *Client) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	var embedResp EmbedResponse
+	if err := c.doJSON(ctx, "POST", "/api/embed", req, &embedResp); err != nil {
+		return nil, err
+	}
+	return &embedResp, nil
+}
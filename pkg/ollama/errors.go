@@ -0,0 +1,22 @@
+package ollama
+
+import "fmt"
+
+// StatusError is returned when Ollama responds with a non-200 status code
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// newStatusError creates a StatusError from a response status and body
+func newStatusError(statusCode int, body []byte) *StatusError {
+	return &StatusError{
+		StatusCode: statusCode,
+		Body:       string(body),
+	}
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+}
@@ -0,0 +1,120 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Special keep_alive values understood by Ollama
+const (
+	// KeepAliveForever keeps a model loaded until it is explicitly unloaded
+	KeepAliveForever = "-1"
+	// KeepAliveUnload unloads a model as soon as the request completes
+	KeepAliveUnload = "0"
+)
+
+// ParseKeepAlive validates a keep_alive value: a Go duration such as "30m",
+// a number of seconds, or a negative value to keep the model loaded forever.
+// The empty string is accepted and means the server default.
+func ParseKeepAlive(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	if _, err := strconv.Atoi(value); err == nil {
+		return value, nil
+	}
+	if _, err := time.ParseDuration(value); err == nil {
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid keep_alive %q: expected a duration or number of seconds", value)
+}
+
+// PreloadRequest describes a model to load into memory ahead of use
+type PreloadRequest struct {
+	Model string
+	// Embedding selects the embed endpoint for models that cannot chat
+	Embedding bool
+	KeepAlive string
+}
+
+// PreloadResult reports how long a node took to load a model
+type PreloadResult struct {
+	Node         string        `json:"node"`
+	Model        string        `json:"model"`
+	LoadDuration time.Duration `json:"load_duration"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// Preload loads a model into memory without generating anything, so the
+// first real request does not pay the load time. The returned duration is
+// the load time reported by Ollama, which is zero if the model was already
+// resident.
+func (c *Client) Preload(ctx context.Context, req PreloadRequest) (time.Duration, error) {
+	if req.Embedding {
+		var embedResp EmbedResponse
+		err := c.doJSON(ctx, "POST", "/api/embed", EmbedRequest{
+			Model:     req.Model,
+			Input:     []string{},
+			KeepAlive: req.KeepAlive,
+		}, &embedResp)
+		if err != nil {
+			return 0, err
+		}
+		return embedResp.LoadDuration, nil
+	}
+
+	// A chat request without messages only loads the model
+	var chatResp ChatResponse
+	err := c.doJSON(ctx, "POST", "/api/chat", ChatRequest{
+		Model:     req.Model,
+		Messages:  []Message{},
+		KeepAlive: req.KeepAlive,
+	}, &chatResp)
+	if err != nil {
+		return 0, err
+	}
+	return chatResp.LoadDuration, nil
+}
+
+// Unload evicts a model from memory
+func (c *Client) Unload(ctx context.Context, model string) error {
+	return c.doJSON(ctx, "POST", "/api/chat", ChatRequest{
+		Model:     model,
+		Messages:  []Message{},
+		KeepAlive: KeepAliveUnload,
+	}, nil)
+}
+
+// Preload loads the requested models on every node of the pool in parallel.
+// Failures are reported per node rather than aborting the warmup, since a
+// model that cannot be loaded on one node may still be served by others.
+func (p *Pool) Preload(ctx context.Context, reqs ...PreloadRequest) []PreloadResult {
+	results := make([]PreloadResult, len(p.nodes)*len(reqs))
+
+	var wg sync.WaitGroup
+	for i, n := range p.nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+			// Models on the same node are loaded one at a time so they do
+			// not compete for memory
+			for j, req := range reqs {
+				result := PreloadResult{Node: n.url, Model: req.Model}
+				loadDuration, err := n.client.Preload(ctx, req)
+				if err != nil {
+					result.Error = err.Error()
+				}
+				result.LoadDuration = loadDuration
+				results[i*len(reqs)+j] = result
+			}
+		}(i, n)
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,30 @@
+package ollama
+
+import "testing"
+
+func TestParseKeepAlive(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "30m", want: "30m"},
+		{value: " 1h ", want: "1h"},
+		{value: "-1", want: KeepAliveForever},
+		{value: "0", want: KeepAliveUnload},
+		{value: "300", want: "300"},
+		{value: "forever", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseKeepAlive(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseKeepAlive(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseKeepAlive(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ModelDetails describes the format and size of a model
+type ModelDetails struct {
+	ParentModel       string   `json:"parent_model,omitempty"`
+	Format            string   `json:"format,omitempty"`
+	Family            string   `json:"family,omitempty"`
+	Families          []string `json:"families,omitempty"`
+	ParameterSize     string   `json:"parameter_size,omitempty"`
+	QuantizationLevel string   `json:"quantization_level,omitempty"`
+}
+
+// ShowModelResponse represents the response from showing a model
+type ShowModelResponse struct {
+	Modelfile    string                 `json:"modelfile,omitempty"`
+	Parameters   string                 `json:"parameters,omitempty"`
+	Template     string                 `json:"template,omitempty"`
+	System       string                 `json:"system,omitempty"`
+	License      string                 `json:"license,omitempty"`
+	Details      ModelDetails           `json:"details"`
+	ModelInfo    map[string]interface{} `json:"model_info,omitempty"`
+	Capabilities []string               `json:"capabilities,omitempty"`
+	ModifiedAt   time.Time              `json:"modified_at,omitempty"`
+}
+
+// ContextLength returns the model's trained context length, or 0 if unknown
+func (r *ShowModelResponse) ContextLength() int {
+	// model_info keys are prefixed with the architecture, e.g. "llama.context_length"
+	for key, value := range r.ModelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if n, ok := value.(float64); ok {
+			return int(n)
+		}
+	}
+	return 0
+}
+
+// RunningModel represents a model currently loaded into memory
+type RunningModel struct {
+	Name      string       `json:"name"`
+	Model     string       `json:"model"`
+	Size      int64        `json:"size"`
+	SizeVRAM  int64        `json:"size_vram"`
+	Digest    string       `json:"digest"`
+	Details   ModelDetails `json:"details"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// ListRunningResponse represents the response from listing running models
+type ListRunningResponse struct {
+	Models []RunningModel `json:"models"`
+}
+
+// PullProgress represents a single progress update while pulling a model
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Percent returns the completion percentage of the current layer, or 0 if unknown
+func (p PullProgress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Completed) / float64(p.Total) * 100
+}
+
+// PullProgressFunc is called for each progress update while pulling a model
+type PullProgressFunc func(progress PullProgress)
+
+// ShowModel returns details about a model, including its parameters and template
+func (c *Client) ShowModel(ctx context.Context, modelName string) (*ShowModelResponse, error) {
+	var showResp ShowModelResponse
+	if err := c.doJSON(ctx, "POST", "/api/show", map[string]string{"model": modelName}, &showResp); err != nil {
+		return nil, err
+	}
+	return &showResp, nil
+}
+
+// DeleteModel removes a model and its data from the Ollama server
+func (c *Client) DeleteModel(ctx context.Context, modelName string) error {
+	return c.doJSON(ctx, "DELETE", "/api/delete", map[string]string{"model": modelName}, nil)
+}
+
+// CopyModel creates a copy of a model under a new name
+func (c *Client) CopyModel(ctx context.Context, source, destination string) error {
+	req := map[string]string{
+		"source":      source,
+		"destination": destination,
+	}
+	return c.doJSON(ctx, "POST", "/api/copy", req, nil)
+}
+
+// ListRunning lists the models currently loaded into memory
+func (c *Client) ListRunning(ctx context.Context) ([]RunningModel, error) {
+	var psResp ListRunningResponse
+	if err := c.doJSON(ctx, "GET", "/api/ps", nil, &psResp); err != nil {
+		return nil, err
+	}
+	return psResp.Models, nil
+}
+
+// PullModelWithProgress pulls a model from the Ollama library, reporting
+// per-layer download progress to fn. A nil fn discards progress updates.
+func (c *Client) PullModelWithProgress(ctx context.Context, modelName string, fn PullProgressFunc) error {
+	req := map[string]interface{}{
+		"model":  modelName,
+		"stream": true,
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Pulls stream progress updates continuously, so the idle timeout
+	// detects a stalled download without bounding the total pull time
+	ctx, cancel := context.WithCancel(withTimeouts(ctx, c.timeouts))
+	defer cancel()
+	wd := newWatchdog(cancel, TimeoutStageFirstToken, c.timeouts.FirstToken)
+	defer wd.stop()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", wd.check(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return newStatusError(resp.StatusCode, body)
+	}
+
+	// Read the streaming response (model pulling is a streaming operation)
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var progress PullProgress
+		if err := decoder.Decode(&progress); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("pull of %s ended before completion", modelName)
+			}
+			return fmt.Errorf("failed to decode status: %w", wd.check(err))
+		}
+		wd.reset(TimeoutStageIdle, c.timeouts.Idle)
+
+		if progress.Error != "" {
+			return fmt.Errorf("failed to pull model %s: %s", modelName, progress.Error)
+		}
+
+		if fn != nil {
+			fn(progress)
+		}
+
+		// The pull is complete when we receive a status with "status": "success"
+		if progress.Status == "success" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestShowModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/show" || r.Method != "POST" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] != "llama3:8b" {
+			t.Errorf("expected model llama3:8b, got %s", req["model"])
+		}
+		_, _ = w.Write([]byte(`{
+			"parameters": "stop \"<|eot_id|>\"",
+			"template": "{{ .Prompt }}",
+			"details": {"family": "llama", "parameter_size": "8.0B"},
+			"model_info": {"general.architecture": "llama", "llama.context_length": 8192}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	resp, err := client.ShowModel(context.Background(), "llama3:8b")
+	if err != nil {
+		t.Fatalf("ShowModel() error = %v", err)
+	}
+
+	if resp.ContextLength() != 8192 {
+		t.Errorf("ContextLength() = %d, want 8192", resp.ContextLength())
+	}
+	if resp.Template != "{{ .Prompt }}" {
+		t.Errorf("Template = %q", resp.Template)
+	}
+	if resp.Details.ParameterSize != "8.0B" {
+		t.Errorf("ParameterSize = %q, want 8.0B", resp.Details.ParameterSize)
+	}
+}
+
+func TestDeleteAndCopyModel(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/api/delete" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"model not found"}`))
+			return
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	ctx := context.Background()
+
+	if err := client.CopyModel(ctx, "llama3:8b", "llama3-backup"); err != nil {
+		t.Errorf("CopyModel() error = %v", err)
+	}
+	if err := client.DeleteModel(ctx, "missing"); err == nil {
+		t.Error("DeleteModel() expected error for missing model")
+	}
+
+	want := []string{"POST /api/copy", "DELETE /api/delete"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %s, want %s", i, calls[i], want[i])
+		}
+	}
+}
+
+func TestListRunning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/ps" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"models":[{"name":"llama3:8b","model":"llama3:8b","size":5000,"size_vram":0}]}`))
+	}))
+	defer server.Close()
+
+	running, err := NewClient(server.URL).ListRunning(context.Background())
+	if err != nil {
+		t.Fatalf("ListRunning() error = %v", err)
+	}
+	if len(running) != 1 || running[0].Name != "llama3:8b" {
+		t.Errorf("ListRunning() = %+v", running)
+	}
+}
+
+func TestPullModelWithProgress(t *testing.T) {
+	tests := []struct {
+		name      string
+		stream    string
+		wantErr   bool
+		wantCalls int
+	}{
+		{
+			name: "successful pull",
+			stream: `{"status":"pulling manifest"}
+{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":50}
+{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":100}
+{"status":"success"}
+`,
+			wantCalls: 4,
+		},
+		{
+			name: "error in stream",
+			stream: `{"status":"pulling manifest"}
+{"error":"pull model manifest: file does not exist"}
+`,
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:      "stream ends without success",
+			stream:    `{"status":"pulling manifest"}` + "\n",
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.stream))
+			}))
+			defer server.Close()
+
+			var updates []PullProgress
+			err := NewClient(server.URL).PullModelWithProgress(context.Background(), "llama3:8b", func(p PullProgress) {
+				updates = append(updates, p)
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PullModelWithProgress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(updates) != tt.wantCalls {
+				t.Errorf("got %d progress updates, want %d", len(updates), tt.wantCalls)
+			}
+			if !tt.wantErr && updates[1].Percent() != 50 {
+				t.Errorf("Percent() = %.1f, want 50", updates[1].Percent())
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package ollamatest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// Embedding returns the deterministic unit-length vector the fake server
+// generates for text. Tests can use it to build expected vectors or to seed
+// a vector store with documents that match a query exactly.
+func Embedding(text string, dim int) []float32 {
+	if dim <= 0 {
+		dim = DefaultEmbeddingDimension
+	}
+
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(text))
+	state := hasher.Sum64()
+
+	vec := make([]float32, dim)
+	var norm float64
+	for i := range vec {
+		// xorshift64 keeps the sequence stable across Go versions
+		state ^= state << 13
+		state ^= state >> 7
+		state ^= state << 17
+		v := float64(state%2000)/1000 - 1
+		vec[i] = float32(v)
+		norm += v * v
+	}
+
+	if norm == 0 {
+		vec[0] = 1
+		return vec
+	}
+	scale := 1 / math.Sqrt(norm)
+	for i := range vec {
+		vec[i] = float32(float64(vec[i]) * scale)
+	}
+	return vec
+}
+
+// digest returns a stable fake sha256 digest for a model name
+func digest(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return fmt.Sprintf("sha256:%x", sum)
+}
@@ -0,0 +1,124 @@
+package ollamatest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/ollama"
+)
+
+// defaultKeepAlive mirrors Ollama's default of keeping a model loaded for
+// five minutes after its last request
+const defaultKeepAlive = 5 * time.Minute
+
+// SetLoadDelay simulates model load time: the first request for a model
+// that is not resident waits d and reports it as load_duration
+func (s *Server) SetLoadDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadDelay = d
+}
+
+// LoadedModels returns the names of the models currently resident
+func (s *Server) LoadedModels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireLocked()
+	names := make([]string, 0, len(s.loaded))
+	for name := range s.loaded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// KeepAlives returns the keep_alive values received for a model, in order
+func (s *Server) KeepAlives(model string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make([]string, len(s.keepAlives[model]))
+	copy(values, s.keepAlives[model])
+	return values
+}
+
+// load makes model resident, waiting for the configured load delay if it
+// was not, and applies keepAlive. It returns the simulated load time and
+// false if the client went away while loading.
+func (s *Server) load(r *http.Request, model, keepAlive string) (time.Duration, bool) {
+	s.mu.Lock()
+	s.expireLocked()
+	s.keepAlives[model] = append(s.keepAlives[model], keepAlive)
+	_, resident := s.loaded[model]
+	delay := s.loadDelay
+	s.mu.Unlock()
+
+	var loadDuration time.Duration
+	if !resident {
+		if !sleep(r, delay) {
+			return 0, false
+		}
+		loadDuration = delay
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ttl, forever := parseKeepAlive(keepAlive)
+	switch {
+	case forever:
+		s.loaded[model] = time.Time{}
+	case ttl == 0:
+		delete(s.loaded, model)
+	default:
+		s.loaded[model] = time.Now().Add(ttl)
+	}
+	return loadDuration, true
+}
+
+// expireLocked unloads models whose keep_alive has elapsed
+func (s *Server) expireLocked() {
+	now := time.Now()
+	for name, expiresAt := range s.loaded {
+		if !expiresAt.IsZero() && now.After(expiresAt) {
+			delete(s.loaded, name)
+		}
+	}
+}
+
+// parseKeepAlive interprets a keep_alive value the way Ollama does,
+// reporting forever for negative values
+func parseKeepAlive(value string) (time.Duration, bool) {
+	if value == "" {
+		return defaultKeepAlive, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, seconds < 0
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d, d < 0
+	}
+	return defaultKeepAlive, false
+}
+
+// handlePS serves /api/ps with the models currently resident
+func (s *Server) handlePS(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.expireLocked()
+	running := make([]ollama.RunningModel, 0, len(s.loaded))
+	for name, expiresAt := range s.loaded {
+		running = append(running, ollama.RunningModel{
+			Name:      name,
+			Model:     name,
+			Digest:    digest(name),
+			ExpiresAt: expiresAt,
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].Name < running[j].Name
+	})
+	writeJSON(w, ollama.ListRunningResponse{Models: running})
+}
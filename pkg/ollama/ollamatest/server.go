@@ -0,0 +1,684 @@
+// Package ollamatest provides a scriptable in-process Ollama server for
+// tests. It implements enough of the Ollama HTTP API for the chat, RAG and
+// model management code paths to run without a real model:
+//
+//	server := ollamatest.NewServer()
+//	defer server.Close()
+//
+//	server.SetResponse("canned answer")
+//	client := ollama.NewClient(server.URL)
+//
+// Chat replies echo the last user message unless a canned response or a
+// responder function is configured. Embeddings are derived from a hash of
+// the input text, so the same text always yields the same vector.
+// Models become resident on first use, honouring keep_alive, and
+// SetLoadDelay simulates the cold-start cost reported as load_duration.
+package ollamatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/ollama"
+)
+
+// Endpoint paths served by the fake server
+const (
+	PathChat   = "/api/chat"
+	PathEmbed  = "/api/embed"
+	PathTags   = "/api/tags"
+	PathPull   = "/api/pull"
+	PathShow   = "/api/show"
+	PathPS     = "/api/ps"
+	PathDelete = "/api/delete"
+	PathCopy   = "/api/copy"
+)
+
+// DefaultEmbeddingDimension is the length of generated embeddings unless
+// changed with SetEmbeddingDimension
+const DefaultEmbeddingDimension = 8
+
+// DefaultContextLength is reported by /api/show for models added without
+// an explicit context length
+const DefaultContextLength = 8192
+
+// Responder produces the assistant reply for a chat request
+type Responder func(req ollama.ChatRequest) string
+
+// FaultMode selects how an injected fault manifests
+type FaultMode int
+
+const (
+	// FaultStatus replies with an HTTP error status and Ollama-style error body
+	FaultStatus FaultMode = iota
+	// FaultHang accepts the request but never responds until the client gives up
+	FaultHang
+	// FaultDisconnect closes the connection, after AfterChunks stream chunks
+	// for streaming chat requests
+	FaultDisconnect
+)
+
+// Fault describes an error to inject on an endpoint
+type Fault struct {
+	Mode FaultMode
+	// StatusCode and Message are used by FaultStatus; they default to 500
+	// and "injected fault"
+	StatusCode int
+	Message    string
+	// AfterChunks delays a FaultDisconnect or FaultHang on streaming chat
+	// requests until that many chunks have been sent
+	AfterChunks int
+	// Times limits how many requests the fault applies to; zero means every
+	// request until ClearFaults is called
+	Times int
+}
+
+// fakeModel is a model known to the fake server
+type fakeModel struct {
+	name          string
+	contextLength int
+	modifiedAt    time.Time
+}
+
+// Server is a fake Ollama server backed by httptest
+type Server struct {
+	// URL is the base URL of the server, suitable for ollama.NewClient
+	URL string
+
+	server *httptest.Server
+	closed chan struct{}
+
+	mu            sync.Mutex
+	models        map[string]*fakeModel
+	responder     Responder
+	latency       time.Duration
+	chunkDelay    time.Duration
+	embeddingDim  int
+	faults        map[string]*Fault
+	requestCounts map[string]int
+	chatRequests  []ollama.ChatRequest
+
+	// Model residency, keyed by model name. A zero expiry means the model
+	// stays loaded until unloaded explicitly.
+	loadDelay  time.Duration
+	loaded     map[string]time.Time
+	keepAlives map[string][]string
+}
+
+// NewServer starts a fake server that knows the given models. Chat and
+// embedding requests are accepted for any model name.
+func NewServer(models ...string) *Server {
+	s := &Server{
+		closed:        make(chan struct{}),
+		models:        make(map[string]*fakeModel),
+		embeddingDim:  DefaultEmbeddingDimension,
+		faults:        make(map[string]*Fault),
+		requestCounts: make(map[string]int),
+		loaded:        make(map[string]time.Time),
+		keepAlives:    make(map[string][]string),
+	}
+	for _, name := range models {
+		s.AddModel(name, DefaultContextLength)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathChat, s.handleChat)
+	mux.HandleFunc(PathEmbed, s.handleEmbed)
+	mux.HandleFunc(PathTags, s.handleTags)
+	mux.HandleFunc(PathPull, s.handlePull)
+	mux.HandleFunc(PathShow, s.handleShow)
+	mux.HandleFunc(PathPS, s.handlePS)
+	mux.HandleFunc(PathDelete, s.handleDelete)
+	mux.HandleFunc(PathCopy, s.handleCopy)
+
+	s.server = httptest.NewServer(s.intercept(mux))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts down the server, unblocking any hung requests
+func (s *Server) Close() {
+	close(s.closed)
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// AddModel registers a model as installed
+func (s *Server) AddModel(name string, contextLength int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models[name] = &fakeModel{
+		name:          name,
+		contextLength: contextLength,
+		modifiedAt:    time.Now(),
+	}
+}
+
+// HasModel reports whether a model is installed
+func (s *Server) HasModel(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.models[name]
+	return ok
+}
+
+// SetResponse makes every chat request answer with the given text
+func (s *Server) SetResponse(content string) {
+	s.SetResponder(func(ollama.ChatRequest) string {
+		return content
+	})
+}
+
+// SetResponder sets a function that computes chat replies. A nil
+// responder restores the default echo behaviour.
+func (s *Server) SetResponder(responder Responder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responder = responder
+}
+
+// SetLatency delays every response by d before any data is written,
+// simulating model load and prompt evaluation time
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetChunkDelay sets the pause between streamed chat chunks
+func (s *Server) SetChunkDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunkDelay = d
+}
+
+// SetEmbeddingDimension sets the length of generated embeddings
+func (s *Server) SetEmbeddingDimension(dim int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embeddingDim = dim
+}
+
+// InjectFault makes requests to path fail as described by fault
+func (s *Server) InjectFault(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := fault
+	s.faults[path] = &f
+}
+
+// ClearFaults removes all injected faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = make(map[string]*Fault)
+}
+
+// RequestCount returns how many requests were made to path
+func (s *Server) RequestCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requestCounts[path]
+}
+
+// ChatRequests returns the chat requests received so far
+func (s *Server) ChatRequests() []ollama.ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make([]ollama.ChatRequest, len(s.chatRequests))
+	copy(requests, s.chatRequests)
+	return requests
+}
+
+// intercept counts requests, applies latency and serves non-streaming faults
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requestCounts[r.URL.Path]++
+		latency := s.latency
+		s.mu.Unlock()
+
+		if latency > 0 && !sleep(r, latency) {
+			return
+		}
+
+		// Faults on streaming chat requests that fire mid-stream are
+		// handled by handleChat itself
+		if fault := s.peekFault(r.URL.Path); fault != nil && fault.AfterChunks == 0 {
+			s.consumeFault(r.URL.Path)
+			s.serveFault(w, r, *fault)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// peekFault returns a copy of the active fault for path, if any
+func (s *Server) peekFault(path string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fault, ok := s.faults[path]
+	if !ok {
+		return nil
+	}
+	f := *fault
+	return &f
+}
+
+// consumeFault counts one use of the fault on path, removing it once its
+// Times budget is spent
+func (s *Server) consumeFault(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fault, ok := s.faults[path]
+	if !ok || fault.Times == 0 {
+		return
+	}
+	fault.Times--
+	if fault.Times == 0 {
+		delete(s.faults, path)
+	}
+}
+
+// serveFault writes the failure described by fault
+func (s *Server) serveFault(w http.ResponseWriter, r *http.Request, fault Fault) {
+	switch fault.Mode {
+	case FaultHang:
+		// The request context is only cancelled on disconnect once the body
+		// has been read, so also watch for the server shutting down
+		select {
+		case <-r.Context().Done():
+		case <-s.closed:
+		}
+	case FaultDisconnect:
+		hijackAndClose(w)
+	default:
+		status := fault.StatusCode
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		message := fault.Message
+		if message == "" {
+			message = "injected fault"
+		}
+		writeError(w, status, message)
+	}
+}
+
+// hijackAndClose drops the underlying connection without a complete response
+func hijackAndClose(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic("ollamatest: response writer does not support hijacking")
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	_ = conn.Close()
+}
+
+// sleep waits for d, returning false if the client went away first
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an Ollama-style error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// decode reads a JSON request body, writing a 400 response on failure
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+// reply computes the assistant response for a chat request
+func (s *Server) reply(req ollama.ChatRequest) string {
+	s.mu.Lock()
+	responder := s.responder
+	s.mu.Unlock()
+
+	if responder != nil {
+		return responder(req)
+	}
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			return req.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// handleChat serves /api/chat, streaming one chunk per word when requested
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	// Ollama streams unless "stream" is explicitly false
+	raw := struct {
+		ollama.ChatRequest
+		Stream *bool `json:"stream"`
+	}{}
+	if !decode(w, r, &raw) {
+		return
+	}
+	req := raw.ChatRequest
+	req.Stream = raw.Stream == nil || *raw.Stream
+
+	s.mu.Lock()
+	s.chatRequests = append(s.chatRequests, req)
+	chunkDelay := s.chunkDelay
+	s.mu.Unlock()
+
+	start := time.Now()
+	loadDuration, ok := s.load(r, req.Model, req.KeepAlive)
+	if !ok {
+		return
+	}
+
+	// A request without messages only loads or unloads the model
+	if len(req.Messages) == 0 {
+		doneReason := "load"
+		if req.KeepAlive == ollama.KeepAliveUnload {
+			doneReason = "unload"
+		}
+		writeJSON(w, ollama.ChatResponse{
+			Model:      req.Model,
+			CreatedAt:  time.Now(),
+			Message:    ollama.Message{Role: "assistant"},
+			Done:       true,
+			DoneReason: doneReason,
+			Metrics: ollama.Metrics{
+				TotalDuration: time.Since(start),
+				LoadDuration:  loadDuration,
+			},
+		})
+		return
+	}
+
+	content := s.reply(req)
+	chunks := splitChunks(content)
+	metrics := func() ollama.Metrics {
+		return ollama.Metrics{
+			TotalDuration:   time.Since(start),
+			LoadDuration:    loadDuration,
+			PromptEvalCount: len(req.Messages),
+			EvalCount:       len(chunks),
+			EvalDuration:    time.Since(start) - loadDuration,
+		}
+	}
+
+	if !req.Stream {
+		writeJSON(w, ollama.ChatResponse{
+			Model:      req.Model,
+			CreatedAt:  time.Now(),
+			Message:    ollama.Message{Role: "assistant", Content: content},
+			Done:       true,
+			DoneReason: "stop",
+			Metrics:    metrics(),
+		})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	encoder := json.NewEncoder(w)
+	fault := s.peekFault(PathChat)
+	for i, chunk := range chunks {
+		if fault != nil && i == fault.AfterChunks {
+			s.consumeFault(PathChat)
+			s.serveFault(w, r, *fault)
+			return
+		}
+		if i > 0 && !sleep(r, chunkDelay) {
+			return
+		}
+		_ = encoder.Encode(ollama.StreamResponse{
+			Model:     req.Model,
+			CreatedAt: time.Now(),
+			Message:   ollama.Message{Role: "assistant", Content: chunk},
+		})
+		flusher.Flush()
+	}
+
+	_ = encoder.Encode(ollama.StreamResponse{
+		Model:      req.Model,
+		CreatedAt:  time.Now(),
+		Message:    ollama.Message{Role: "assistant"},
+		Done:       true,
+		DoneReason: "stop",
+		Metrics:    metrics(),
+	})
+	flusher.Flush()
+}
+
+// splitChunks splits content into word-sized stream chunks, keeping the
+// separating whitespace so the chunks concatenate back to content
+func splitChunks(content string) []string {
+	var chunks []string
+	start := 0
+	for i := 1; i < len(content); i++ {
+		if content[i] == ' ' && content[i-1] != ' ' {
+			chunks = append(chunks, content[start:i])
+			start = i
+		}
+	}
+	if start < len(content) {
+		chunks = append(chunks, content[start:])
+	}
+	return chunks
+}
+
+// handleEmbed serves /api/embed for a single string or a list of strings
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model     string          `json:"model"`
+		Input     json.RawMessage `json:"input"`
+		KeepAlive string          `json:"keep_alive"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	var inputs []string
+	var single string
+	if err := json.Unmarshal(req.Input, &single); err == nil {
+		inputs = []string{single}
+	} else if err := json.Unmarshal(req.Input, &inputs); err != nil {
+		writeError(w, http.StatusBadRequest, "input must be a string or a list of strings")
+		return
+	}
+
+	start := time.Now()
+	loadDuration, ok := s.load(r, req.Model, req.KeepAlive)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	dim := s.embeddingDim
+	s.mu.Unlock()
+
+	resp := ollama.EmbedResponse{
+		Model:        req.Model,
+		Embeddings:   make([][]float32, len(inputs)),
+		LoadDuration: loadDuration,
+	}
+	for i, input := range inputs {
+		resp.Embeddings[i] = Embedding(input, dim)
+	}
+	resp.TotalDuration = time.Since(start)
+	writeJSON(w, resp)
+}
+
+// handleTags serves /api/tags with the installed models sorted by name
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	models := make([]ollama.ModelInfo, 0, len(s.models))
+	for _, m := range s.models {
+		models = append(models, ollama.ModelInfo{
+			Name:       m.name,
+			ModifiedAt: m.modifiedAt,
+			Digest:     digest(m.name),
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Name < models[j].Name
+	})
+	writeJSON(w, ollama.ListModelsResponse{Models: models})
+}
+
+// handlePull serves /api/pull, streaming progress and installing the model
+func (s *Server) handlePull(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+		Name  string `json:"name"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	name := req.Model
+	if name == "" {
+		name = req.Name
+	}
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+
+	s.mu.Lock()
+	chunkDelay := s.chunkDelay
+	s.mu.Unlock()
+
+	const total = 1000
+	steps := []ollama.PullProgress{
+		{Status: "pulling manifest"},
+		{Status: "pulling " + digest(name), Digest: digest(name), Total: total, Completed: total / 2},
+		{Status: "pulling " + digest(name), Digest: digest(name), Total: total, Completed: total},
+		{Status: "verifying sha256 digest"},
+		{Status: "writing manifest"},
+		{Status: "success"},
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	for i, step := range steps {
+		if i > 0 && !sleep(r, chunkDelay) {
+			return
+		}
+		if step.Status == "success" {
+			s.AddModel(name, DefaultContextLength)
+		}
+		_ = encoder.Encode(step)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// handleShow serves /api/show for installed models
+func (s *Server) handleShow(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	model, ok := s.models[req.Model]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", req.Model))
+		return
+	}
+
+	family := strings.SplitN(model.name, ":", 2)[0]
+	writeJSON(w, ollama.ShowModelResponse{
+		Details: ollama.ModelDetails{
+			Format: "gguf",
+			Family: family,
+		},
+		ModelInfo: map[string]interface{}{
+			"general.architecture":     family,
+			family + ".context_length": model.contextLength,
+		},
+		ModifiedAt: model.modifiedAt,
+	})
+}
+
+// handleDelete serves /api/delete
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.models[req.Model]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", req.Model))
+		return
+	}
+	delete(s.models, req.Model)
+	delete(s.loaded, req.Model)
+}
+
+// handleCopy serves /api/copy
+func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	source, ok := s.models[req.Source]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", req.Source))
+		return
+	}
+	s.models[req.Destination] = &fakeModel{
+		name:          req.Destination,
+		contextLength: source.contextLength,
+		modifiedAt:    time.Now(),
+	}
+}
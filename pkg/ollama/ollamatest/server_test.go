@@ -0,0 +1,264 @@
+package ollamatest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/ollama"
+)
+
+func userRequest(content string, stream bool) ollama.ChatRequest {
+	return ollama.ChatRequest{
+		Model:    "llama3:8b",
+		Messages: []ollama.Message{{Role: "user", Content: content}},
+		Stream:   stream,
+	}
+}
+
+func collect(respChan <-chan ollama.StreamResponse, errChan <-chan error) (string, int, error) {
+	var sb strings.Builder
+	chunks := 0
+	for resp := range respChan {
+		if resp.Message.Content != "" {
+			chunks++
+			sb.WriteString(resp.Message.Content)
+		}
+	}
+	return sb.String(), chunks, <-errChan
+}
+
+func TestChatEchoAndCanned(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := ollama.NewClient(server.URL)
+
+	resp, err := client.Chat(context.Background(), userRequest("hello there", false))
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Message.Content != "hello there" {
+		t.Errorf("echo Content = %q", resp.Message.Content)
+	}
+
+	server.SetResponse("Go is a programming language")
+	content, chunks, err := collect(client.ChatStream(context.Background(), userRequest("what is go?", true)))
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if content != "Go is a programming language" {
+		t.Errorf("streamed content = %q", content)
+	}
+	if chunks != 5 {
+		t.Errorf("chunks = %d, want 5", chunks)
+	}
+
+	if got := server.RequestCount(PathChat); got != 2 {
+		t.Errorf("RequestCount = %d, want 2", got)
+	}
+	if requests := server.ChatRequests(); len(requests) != 2 || !requests[1].Stream {
+		t.Errorf("unexpected recorded requests: %+v", requests)
+	}
+}
+
+func TestEmbedDeterministic(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetEmbeddingDimension(16)
+	client := ollama.NewClient(server.URL)
+
+	resp, err := client.Embed(context.Background(), ollama.EmbedRequest{
+		Model: "nomic-embed-text",
+		Input: []string{"alpha", "beta", "alpha"},
+	})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(resp.Embeddings) != 3 || len(resp.Embeddings[0]) != 16 {
+		t.Fatalf("unexpected embeddings shape")
+	}
+	if !reflect.DeepEqual(resp.Embeddings[0], resp.Embeddings[2]) {
+		t.Error("same input produced different embeddings")
+	}
+	if reflect.DeepEqual(resp.Embeddings[0], resp.Embeddings[1]) {
+		t.Error("different inputs produced the same embedding")
+	}
+	if !reflect.DeepEqual(resp.Embeddings[1], Embedding("beta", 16)) {
+		t.Error("server embedding does not match Embedding()")
+	}
+}
+
+func TestModelLifecycle(t *testing.T) {
+	server := NewServer("llama3:8b")
+	defer server.Close()
+	client := ollama.NewClient(server.URL)
+	ctx := context.Background()
+
+	var statuses []string
+	if err := client.PullModelWithProgress(ctx, "mistral:7b", func(p ollama.PullProgress) {
+		statuses = append(statuses, p.Status)
+	}); err != nil {
+		t.Fatalf("PullModelWithProgress() error = %v", err)
+	}
+	if len(statuses) == 0 || statuses[len(statuses)-1] != "success" {
+		t.Errorf("unexpected pull statuses: %v", statuses)
+	}
+
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0].Name != "llama3:8b" || models[1].Name != "mistral:7b" {
+		t.Errorf("unexpected models: %+v", models)
+	}
+
+	details, err := client.ShowModel(ctx, "llama3:8b")
+	if err != nil {
+		t.Fatalf("ShowModel() error = %v", err)
+	}
+	if details.ContextLength() != DefaultContextLength {
+		t.Errorf("ContextLength() = %d", details.ContextLength())
+	}
+
+	var statusErr *ollama.StatusError
+	if _, err := client.ShowModel(ctx, "missing"); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown model, got %v", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	t.Run("status error", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		server.InjectFault(PathEmbed, Fault{StatusCode: http.StatusServiceUnavailable, Times: 1})
+		client := ollama.NewClient(server.URL)
+
+		_, err := client.Embed(context.Background(), ollama.EmbedRequest{Model: "m", Input: "x"})
+		var statusErr *ollama.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503, got %v", err)
+		}
+		if _, err := client.Embed(context.Background(), ollama.EmbedRequest{Model: "m", Input: "x"}); err != nil {
+			t.Errorf("fault should be spent after one request, got %v", err)
+		}
+	})
+
+	t.Run("disconnect mid-stream", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		server.SetResponse("one two three")
+		server.InjectFault(PathChat, Fault{Mode: FaultDisconnect, AfterChunks: 2})
+		client := ollama.NewClient(server.URL)
+
+		_, chunks, err := collect(client.ChatStream(context.Background(), userRequest("hi", true)))
+		if err == nil {
+			t.Fatal("expected error after disconnect")
+		}
+		if chunks != 2 {
+			t.Errorf("chunks = %d, want 2", chunks)
+		}
+	})
+
+	t.Run("hang triggers first token timeout", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		server.InjectFault(PathChat, Fault{Mode: FaultHang})
+		client := ollama.NewClient(server.URL, ollama.Timeouts{FirstToken: 50 * time.Millisecond})
+
+		_, err := client.Chat(context.Background(), userRequest("hi", false))
+		if !ollama.IsTimeout(err) {
+			t.Errorf("expected timeout, got %v", err)
+		}
+	})
+}
+
+func TestLatency(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetLatency(100 * time.Millisecond)
+	client := ollama.NewClient(server.URL)
+
+	start := time.Now()
+	if _, err := client.Chat(context.Background(), userRequest("hi", false)); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("response arrived after %v, want at least 100ms", elapsed)
+	}
+}
+
+func TestPreloadAndKeepAlive(t *testing.T) {
+	server := NewServer("llama3:8b", "nomic-embed-text")
+	defer server.Close()
+	server.SetLoadDelay(50 * time.Millisecond)
+	client := ollama.NewClient(server.URL)
+	ctx := context.Background()
+
+	loadDuration, err := client.Preload(ctx, ollama.PreloadRequest{Model: "llama3:8b", KeepAlive: ollama.KeepAliveForever})
+	if err != nil {
+		t.Fatalf("Preload() error = %v", err)
+	}
+	if loadDuration != 50*time.Millisecond {
+		t.Errorf("cold load reported %v, want 50ms", loadDuration)
+	}
+
+	// A resident model reports no load time. Every request resets the
+	// keep_alive, so it has to be repeated to keep the model pinned.
+	req := userRequest("hi", false)
+	req.KeepAlive = ollama.KeepAliveForever
+	resp, err := client.Chat(ctx, req)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.LoadDuration != 0 {
+		t.Errorf("warm chat reported load time %v", resp.LoadDuration)
+	}
+	if resp.EvalCount != 1 {
+		t.Errorf("EvalCount = %d, want 1", resp.EvalCount)
+	}
+
+	if _, err := client.Preload(ctx, ollama.PreloadRequest{Model: "nomic-embed-text", Embedding: true, KeepAlive: "10m"}); err != nil {
+		t.Fatalf("Preload(embedding) error = %v", err)
+	}
+	running, err := client.ListRunning(ctx)
+	if err != nil {
+		t.Fatalf("ListRunning() error = %v", err)
+	}
+	if len(running) != 2 || !running[0].ExpiresAt.IsZero() || running[1].ExpiresAt.IsZero() {
+		t.Errorf("unexpected running models: %+v", running)
+	}
+
+	if err := client.Unload(ctx, "llama3:8b"); err != nil {
+		t.Fatalf("Unload() error = %v", err)
+	}
+	if loaded := server.LoadedModels(); len(loaded) != 1 || loaded[0] != "nomic-embed-text" {
+		t.Errorf("LoadedModels() = %v", loaded)
+	}
+	if got := server.KeepAlives("llama3:8b"); !reflect.DeepEqual(got, []string{"-1", "-1", "0"}) {
+		t.Errorf("KeepAlives() = %v", got)
+	}
+}
+
+func TestPoolPreload(t *testing.T) {
+	first := NewServer()
+	defer first.Close()
+	second := NewServer()
+	defer second.Close()
+	second.InjectFault(PathChat, Fault{StatusCode: http.StatusNotFound, Message: "model not found"})
+
+	pool, _ := ollama.NewPool([]string{first.URL, second.URL})
+	results := pool.Preload(context.Background(), ollama.PreloadRequest{Model: "llama3:8b"})
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Node != first.URL || results[0].Error != "" {
+		t.Errorf("unexpected result for healthy node: %+v", results[0])
+	}
+	if results[1].Node != second.URL || !strings.Contains(results[1].Error, "model not found") {
+		t.Errorf("unexpected result for failing node: %+v", results[1])
+	}
+}
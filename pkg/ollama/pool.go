@@ -0,0 +1,320 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// node is a single Ollama endpoint in a pool
+type node struct {
+	url         string
+	client      *Client
+	healthy     bool
+	inflight    int
+	lastError   string
+	lastChecked time.Time
+	failures    int
+}
+
+// NodeStatus reports the state of a pool endpoint
+type NodeStatus struct {
+	URL         string    `json:"url"`
+	Healthy     bool      `json:"healthy"`
+	Inflight    int       `json:"inflight"`
+	Failures    int       `json:"consecutive_failures"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastChecked time.Time `json:"last_checked,omitempty"`
+}
+
+// Pool load-balances chat and embedding requests across several Ollama
+// endpoints. Requests go to the healthy node with the fewest outstanding
+// requests and fail over to the next node when a node cannot be reached,
+// as long as no response data has been returned to the caller yet.
+type Pool struct {
+	nodes []*node
+	mu    sync.Mutex
+}
+
+// NewPool creates a pool over the given Ollama base URLs. All nodes start
+// out healthy and share the same timeouts.
+func NewPool(urls []string, timeouts ...Timeouts) (*Pool, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("at least one Ollama URL is required")
+	}
+
+	pool := &Pool{
+		nodes: make([]*node, 0, len(urls)),
+	}
+	for _, url := range urls {
+		pool.nodes = append(pool.nodes, &node{
+			url:     url,
+			client:  NewClient(url, timeouts...),
+			healthy: true,
+		})
+	}
+
+	return pool, nil
+}
+
+// Client returns the client for the node with the given URL, or the first
+// node if url is empty. It returns nil if no node matches.
+func (p *Pool) Client(url string) *Client {
+	if url == "" {
+		return p.nodes[0].client
+	}
+	for _, n := range p.nodes {
+		if n.url == url {
+			return n.client
+		}
+	}
+	return nil
+}
+
+// Status returns the current state of every node in the pool
+func (p *Pool) Status() []NodeStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]NodeStatus, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		statuses = append(statuses, NodeStatus{
+			URL:         n.url,
+			Healthy:     n.healthy,
+			Inflight:    n.inflight,
+			Failures:    n.failures,
+			LastError:   n.lastError,
+			LastChecked: n.lastChecked,
+		})
+	}
+	return statuses
+}
+
+// Healthy reports whether at least one node is healthy
+func (p *Pool) Healthy() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, n := range p.nodes {
+		if n.healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHealth pings every node once and updates its health
+func (p *Pool) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, n := range p.nodes {
+		wg.Add(1)
+		go func(n *node) {
+			defer wg.Done()
+			err := n.client.Ping(ctx)
+			p.mu.Lock()
+			n.lastChecked = time.Now()
+			p.mu.Unlock()
+			if err != nil {
+				p.markFailed(n, err)
+			} else {
+				p.markHealthy(n)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+// StartHealthChecks probes all nodes every interval until ctx is cancelled
+func (p *Pool) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		probeCtx, cancel := context.WithTimeout(ctx, interval)
+		p.CheckHealth(probeCtx)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Chat sends a chat completion request to the least loaded healthy node
+func (p *Pool) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	var lastErr error
+	for _, n := range p.candidates() {
+		p.acquire(n)
+		resp, err := n.client.Chat(ctx, req)
+		p.release(n)
+
+		if err == nil {
+			p.markHealthy(n)
+			return resp, nil
+		}
+		if !p.shouldFailover(ctx, err) {
+			return nil, err
+		}
+		p.markFailed(n, err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("all Ollama nodes failed: %w", lastErr)
+}
+
+// ChatStream sends a streaming chat request to the least loaded healthy node.
+// A node that fails before producing its first chunk is skipped; once
+// chunks have been delivered, errors are passed through unchanged.
+func (p *Pool) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamResponse, <-chan error) {
+	respChan := make(chan StreamResponse)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(respChan)
+		defer close(errChan)
+
+		var lastErr error
+		for _, n := range p.candidates() {
+			p.acquire(n)
+			started, err := p.forwardStream(ctx, n, req, respChan)
+			p.release(n)
+
+			if err == nil {
+				p.markHealthy(n)
+				return
+			}
+			failover := p.shouldFailover(ctx, err)
+			if failover {
+				p.markFailed(n, err)
+			}
+			if started || !failover {
+				errChan <- err
+				return
+			}
+			lastErr = err
+		}
+		errChan <- fmt.Errorf("all Ollama nodes failed: %w", lastErr)
+	}()
+
+	return respChan, errChan
+}
+
+// forwardStream relays one node's stream to out, reporting whether any
+// chunk was delivered before the stream ended
+func (p *Pool) forwardStream(ctx context.Context, n *node, req ChatRequest, out chan<- StreamResponse) (bool, error) {
+	nodeResp, nodeErr := n.client.ChatStream(ctx, req)
+
+	started := false
+	for chunk := range nodeResp {
+		started = true
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+			// Drain so the node's goroutine can exit
+			for range nodeResp {
+			}
+			return started, ctx.Err()
+		}
+	}
+
+	return started, <-nodeErr
+}
+
+// Embed generates embeddings on the least loaded healthy node
+func (p *Pool) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	var lastErr error
+	for _, n := range p.candidates() {
+		p.acquire(n)
+		resp, err := n.client.Embed(ctx, req)
+		p.release(n)
+
+		if err == nil {
+			p.markHealthy(n)
+			return resp, nil
+		}
+		if !p.shouldFailover(ctx, err) {
+			return nil, err
+		}
+		p.markFailed(n, err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("all Ollama nodes failed: %w", lastErr)
+}
+
+// candidates orders nodes for a request: healthy nodes by outstanding
+// requests, followed by unhealthy ones as a last resort
+func (p *Pool) candidates() []*node {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ordered := make([]*node, len(p.nodes))
+	copy(ordered, p.nodes)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].healthy != ordered[j].healthy {
+			return ordered[i].healthy
+		}
+		return ordered[i].inflight < ordered[j].inflight
+	})
+	return ordered
+}
+
+// shouldFailover reports whether err means the node is unusable, as opposed
+// to the caller giving up or Ollama rejecting the request itself
+func (p *Pool) shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+// acquire records an outstanding request on a node
+func (p *Pool) acquire(n *node) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n.inflight++
+}
+
+// release records the completion of a request on a node
+func (p *Pool) release(n *node) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n.inflight--
+}
+
+// markHealthy records a successful request or probe
+func (p *Pool) markHealthy(n *node) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !n.healthy {
+		log.Printf("Ollama node %s is healthy again", n.url)
+	}
+	n.healthy = true
+	n.failures = 0
+	n.lastError = ""
+}
+
+// markFailed takes a node out of rotation until it passes a health probe
+func (p *Pool) markFailed(n *node, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if n.healthy {
+		log.Printf("Ollama node %s marked unhealthy: %v", n.url, err)
+	}
+	n.healthy = false
+	n.failures++
+	n.lastError = err.Error()
+}
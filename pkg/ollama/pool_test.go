@@ -0,0 +1,186 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCountingServer returns a server that counts requests and replies with a fixed body
+func newCountingServer(status int, body string, count *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(count, 1)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+// deadURL returns the URL of a server that is no longer listening
+func deadURL() string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestNewPoolRequiresURLs(t *testing.T) {
+	if _, err := NewPool(nil); err == nil {
+		t.Error("expected error for empty URL list")
+	}
+}
+
+func TestPoolChatFailover(t *testing.T) {
+	var calls int32
+	healthy := newCountingServer(http.StatusOK, `{"message":{"role":"assistant","content":"hi"},"done":true}`, &calls)
+	defer healthy.Close()
+
+	dead := deadURL()
+	pool, _ := NewPool([]string{dead, healthy.URL})
+
+	resp, err := pool.Chat(context.Background(), ChatRequest{Model: "test"})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Message.Content != "hi" {
+		t.Errorf("Content = %q, want hi", resp.Message.Content)
+	}
+
+	status := pool.Status()
+	if status[0].Healthy {
+		t.Error("expected dead node to be marked unhealthy")
+	}
+	if !status[1].Healthy {
+		t.Error("expected live node to stay healthy")
+	}
+
+	// The unhealthy node is now tried last
+	if _, err := pool.Chat(context.Background(), ChatRequest{Model: "test"}); err != nil {
+		t.Fatalf("second Chat() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("healthy node received %d calls, want 2", calls)
+	}
+}
+
+func TestPoolClientErrorDoesNotFailover(t *testing.T) {
+	var first, second int32
+	badRequest := newCountingServer(http.StatusNotFound, `{"error":"model not found"}`, &first)
+	defer badRequest.Close()
+	other := newCountingServer(http.StatusOK, `{"done":true}`, &second)
+	defer other.Close()
+
+	pool, _ := NewPool([]string{badRequest.URL, other.URL})
+
+	_, err := pool.Chat(context.Background(), ChatRequest{Model: "missing"})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 StatusError, got %v", err)
+	}
+	if second != 0 {
+		t.Error("client errors should not fail over to other nodes")
+	}
+	if !pool.Status()[0].Healthy {
+		t.Error("client errors should not mark the node unhealthy")
+	}
+}
+
+func TestPoolLeastOutstandingRouting(t *testing.T) {
+	release := make(chan struct{})
+	var slowCalls, fastCalls int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&slowCalls, 1)
+		<-release
+		_, _ = w.Write([]byte(`{"embeddings":[[1]]}`))
+	}))
+	defer slow.Close()
+	fast := newCountingServer(http.StatusOK, `{"embeddings":[[2]]}`, &fastCalls)
+	defer fast.Close()
+
+	pool, _ := NewPool([]string{slow.URL, fast.URL})
+
+	// Occupy the first node
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = pool.Embed(context.Background(), EmbedRequest{Model: "test", Input: "a"})
+	}()
+	for atomic.LoadInt32(&slowCalls) == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := pool.Embed(context.Background(), EmbedRequest{Model: "test", Input: "b"})
+		if err != nil {
+			t.Fatalf("Embed() error = %v", err)
+		}
+		if resp.Embeddings[0][0] != 2 {
+			t.Errorf("request %d was routed to the busy node", i)
+		}
+	}
+
+	close(release)
+	<-done
+
+	if fastCalls != 3 {
+		t.Errorf("idle node received %d calls, want 3", fastCalls)
+	}
+}
+
+func TestPoolChatStreamFailover(t *testing.T) {
+	t.Run("fails over before first chunk", func(t *testing.T) {
+		live := newChunkServer(0, 2, 0, false)
+		defer live.Close()
+
+		pool, _ := NewPool([]string{deadURL(), live.URL})
+		chunks, err := drainStream(pool.ChatStream(context.Background(), ChatRequest{Model: "test"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if chunks != 2 {
+			t.Errorf("received %d chunks, want 2", chunks)
+		}
+	})
+
+	t.Run("does not fail over after stream started", func(t *testing.T) {
+		stalling := newChunkServer(0, 1, 0, true)
+		defer stalling.Close()
+		var otherCalls int32
+		other := newCountingServer(http.StatusOK, `{"done":true}`, &otherCalls)
+		defer other.Close()
+
+		pool, _ := NewPool([]string{stalling.URL, other.URL}, Timeouts{FirstToken: time.Second, Idle: 50 * time.Millisecond})
+		chunks, err := drainStream(pool.ChatStream(context.Background(), ChatRequest{Model: "test"}))
+		if !IsTimeout(err) {
+			t.Fatalf("expected idle timeout, got %v", err)
+		}
+		if chunks != 1 {
+			t.Errorf("received %d chunks, want 1", chunks)
+		}
+		if otherCalls != 0 {
+			t.Error("stream should not be retried once chunks were delivered")
+		}
+	})
+}
+
+func TestPoolCheckHealth(t *testing.T) {
+	var calls int32
+	live := newCountingServer(http.StatusOK, `{"models":[]}`, &calls)
+	defer live.Close()
+
+	pool, _ := NewPool([]string{live.URL, deadURL()})
+	pool.CheckHealth(context.Background())
+
+	status := pool.Status()
+	if !status[0].Healthy || status[1].Healthy {
+		t.Errorf("unexpected health after probe: %+v", status)
+	}
+	if status[1].LastError == "" || status[1].LastChecked.IsZero() {
+		t.Error("expected failed probe to record error and check time")
+	}
+	if !pool.Healthy() {
+		t.Error("pool with one live node should be healthy")
+	}
+}
@@ -0,0 +1,189 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// TimeoutStage identifies which part of a request timed out
+type TimeoutStage string
+
+const (
+	// TimeoutStageConnect means the TCP connection could not be established in time
+	TimeoutStageConnect TimeoutStage = "connect"
+	// TimeoutStageFirstToken means no response data arrived after the request was sent
+	TimeoutStageFirstToken TimeoutStage = "first_token"
+	// TimeoutStageIdle means the stream stalled between two chunks
+	TimeoutStageIdle TimeoutStage = "idle"
+)
+
+// Timeouts controls how long the client waits at each stage of a request.
+// Unlike http.Client.Timeout, none of these bound the total duration of a
+// stream, so long generations on slow hosts are not cut off while tokens
+// keep arriving. A zero value disables that stage's timeout.
+type Timeouts struct {
+	// Connect bounds establishing the TCP connection to Ollama
+	Connect time.Duration
+	// FirstToken bounds the wait for the first response chunk, which covers
+	// model loading and prompt evaluation. For non-streaming requests it
+	// bounds the wait for the complete response.
+	FirstToken time.Duration
+	// Idle bounds the gap between two consecutive stream chunks
+	Idle time.Duration
+}
+
+// DefaultTimeouts returns default timeouts suited to CPU-only hosts
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Connect:    10 * time.Second,
+		FirstToken: 2 * time.Minute,
+		Idle:       30 * time.Second,
+	}
+}
+
+// merge returns t with any non-zero fields of override applied
+func (t Timeouts) merge(override *Timeouts) Timeouts {
+	if override == nil {
+		return t
+	}
+	if override.Connect > 0 {
+		t.Connect = override.Connect
+	}
+	if override.FirstToken > 0 {
+		t.FirstToken = override.FirstToken
+	}
+	if override.Idle > 0 {
+		t.Idle = override.Idle
+	}
+	return t
+}
+
+// TimeoutError is returned when a request exceeds one of its stage timeouts
+type TimeoutError struct {
+	Stage TimeoutStage
+	After time.Duration
+}
+
+// Error implements the error interface
+func (e *TimeoutError) Error() string {
+	switch e.Stage {
+	case TimeoutStageConnect:
+		return fmt.Sprintf("ollama: connect timed out after %s", e.After)
+	case TimeoutStageFirstToken:
+		return fmt.Sprintf("ollama: no response within %s", e.After)
+	default:
+		return fmt.Sprintf("ollama: stream idle for %s", e.After)
+	}
+}
+
+// Timeout reports that the error is a timeout, matching net.Error
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// IsTimeout reports whether err is caused by an Ollama stage timeout
+func IsTimeout(err error) bool {
+	var timeoutErr *TimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// timeoutsKey is the context key for per-request timeouts
+type timeoutsKey struct{}
+
+// withTimeouts attaches the effective timeouts of a request to its context
+func withTimeouts(ctx context.Context, t Timeouts) context.Context {
+	return context.WithValue(ctx, timeoutsKey{}, t)
+}
+
+// dialContext dials Ollama using the connect timeout of the request in ctx
+func (c *Client) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	t, ok := ctx.Value(timeoutsKey{}).(Timeouts)
+	if !ok {
+		t = c.timeouts
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   t.Connect,
+		KeepAlive: 30 * time.Second,
+	}
+
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+			return nil, &TimeoutError{Stage: TimeoutStageConnect, After: t.Connect}
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// watchdog cancels a request when no data arrives within the current stage timeout
+type watchdog struct {
+	cancel context.CancelFunc
+	timer  *time.Timer
+	armed  int // incremented on every reset/stop so stale timers are ignored
+	err    *TimeoutError
+	mu     sync.Mutex
+}
+
+// newWatchdog starts a watchdog for the given stage. A zero timeout leaves it disarmed.
+func newWatchdog(cancel context.CancelFunc, stage TimeoutStage, timeout time.Duration) *watchdog {
+	w := &watchdog{cancel: cancel}
+	w.reset(stage, timeout)
+	return w
+}
+
+// reset re-arms the watchdog for the given stage, replacing any pending deadline
+func (w *watchdog) reset(stage TimeoutStage, timeout time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.disarm()
+	if timeout <= 0 || w.err != nil {
+		return
+	}
+
+	armed := w.armed
+	w.timer = time.AfterFunc(timeout, func() {
+		w.mu.Lock()
+		if w.armed != armed || w.err != nil {
+			w.mu.Unlock()
+			return
+		}
+		w.err = &TimeoutError{Stage: stage, After: timeout}
+		w.mu.Unlock()
+		w.cancel()
+	})
+}
+
+// stop disarms the watchdog
+func (w *watchdog) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.disarm()
+}
+
+// disarm stops any pending timer. The caller must hold w.mu.
+func (w *watchdog) disarm() {
+	w.armed++
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+}
+
+// check returns the timeout error if the watchdog fired, otherwise err unchanged
+func (w *watchdog) check(err error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	return err
+}
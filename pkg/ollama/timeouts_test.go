@@ -0,0 +1,160 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newChunkServer returns a server that waits initialDelay, then streams
+// chunks chat chunks separated by gap, optionally stalling afterwards
+func newChunkServer(initialDelay time.Duration, chunks int, gap time.Duration, stall bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		wait := func(d time.Duration) bool {
+			select {
+			case <-time.After(d):
+				return true
+			case <-r.Context().Done():
+				return false
+			}
+		}
+
+		if !wait(initialDelay) {
+			return
+		}
+		for i := 0; i < chunks; i++ {
+			if i > 0 && !wait(gap) {
+				return
+			}
+			fmt.Fprintf(w, `{"message":{"role":"assistant","content":"t%d "},"done":false}`+"\n", i)
+			flusher.Flush()
+		}
+		if stall {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+}
+
+// drainStream collects a ChatStream until it finishes
+func drainStream(respChan <-chan StreamResponse, errChan <-chan error) (int, error) {
+	chunks := 0
+	for resp := range respChan {
+		if resp.Message.Content != "" {
+			chunks++
+		}
+	}
+	return chunks, <-errChan
+}
+
+func TestChatStreamTimeouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeouts   Timeouts
+		override   *Timeouts
+		delay      time.Duration
+		chunks     int
+		gap        time.Duration
+		stall      bool
+		wantStage  TimeoutStage
+		wantChunks int
+	}{
+		{
+			name:       "long stream within idle timeout",
+			timeouts:   Timeouts{FirstToken: 200 * time.Millisecond, Idle: 200 * time.Millisecond},
+			chunks:     6,
+			gap:        80 * time.Millisecond,
+			wantChunks: 6,
+		},
+		{
+			name:      "first token timeout",
+			timeouts:  Timeouts{FirstToken: 50 * time.Millisecond, Idle: time.Second},
+			delay:     time.Second,
+			chunks:    1,
+			wantStage: TimeoutStageFirstToken,
+		},
+		{
+			name:       "idle timeout after partial stream",
+			timeouts:   Timeouts{FirstToken: time.Second, Idle: 50 * time.Millisecond},
+			chunks:     2,
+			stall:      true,
+			wantStage:  TimeoutStageIdle,
+			wantChunks: 2,
+		},
+		{
+			name:       "per-request override",
+			timeouts:   Timeouts{FirstToken: time.Second, Idle: 20 * time.Millisecond},
+			override:   &Timeouts{Idle: 500 * time.Millisecond},
+			chunks:     3,
+			gap:        100 * time.Millisecond,
+			wantChunks: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChunkServer(tt.delay, tt.chunks, tt.gap, tt.stall)
+			defer server.Close()
+
+			client := NewClient(server.URL, tt.timeouts)
+			respChan, errChan := client.ChatStream(context.Background(), ChatRequest{
+				Model:    "test",
+				Timeouts: tt.override,
+			})
+
+			chunks, err := drainStream(respChan, errChan)
+			if chunks != tt.wantChunks {
+				t.Errorf("received %d chunks, want %d", chunks, tt.wantChunks)
+			}
+
+			if tt.wantStage == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var timeoutErr *TimeoutError
+			if !errors.As(err, &timeoutErr) {
+				t.Fatalf("expected *TimeoutError, got %v", err)
+			}
+			if timeoutErr.Stage != tt.wantStage {
+				t.Errorf("Stage = %s, want %s", timeoutErr.Stage, tt.wantStage)
+			}
+		})
+	}
+}
+
+func TestChatFirstTokenTimeout(t *testing.T) {
+	server := newChunkServer(time.Second, 1, 0, false)
+	defer server.Close()
+
+	client := NewClient(server.URL, Timeouts{FirstToken: 50 * time.Millisecond})
+	_, err := client.Chat(context.Background(), ChatRequest{Model: "test"})
+	if !IsTimeout(err) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+}
+
+func TestChatStreamCallerCancellationIsNotTimeout(t *testing.T) {
+	server := newChunkServer(time.Second, 1, 0, false)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client := NewClient(server.URL, Timeouts{FirstToken: 5 * time.Second})
+	_, err := drainStream(client.ChatStream(ctx, ChatRequest{Model: "test"}))
+	if err == nil {
+		t.Fatal("expected error after caller cancellation")
+	}
+	if IsTimeout(err) {
+		t.Errorf("caller cancellation reported as stage timeout: %v", err)
+	}
+}
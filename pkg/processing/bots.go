@@ -0,0 +1,316 @@
+package processing
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// BotEvent is what a bot notification reports, such as a pull request
+// being merged, so bot messages can be filtered, down-ranked or searched
+// by repository
+type BotEvent struct {
+	// App is the integration that posted the message, e.g. "github"
+	App string
+	// Kind is "pull_request", "issue", "push" or "release"
+	Kind string
+	// Repo is the repository as "owner/name"
+	Repo string
+	// Number is the pull request or issue number, if any
+	Number int
+	// Action is what happened, e.g. "opened", "merged" or "published"
+	Action string
+	// Actor is the login of whoever triggered the event
+	Actor string
+	// Title is the pull request or issue title
+	Title string
+	// Ref is the branch pushed to or the release's tag
+	Ref string
+	// URL links to the pull request, issue, comparison or release
+	URL string
+}
+
+// botInterpreters turn bot messages into events, tried in order
+var botInterpreters = []func(msg models.SlackMessage) (BotEvent, bool){
+	interpretGitHubMessage,
+}
+
+// InterpretBotMessage returns the event a bot message reports, if the
+// message comes from a known integration and can be understood
+func InterpretBotMessage(msg models.SlackMessage) (BotEvent, bool) {
+	for _, interpret := range botInterpreters {
+		if event, ok := interpret(msg); ok {
+			return event, true
+		}
+	}
+	return BotEvent{}, false
+}
+
+var botEventKinds = map[string]string{
+	"pull_request": "Pull request",
+	"issue":        "Issue",
+	"push":         "Push",
+	"release":      "Release",
+}
+
+// Summary describes the event in one line, e.g.
+// "[acme/api] Pull request #42 merged by alice: Add retries"
+func (e BotEvent) Summary() string {
+	var sb strings.Builder
+	if e.Repo != "" {
+		sb.WriteString("[" + e.Repo + "] ")
+	}
+	sb.WriteString(botEventKinds[e.Kind])
+	switch {
+	case e.Number > 0:
+		sb.WriteString(" #" + strconv.Itoa(e.Number))
+	case e.Kind == "release" && e.Ref != "":
+		sb.WriteString(" " + e.Ref)
+	case e.Kind == "push" && e.Ref != "":
+		sb.WriteString(" to " + e.Ref)
+	}
+	if e.Action != "" && e.Action != "pushed" {
+		sb.WriteString(" " + e.Action)
+	}
+	if e.Actor != "" {
+		sb.WriteString(" by " + e.Actor)
+	}
+	if e.Title != "" {
+		sb.WriteString(": " + e.Title)
+	}
+	return sb.String()
+}
+
+// Tags returns the tags identifying the event, e.g. "github",
+// "pull-request", "repo:acme/api", "acme/api#42", "action:merged" and
+// "actor:alice"
+func (e BotEvent) Tags() []string {
+	tags := []string{e.App, strings.ReplaceAll(e.Kind, "_", "-")}
+	if e.Repo != "" {
+		tags = append(tags, "repo:"+e.Repo)
+		if e.Number > 0 {
+			tags = append(tags, e.Repo+"#"+strconv.Itoa(e.Number))
+		}
+	}
+	if e.Action != "" {
+		tags = append(tags, "action:"+e.Action)
+	}
+	if e.Actor != "" {
+		tags = append(tags, "actor:"+e.Actor)
+	}
+	return tags
+}
+
+// Metadata returns the event's fields as record metadata
+func (e BotEvent) Metadata() map[string]string {
+	metadata := map[string]string{
+		"bot_app":   e.App,
+		"bot_event": e.Kind,
+	}
+	for key, value := range map[string]string{
+		"repo":      e.Repo,
+		"action":    e.Action,
+		"actor":     e.Actor,
+		"ref":       e.Ref,
+		"event_url": e.URL,
+	} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+	if e.Number > 0 {
+		metadata["number"] = strconv.Itoa(e.Number)
+	}
+	return metadata
+}
+
+var (
+	// slackLink matches Slack's link markup, <url> and <url|label>
+	slackLink = regexp.MustCompile(`<(https?://[^|>]+)(?:\|([^>]*))?>`)
+	// githubURL matches bare GitHub URLs
+	githubURL = regexp.MustCompile(`https?://(?:www\.)?github\.com/[^\s>|)]+`)
+	// repoPrefix matches the "[owner/repo]" legacy notifications start with
+	// and, for pushes, "[owner/repo:branch]"
+	repoPrefix   = regexp.MustCompile(`^\s*\[([\w.-]+/[\w.-]+)(?::([^\]]+))?\]`)
+	issueNumber  = regexp.MustCompile(`#(\d+)\b`)
+	githubActor  = regexp.MustCompile(`(?i)\bby\s+@?([A-Za-z0-9][A-Za-z0-9-]*)`)
+	pushedBranch = regexp.MustCompile("(?i)pushed to\\s+`?([\\w./-]+)`?")
+	// githubActions are the verbs of GitHub notifications, most specific
+	// first, with the action they report
+	githubActions = []struct {
+		pattern *regexp.Regexp
+		action  string
+	}{
+		{regexp.MustCompile(`(?i)\bcomment(ed)?\b`), "commented"},
+		{regexp.MustCompile(`(?i)\breview requested\b`), "review_requested"},
+		{regexp.MustCompile(`(?i)\bapproved\b`), "approved"},
+		{regexp.MustCompile(`(?i)\bmerged\b`), "merged"},
+		{regexp.MustCompile(`(?i)\breopened\b`), "reopened"},
+		{regexp.MustCompile(`(?i)\bclosed\b`), "closed"},
+		{regexp.MustCompile(`(?i)\b(opened|submitted)\b`), "opened"},
+		{regexp.MustCompile(`(?i)\bpublished\b`), "published"},
+		{regexp.MustCompile(`(?i)\bcreated\b`), "created"},
+		{regexp.MustCompile(`(?i)\bdeleted\b`), "deleted"},
+		{regexp.MustCompile(`(?i)\bpushed\b`), "pushed"},
+	}
+)
+
+// githubLink is a link to a GitHub repository page
+type githubLink struct {
+	url    string
+	label  string
+	repo   string
+	kind   string
+	number int
+	ref    string
+}
+
+// parseGitHubLink recognizes links to pull requests, issues, comparisons,
+// commits, releases and branches
+func parseGitHubLink(rawURL, label string) (githubLink, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(strings.TrimPrefix(u.Host, "www."), "github.com") {
+		return githubLink{}, false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return githubLink{}, false
+	}
+
+	link := githubLink{url: rawURL, label: label, repo: parts[0] + "/" + parts[1]}
+	if len(parts) < 4 {
+		return link, true
+	}
+	switch parts[2] {
+	case "pull", "issues":
+		link.kind = "issue"
+		if parts[2] == "pull" {
+			link.kind = "pull_request"
+		}
+		link.number, _ = strconv.Atoi(parts[3])
+	case "compare", "commit", "commits":
+		link.kind = "push"
+	case "releases":
+		if len(parts) >= 5 && parts[3] == "tag" {
+			link.kind = "release"
+			link.ref = strings.Join(parts[4:], "/")
+		}
+	case "tree":
+		link.ref = strings.Join(parts[3:], "/")
+	}
+	return link, true
+}
+
+// isGitHubMessage reports whether a message was posted by the GitHub app
+// or a legacy GitHub integration
+func isGitHubMessage(msg models.SlackMessage) bool {
+	if strings.EqualFold(msg.BotName, "github") {
+		return true
+	}
+	return msg.BotID != "" && strings.Contains(msg.Content, "github.com/")
+}
+
+// interpretGitHubMessage understands the notifications of the GitHub app
+// and of the legacy integration, e.g.
+//
+//	[acme/api] Pull request submitted by alice
+//	<https://github.com/acme/api/pull/42|#42 Add retries>
+func interpretGitHubMessage(msg models.SlackMessage) (BotEvent, bool) {
+	if !isGitHubMessage(msg) || strings.TrimSpace(msg.Content) == "" {
+		return BotEvent{}, false
+	}
+
+	var links []githubLink
+	for _, match := range slackLink.FindAllStringSubmatch(msg.Content, -1) {
+		if link, ok := parseGitHubLink(match[1], match[2]); ok {
+			links = append(links, link)
+		}
+	}
+	for _, rawURL := range githubURL.FindAllString(slackLink.ReplaceAllString(msg.Content, ""), -1) {
+		if link, ok := parseGitHubLink(rawURL, ""); ok {
+			links = append(links, link)
+		}
+	}
+
+	// The prose is the text with links replaced by their labels
+	prose := slackLink.ReplaceAllStringFunc(msg.Content, func(link string) string {
+		match := slackLink.FindStringSubmatch(link)
+		if match[2] != "" {
+			return match[2]
+		}
+		return match[1]
+	})
+	lower := strings.ToLower(prose)
+
+	event := BotEvent{App: "github"}
+	for _, link := range links {
+		if event.Repo == "" {
+			event.Repo = link.repo
+		}
+		if event.Kind == "" && link.kind != "" {
+			event.Kind = link.kind
+			event.Number = link.number
+			event.URL = link.url
+			event.Ref = link.ref
+			if link.number > 0 {
+				event.Title = strings.TrimSpace(issueNumber.ReplaceAllString(link.label, ""))
+			}
+		}
+		if event.Kind == "push" && event.Ref == "" && link.ref != "" {
+			event.Ref = link.ref
+		}
+	}
+	branch := ""
+	if match := repoPrefix.FindStringSubmatch(prose); match != nil {
+		event.Repo, branch = match[1], match[2]
+	}
+
+	if event.Kind == "" {
+		switch {
+		case strings.Contains(lower, "pull request"):
+			event.Kind = "pull_request"
+		case strings.Contains(lower, "issue"):
+			event.Kind = "issue"
+		case strings.Contains(lower, "release"):
+			event.Kind = "release"
+		case strings.Contains(lower, "new commit") || strings.Contains(lower, "commit") && strings.Contains(lower, "pushed"):
+			event.Kind = "push"
+		default:
+			return BotEvent{}, false
+		}
+	}
+
+	if event.Number == 0 && (event.Kind == "pull_request" || event.Kind == "issue") {
+		if match := issueNumber.FindStringSubmatch(prose); match != nil {
+			event.Number, _ = strconv.Atoi(match[1])
+		}
+	}
+	if event.Kind == "push" && event.Ref == "" {
+		event.Ref = branch
+		if match := pushedBranch.FindStringSubmatch(prose); match != nil {
+			event.Ref = match[1]
+		}
+	}
+	// Titles such as "Fix closed connections" must not be read as actions
+	verbs := prose
+	if event.Title != "" {
+		verbs = strings.Replace(verbs, event.Title, "", 1)
+	}
+	for _, verb := range githubActions {
+		if verb.pattern.MatchString(verbs) {
+			event.Action = verb.action
+			break
+		}
+	}
+	if event.Action == "" && event.Kind == "push" {
+		event.Action = "pushed"
+	}
+	if match := githubActor.FindStringSubmatch(prose); match != nil {
+		event.Actor = match[1]
+	}
+
+	return event, true
+}
@@ -0,0 +1,149 @@
+package processing
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/ollama/ollamatest"
+)
+
+func TestInterpretBotMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message models.SlackMessage
+		want    BotEvent
+		wantOK  bool
+	}{
+		{
+			name: "pull request opened",
+			message: models.SlackMessage{BotID: "B1", BotName: "GitHub", Content: "Pull request opened by <https://github.com/alice|alice>\n" +
+				"<https://github.com/acme/api/pull/42|#42 Fix closed connections>"},
+			want: BotEvent{App: "github", Kind: "pull_request", Repo: "acme/api", Number: 42, Action: "opened",
+				Actor: "alice", Title: "Fix closed connections", URL: "https://github.com/acme/api/pull/42"},
+			wantOK: true,
+		},
+		{
+			name:    "legacy pull request merged",
+			message: models.SlackMessage{BotID: "B1", BotName: "github", Content: "[acme/api] Pull request merged by bob #42"},
+			want:    BotEvent{App: "github", Kind: "pull_request", Repo: "acme/api", Number: 42, Action: "merged", Actor: "bob"},
+			wantOK:  true,
+		},
+		{
+			name: "issue comment",
+			message: models.SlackMessage{BotID: "B1", BotName: "GitHub", Content: "Comment on issue " +
+				"<https://github.com/acme/api/issues/7#issuecomment-1|#7 Crash on start> by carol"},
+			want: BotEvent{App: "github", Kind: "issue", Repo: "acme/api", Number: 7, Action: "commented",
+				Actor: "carol", Title: "Crash on start", URL: "https://github.com/acme/api/issues/7#issuecomment-1"},
+			wantOK: true,
+		},
+		{
+			name: "commits pushed",
+			message: models.SlackMessage{BotID: "B1", BotName: "GitHub", Content: "<https://github.com/acme/api/compare/1a2b...3c4d|3 new commits> " +
+				"pushed to `<https://github.com/acme/api/tree/main|main>` by <https://github.com/dave|dave>"},
+			want: BotEvent{App: "github", Kind: "push", Repo: "acme/api", Action: "pushed", Actor: "dave", Ref: "main",
+				URL: "https://github.com/acme/api/compare/1a2b...3c4d"},
+			wantOK: true,
+		},
+		{
+			name:    "legacy push",
+			message: models.SlackMessage{BotID: "B1", Content: "[acme/api:release-1.x] 1 new commit by erin: https://github.com/acme/api/commit/abc123"},
+			want: BotEvent{App: "github", Kind: "push", Repo: "acme/api", Action: "pushed", Actor: "erin", Ref: "release-1.x",
+				URL: "https://github.com/acme/api/commit/abc123"},
+			wantOK: true,
+		},
+		{
+			name: "release published",
+			message: models.SlackMessage{BotID: "B1", BotName: "GitHub", Content: "Release " +
+				"<https://github.com/acme/api/releases/tag/v1.2.0|v1.2.0> published by frank"},
+			want: BotEvent{App: "github", Kind: "release", Repo: "acme/api", Action: "published", Actor: "frank", Ref: "v1.2.0",
+				URL: "https://github.com/acme/api/releases/tag/v1.2.0"},
+			wantOK: true,
+		},
+		{
+			name:    "user linking a pull request",
+			message: models.SlackMessage{User: "U1", Content: "Can someone review https://github.com/acme/api/pull/42?"},
+		},
+		{
+			name:    "other bot",
+			message: models.SlackMessage{BotID: "B2", BotName: "giphy", Content: "Deploy finished"},
+		},
+		{
+			name:    "GitHub message without text",
+			message: models.SlackMessage{BotID: "B1", BotName: "GitHub"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := InterpretBotMessage(tt.message)
+			if ok != tt.wantOK {
+				t.Fatalf("InterpretBotMessage() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("InterpretBotMessage() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBotEvent_Summary(t *testing.T) {
+	event := BotEvent{App: "github", Kind: "pull_request", Repo: "acme/api", Number: 42, Action: "merged", Actor: "bob", Title: "Add retries"}
+	if got := event.Summary(); got != "[acme/api] Pull request #42 merged by bob: Add retries" {
+		t.Errorf("Summary() = %q", got)
+	}
+	want := []string{"github", "pull-request", "repo:acme/api", "acme/api#42", "action:merged", "actor:bob"}
+	if got := event.Tags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+
+	push := BotEvent{App: "github", Kind: "push", Repo: "acme/api", Action: "pushed", Actor: "dave", Ref: "main"}
+	if got := push.Summary(); got != "[acme/api] Push to main by dave" {
+		t.Errorf("Summary() = %q", got)
+	}
+}
+
+func TestDocumentProcessor_ProcessBotMessage(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	server.SetEmbeddingDimension(8)
+	processor, store := storingProcessor(t, server.URL)
+
+	docs, err := processor.ProcessMessage(context.Background(), models.SlackMessage{
+		MessageID: "1599934232.150700",
+		Channel:   "C1",
+		Type:      "message",
+		BotID:     "B1",
+		BotName:   "GitHub",
+		Content:   "[acme/api] Pull request merged by bob\n<https://github.com/acme/api/pull/42|#42 Add retries>",
+	})
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("ProcessMessage() returned %d documents, want 1", len(docs))
+	}
+
+	metadata := docs[0].Metadata
+	if metadata.Title != "[acme/api] Pull request #42 merged by bob: Add retries" {
+		t.Errorf("Title = %q", metadata.Title)
+	}
+	tags := make(map[string]bool)
+	for _, tag := range metadata.Tags {
+		tags[tag] = true
+	}
+	for _, tag := range []string{"slack", "bot", "github", "pull-request", "repo:acme/api", "acme/api#42", "action:merged", "actor:bob"} {
+		if !tags[tag] {
+			t.Errorf("missing tag %q in %v", tag, metadata.Tags)
+		}
+	}
+
+	// The event is stored with the document
+	if len(store.documents) != 1 {
+		t.Fatalf("stored %d documents, want 1", len(store.documents))
+	}
+	if stored := store.documents[0].Metadata; stored.BotEvent != "pull_request" || stored.EventURL != "https://github.com/acme/api/pull/42" {
+		t.Errorf("stored bot event = %q, URL = %q", stored.BotEvent, stored.EventURL)
+	}
+}
@@ -0,0 +1,76 @@
+package processing
+
+import (
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+// ChannelResolver looks up Slack channels by ID
+type ChannelResolver interface {
+	Lookup(id string) (models.SlackChannel, bool)
+}
+
+// SetChannelResolver makes the processor tag documents with channel names
+// and derive permissions from channel visibility and membership
+func (p *DocumentProcessor) SetChannelResolver(channels ChannelResolver) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.channels = channels
+}
+
+// lookupChannel returns the channel with the given ID if a resolver is set
+func (p *DocumentProcessor) lookupChannel(id string) (models.SlackChannel, bool) {
+	p.mu.RLock()
+	channels := p.channels
+	p.mu.RUnlock()
+
+	if channels == nil || id == "" {
+		return models.SlackChannel{}, false
+	}
+	return channels.Lookup(id)
+}
+
+// channelName returns the channel's display name, falling back to the ID
+func (p *DocumentProcessor) channelName(id string) string {
+	if channel, ok := p.lookupChannel(id); ok {
+		return channel.DisplayName()
+	}
+	return id
+}
+
+// channelPermissions returns who can read a channel's messages: everyone
+// for public channels and the members of private ones. Channels that are
+// unknown, or private without membership data, fall back to the channel ID.
+func (p *DocumentProcessor) channelPermissions(id string) []string {
+	channel, ok := p.lookupChannel(id)
+	switch {
+	case !ok:
+		return []string{id}
+	case !channel.IsPrivate:
+		return []string{vector.PublicPermission}
+	case len(channel.Members) == 0:
+		return []string{id}
+	default:
+		permissions := make([]string, len(channel.Members))
+		copy(permissions, channel.Members)
+		return permissions
+	}
+}
+
+// channelTags returns the channel's name, or its ID if unknown, followed by
+// tags describing the channel
+func (p *DocumentProcessor) channelTags(id string) []string {
+	channel, ok := p.lookupChannel(id)
+	if !ok || channel.Name == "" {
+		return []string{id}
+	}
+
+	tags := []string{channel.Name}
+	if channel.IsPrivate {
+		tags = append(tags, "private")
+	}
+	if channel.IsArchived {
+		tags = append(tags, "archived")
+	}
+	return tags
+}
@@ -0,0 +1,52 @@
+package processing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+// mapChannels is a ChannelResolver backed by a map
+type mapChannels map[string]models.SlackChannel
+
+func (m mapChannels) Lookup(id string) (models.SlackChannel, bool) {
+	channel, ok := m[id]
+	return channel, ok
+}
+
+func TestDocumentProcessor_ChannelPermissionsAndTags(t *testing.T) {
+	processor := NewDocumentProcessor(nil, 100, 20)
+	processor.SetChannelResolver(mapChannels{
+		"C1": {ID: "C1", Name: "general"},
+		"C2": {ID: "C2", Name: "geospatial", IsArchived: true},
+		"G1": {ID: "G1", Name: "champions", IsPrivate: true, Members: []string{"U1", "U2"}},
+		"G2": {ID: "G2", Name: "secret", IsPrivate: true},
+	})
+
+	tests := []struct {
+		name            string
+		channel         string
+		wantPermissions []string
+		wantTags        []string
+	}{
+		{"public channel", "C1", []string{vector.PublicPermission}, []string{"slack", "general", "message"}},
+		{"archived channel", "C2", []string{vector.PublicPermission}, []string{"slack", "geospatial", "archived", "message"}},
+		{"private channel", "G1", []string{"U1", "U2"}, []string{"slack", "champions", "private", "message"}},
+		{"private channel without members", "G2", []string{"G2"}, []string{"slack", "secret", "private", "message"}},
+		{"unknown channel", "C9", []string{"C9"}, []string{"slack", "C9", "message"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := models.SlackMessage{Channel: tt.channel, Type: "message"}
+			if got := processor.extractPermissions(msg); !reflect.DeepEqual(got, tt.wantPermissions) {
+				t.Errorf("extractPermissions() = %v, want %v", got, tt.wantPermissions)
+			}
+			if got := processor.extractTags(msg); !reflect.DeepEqual(got, tt.wantTags) {
+				t.Errorf("extractTags() = %v, want %v", got, tt.wantTags)
+			}
+		})
+	}
+}
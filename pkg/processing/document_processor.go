@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/testsabirweb/connect_llm/pkg/embeddings"
@@ -17,64 +18,474 @@ type DocumentProcessor struct {
 	embedder     *embeddings.OllamaEmbedder
 	chunkSize    int
 	chunkOverlap int
+	users        UserResolver
+	channels     ChannelResolver
+	redactor     *Redactor
+	guard        *SecretGuard
+	stages       map[string]Stage
+	pipeline     *Pipeline
+	mu           sync.RWMutex
 }
 
 // NewDocumentProcessor creates a new document processor
 func NewDocumentProcessor(embedder *embeddings.OllamaEmbedder, chunkSize, chunkOverlap int) *DocumentProcessor {
-	return &DocumentProcessor{
+	p := &DocumentProcessor{
 		embedder:     embedder,
 		chunkSize:    chunkSize,
 		chunkOverlap: chunkOverlap,
 	}
+	p.stages = map[string]Stage{
+		StageNormalize: NewStage(StageNormalize, p.normalizeItem),
+		StageFilter:    NewStage(StageFilter, p.filterItem),
+		StageEnrich:    NewStage(StageEnrich, p.enrichItem),
+		StageSecrets:   NewStage(StageSecrets, p.guardItem),
+		StageRedact:    NewStage(StageRedact, p.redactItem),
+		StageChunk:     NewStage(StageChunk, p.chunkItem),
+		StageEmbed:     NewStage(StageEmbed, p.embedItem),
+	}
+	p.pipeline, _ = p.buildPipeline(DefaultStages) //nolint:errcheck // The default stages are all built in
+	return p
+}
+
+// RegisterStage makes a stage available to SetStages under its name,
+// replacing any stage of that name, e.g. a language detection stage or
+// NewStoreStage. The pipeline in use is rebuilt if it includes the stage.
+func (p *DocumentProcessor) RegisterStage(stage Stage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stages[stage.Name()] = stage
+	if pipeline, err := p.buildPipeline(p.pipeline.Stages()); err == nil {
+		p.pipeline = pipeline
+	}
+}
+
+// SetStages sets the stages messages and records are processed with, by
+// name and in order. Empty restores DefaultStages. Orders that would leave
+// text unscreened, or embed documents before they are chunked, are
+// rejected; set the SecretGuard and Redactor first, so they are checked.
+func (p *DocumentProcessor) SetStages(names ...string) error {
+	if len(names) == 0 {
+		names = DefaultStages
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pipeline, err := p.buildPipeline(names)
+	if err != nil {
+		return err
+	}
+	p.pipeline = pipeline
+	return nil
+}
+
+// buildPipeline creates a pipeline of the registered stages with the given
+// names. The caller must hold p.mu, except in NewDocumentProcessor.
+func (p *DocumentProcessor) buildPipeline(names []string) (*Pipeline, error) {
+	stages := make([]Stage, 0, len(names))
+	for _, name := range names {
+		stage, ok := p.stages[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown processing stage: %s", name)
+		}
+		stages = append(stages, stage)
+	}
+	pipeline := NewPipeline(stages...)
+	if err := p.checkStages(pipeline.Stages()); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// checkStages rejects stage orders that would embed or store text the
+// secret guard or redactor never saw: the secrets and redact stages must be
+// included while a guard or redactor is set, and must come after the
+// stages building the record and before chunk, which copies the text into
+// documents. Documents must be chunked before they are embedded or stored,
+// and no stage may run twice. The caller must hold p.mu.
+func (p *DocumentProcessor) checkStages(names []string) error {
+	position := make(map[string]int, len(names))
+	for i, name := range names {
+		if _, ok := position[name]; ok {
+			return fmt.Errorf("duplicate processing stage: %s", name)
+		}
+		position[name] = i
+	}
+	// before reports whether first runs before second, if both run
+	before := func(first, second string) bool {
+		i, hasFirst := position[first]
+		j, hasSecond := position[second]
+		return !hasFirst || !hasSecond || i < j
+	}
+
+	if p.guard != nil && !hasStage(position, StageSecrets) {
+		return fmt.Errorf("processing stages must include %s while secret scanning is enabled", StageSecrets)
+	}
+	if p.redactor != nil && !hasStage(position, StageRedact) {
+		return fmt.Errorf("processing stages must include %s while redaction is enabled", StageRedact)
+	}
+	for _, screen := range []string{StageSecrets, StageRedact} {
+		for _, builder := range []string{StageNormalize, StageEnrich} {
+			if !before(builder, screen) {
+				return fmt.Errorf("processing stage %s must come after %s", screen, builder)
+			}
+		}
+		if !before(screen, StageChunk) {
+			return fmt.Errorf("processing stage %s must come before %s", screen, StageChunk)
+		}
+	}
+	if !before(StageSecrets, StageRedact) {
+		return fmt.Errorf("processing stage %s must come before %s", StageSecrets, StageRedact)
+	}
+	for _, consumer := range []string{StageEmbed, StageStore} {
+		if hasStage(position, consumer) && (!hasStage(position, StageChunk) || !before(StageChunk, consumer)) {
+			return fmt.Errorf("processing stage %s must come after %s", consumer, StageChunk)
+		}
+	}
+	if !before(StageEmbed, StageStore) {
+		return fmt.Errorf("processing stage %s must come before %s", StageEmbed, StageStore)
+	}
+	return nil
+}
+
+// hasStage reports whether the stage positions include name
+func hasStage(position map[string]int, name string) bool {
+	_, ok := position[name]
+	return ok
+}
+
+// Pipeline returns the pipeline messages and records are processed with,
+// e.g. for its metrics
+func (p *DocumentProcessor) Pipeline() *Pipeline {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pipeline
 }
 
 // ProcessMessage converts a Slack message to one or more documents
 func (p *DocumentProcessor) ProcessMessage(ctx context.Context, msg models.SlackMessage) ([]vector.Document, error) {
-	// Skip empty messages
-	if msg.Content == "" && len(msg.FileIDs) == 0 {
+	return p.process(ctx, &Item{Message: &msg})
+}
+
+// ProcessRecord converts a record from any connector to one or more
+// documents. The title defaults to the start of the text and the author to
+// the author ID.
+func (p *DocumentProcessor) ProcessRecord(ctx context.Context, record models.Record) ([]vector.Document, error) {
+	return p.process(ctx, &Item{Record: record})
+}
+
+// process runs an item through the pipeline
+func (p *DocumentProcessor) process(ctx context.Context, item *Item) ([]vector.Document, error) {
+	if err := p.Pipeline().Run(ctx, item); err != nil {
+		return nil, err
+	}
+	if item.Drop {
 		return nil, nil
 	}
+	return item.Documents, nil
+}
+
+// normalizeItem builds the record of a Slack message, embedding readable
+// text, with names rather than user and channel IDs so queries naming a
+// person or channel match, and keeping the markup for display. Records
+// from other connectors only have surrounding space trimmed.
+func (p *DocumentProcessor) normalizeItem(ctx context.Context, item *Item) error {
+	if item.Thread != nil {
+		p.normalizeThread(item)
+		return nil
+	}
+	msg := item.Message
+	if msg == nil {
+		item.Record.Text = strings.TrimSpace(item.Record.Text)
+		return nil
+	}
+
+	// Messages posted with Block Kit may only have blocks
+	if msg.Content == "" {
+		msg.Content = slackBlocksMarkup(msg.Blocks)
+	}
 
-	// Generate chunks if content is too long
-	chunks := p.chunkText(msg.Content)
+	text := p.normalizeText(msg.Content)
+	normalized := *msg
+	normalized.Content = text.Text
+
+	item.Record = normalized.Record()
+	if text.Text != msg.Content {
+		item.Record.RawText = msg.Content
+	}
+	item.Record.Links = text.Links
+	item.Record.LinkedChannels = text.Channels
+	item.Record.Code = text.Code
+	return nil
+}
+
+// filterItem drops empty messages and records. Messages with files are
+// kept, as their files are indexed with them.
+func (p *DocumentProcessor) filterItem(ctx context.Context, item *Item) error {
+	hasFiles := item.Message != nil && len(item.Message.FileIDs) > 0
+	item.Drop = strings.TrimSpace(item.Record.Text) == "" && item.Record.RawText == "" && !hasFiles
+	return nil
+}
+
+// enrichItem titles records and fills in their authors. Slack messages
+// also get permissions, tags and a link, and bot notifications are titled
+// and tagged with what they report, so they can be filtered or searched by
+// repository.
+func (p *DocumentProcessor) enrichItem(ctx context.Context, item *Item) error {
+	record := &item.Record
+	if item.Thread != nil {
+		p.enrichThread(item)
+		return nil
+	}
+	if item.Message == nil {
+		if record.Title == "" {
+			record.Title = truncateTitle(record.Text)
+		}
+		if record.Author == "" {
+			record.Author = record.AuthorID
+		}
+		if record.UpdatedAt.IsZero() {
+			record.UpdatedAt = record.CreatedAt
+		}
+		return nil
+	}
+
+	// Bot notifications are interpreted from their markup
+	event, isEvent := InterpretBotMessage(*item.Message)
+	msg := *item.Message
+	msg.Content = record.Text
+
+	record.Title = p.generateTitle(msg)
+	record.Author = p.displayName(msg.User)
+	record.Permissions = p.extractPermissions(msg)
+	record.Tags = p.extractTags(msg)
+	record.URL = p.generateSlackURL(msg)
+	if isEvent {
+		record.Title = event.Summary()
+		record.Tags = append(record.Tags, event.Tags()...)
+		for key, value := range event.Metadata() {
+			record.Metadata[key] = value
+		}
+	}
+	return nil
+}
+
+// guardItem screens the record for secrets before redaction can alter
+// them
+func (p *DocumentProcessor) guardItem(ctx context.Context, item *Item) error {
+	guard := p.secretGuard()
+	switch {
+	case guard == nil:
+	case item.transcript != nil:
+		p.screenThread(item, guard.guardText)
+	case guard.guardRecord(&item.Record):
+		item.Drop = true
+	}
+	return nil
+}
+
+// redactItem redacts personal data from the record
+func (p *DocumentProcessor) redactItem(ctx context.Context, item *Item) error {
+	if item.transcript != nil {
+		if redactor := p.activeRedactor(); redactor != nil {
+			p.screenThread(item, redactor.redactLine)
+		}
+		return nil
+	}
+	if p.redactRecord(&item.Record) {
+		item.Drop = true
+	}
+	return nil
+}
+
+// chunkItem splits the record's text into documents, at least one even if
+// the text is empty
+func (p *DocumentProcessor) chunkItem(ctx context.Context, item *Item) error {
+	record := item.Record
+	chunks := p.chunkText(record.Text)
 	if len(chunks) == 0 {
-		chunks = []string{msg.Content} // At least one chunk even if empty
+		chunks = []string{record.Text}
 	}
 
-	documents := make([]vector.Document, 0, len(chunks))
+	// Thread documents are told apart from those of the parent message
+	idPrefix := ""
+	if item.Thread != nil {
+		idPrefix = "thread:"
+	}
 
+	item.Documents = make([]vector.Document, 0, len(chunks))
 	for i, chunk := range chunks {
-		// Generate unique ID for the chunk
-		docID := p.generateDocumentID(msg.MessageID, i)
+		item.Documents = append(item.Documents, vector.Document{
+			// Generate unique ID for the chunk
+			ID:         p.generateDocumentID(idPrefix+record.ID, i),
+			Content:    chunk,
+			RawContent: record.RawText,
+			Source:     record.Source,
+			SourceID:   record.ID,
+			Metadata: vector.DocumentMetadata{
+				Title:          record.Title,
+				Author:         record.Author,
+				AuthorID:       record.AuthorID,
+				CreatedAt:      record.CreatedAt,
+				UpdatedAt:      record.UpdatedAt,
+				Permissions:    record.Permissions,
+				Tags:           record.Tags,
+				URL:            record.URL,
+				ThreadID:       record.ThreadID,
+				ParentID:       record.ParentID,
+				Links:          record.Links,
+				LinkedChannels: record.LinkedChannels,
+				Code:           record.Code,
+				BotEvent:       record.Metadata["bot_event"],
+				Ref:            record.Metadata["ref"],
+				EventURL:       record.Metadata["event_url"],
+				ReactionCount:  record.Engagement.Reactions,
+				ReplyCount:     record.Engagement.Replies,
+				Pinned:         record.Engagement.Pinned,
+				Reactions:      reactionList(record.Metadata["reactions"]),
+			},
+		})
+	}
+	return nil
+}
+
+// reactionList splits the reactions of a record's metadata, e.g.
+// "wave:2,+1:1", into one entry per emoji
+func reactionList(reactions string) []string {
+	if reactions == "" {
+		return nil
+	}
+	return strings.Split(reactions, ",")
+}
 
-		// Generate embedding for the chunk
-		embedding, err := p.embedder.GenerateEmbedding(ctx, chunk)
+// embedItem generates an embedding for each document
+func (p *DocumentProcessor) embedItem(ctx context.Context, item *Item) error {
+	for i := range item.Documents {
+		embedding, err := p.embedder.GenerateEmbedding(ctx, item.Documents[i].Content)
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate embedding: %w", err)
+			return fmt.Errorf("failed to generate embedding: %w", err)
 		}
+		item.Documents[i].Embedding = embedding
+	}
+	return nil
+}
 
-		// Create document
-		doc := vector.Document{
-			ID:        docID,
-			Content:   chunk,
-			Source:    "slack",
-			SourceID:  msg.MessageID,
-			Embedding: embedding,
-			Metadata: vector.DocumentMetadata{
-				Title:       p.generateTitle(msg),
-				Author:      msg.User,
-				CreatedAt:   msg.Timestamp,
-				UpdatedAt:   msg.Timestamp,
-				Permissions: p.extractPermissions(msg),
-				Tags:        p.extractTags(msg),
-				URL:         p.generateSlackURL(msg),
-			},
+// ProcessThread converts a reconstructed thread into documents holding the
+// whole conversation, so questions about a thread's outcome can be answered
+// from a single retrieval result. The thread runs through the same stages
+// as messages, its transcript as the record's text.
+func (p *DocumentProcessor) ProcessThread(ctx context.Context, thread models.SlackThread) ([]vector.Document, error) {
+	return p.process(ctx, &Item{Thread: &thread})
+}
+
+// normalizeThread builds the record of a thread, its text a transcript with
+// one line per message. Threads without any text are dropped.
+func (p *DocumentProcessor) normalizeThread(item *Item) {
+	thread := item.Thread
+	updatedAt := thread.Parent.Timestamp
+	if len(thread.Replies) > 0 {
+		updatedAt = thread.Replies[len(thread.Replies)-1].Timestamp
+	}
+
+	// The conversation as a whole is as useful as its messages together
+	engagement := models.Engagement{Replies: len(thread.Replies)}
+	for _, msg := range append([]models.SlackMessage{thread.Parent}, thread.Replies...) {
+		engagement = engagement.Add(models.Engagement{
+			Reactions: msg.Reactions.Total(),
+			Pinned:    msg.IsPinned(),
+		})
+	}
+
+	threadID := thread.ID()
+	item.transcript = p.threadTranscript(*thread)
+	item.Record = models.Record{
+		ID:         threadID,
+		Source:     "slack",
+		Text:       item.transcript.String(),
+		AuthorID:   thread.Parent.User,
+		CreatedAt:  thread.Parent.Timestamp,
+		UpdatedAt:  updatedAt,
+		ThreadID:   threadID,
+		Engagement: engagement,
+		Metadata:   map[string]string{"channel": thread.Channel, "thread_ts": thread.ThreadTS},
+	}
+	item.Drop = len(item.transcript.lines) == 0
+}
+
+// enrichThread titles a thread after its parent message and tags it as a
+// thread summary
+func (p *DocumentProcessor) enrichThread(item *Item) {
+	thread := item.Thread
+	record := &item.Record
+	record.Title = "Thread: " + p.generateTitle(thread.Parent)
+	record.Author = p.displayName(thread.Parent.User)
+	record.Permissions = p.extractPermissions(thread.Parent)
+	record.Tags = append(append([]string{"slack"}, p.channelTags(thread.Channel)...), "thread-summary")
+	record.URL = p.generateSlackURL(thread.Parent)
+}
+
+// screenThread applies screen to the title and to each message of a
+// thread's transcript on its own, leaving out the messages screen returns
+// "" for, and drops the thread if none remain. The transcript is rendered
+// into the record's text again.
+func (p *DocumentProcessor) screenThread(item *Item, screen func(string) string) {
+	if item.transcript == nil {
+		return
+	}
+	if item.Record.Title != "" {
+		if item.Record.Title = screen(item.Record.Title); item.Record.Title == "" {
+			item.Record.Title = "Thread: " + p.generateTitle(models.SlackMessage{})
+		}
+	}
+
+	lines := item.transcript.lines[:0]
+	for _, line := range item.transcript.lines {
+		if line.text = screen(line.text); line.text != "" {
+			lines = append(lines, line)
 		}
+	}
+	item.transcript.lines = lines
+	item.Record.Text = item.transcript.String()
+	item.Drop = len(lines) == 0
+}
+
+// transcript is a thread rendered as a header and one line per message
+type transcript struct {
+	header string
+	lines  []transcriptLine
+}
+
+// transcriptLine is a message of a transcript and who wrote it
+type transcriptLine struct {
+	speaker string
+	text    string
+}
+
+// String renders the transcript as text
+func (t *transcript) String() string {
+	var sb strings.Builder
+	sb.WriteString(t.header)
+	for _, line := range t.lines {
+		fmt.Fprintf(&sb, "\n%s: %s", line.speaker, line.text)
+	}
+	return sb.String()
+}
 
-		documents = append(documents, doc)
+// threadTranscript renders a thread as one line per message
+func (p *DocumentProcessor) threadTranscript(thread models.SlackThread) *transcript {
+	participants := thread.Participants()
+	for i, user := range participants {
+		participants[i] = p.displayName(user)
 	}
+	t := &transcript{header: fmt.Sprintf("Thread in channel %s with %d replies from %s",
+		p.channelName(thread.Channel), len(thread.Replies), strings.Join(participants, ", "))}
 
-	return documents, nil
+	for _, msg := range append([]models.SlackMessage{thread.Parent}, thread.Replies...) {
+		text := strings.TrimSpace(p.normalizeText(msg.Content).Text)
+		if text == "" {
+			continue
+		}
+		t.lines = append(t.lines, transcriptLine{speaker: p.displayName(msg.User), text: text})
+	}
+	return t
 }
 
 // ProcessMessages processes multiple messages into documents
@@ -138,10 +549,7 @@ func (p *DocumentProcessor) generateDocumentID(messageID string, chunkIndex int)
 // generateTitle creates a title for the document
 func (p *DocumentProcessor) generateTitle(msg models.SlackMessage) string {
 	// Use first 50 characters of content as title
-	title := msg.Content
-	if len(title) > 50 {
-		title = title[:47] + "..."
-	}
+	title := truncateTitle(msg.Content)
 
 	// If no content, use message type
 	if title == "" {
@@ -155,16 +563,23 @@ func (p *DocumentProcessor) generateTitle(msg models.SlackMessage) string {
 	return title
 }
 
+// truncateTitle shortens text to at most 50 characters for use as a title
+func truncateTitle(text string) string {
+	if len(text) > 50 {
+		return text[:47] + "..."
+	}
+	return text
+}
+
 // extractPermissions determines who can access this document
 func (p *DocumentProcessor) extractPermissions(msg models.SlackMessage) []string {
-	// For now, use channel ID as permission
-	// In a real system, you'd map channels to user groups
-	return []string{msg.Channel}
+	return p.channelPermissions(msg.Channel)
 }
 
 // extractTags generates tags for the document
 func (p *DocumentProcessor) extractTags(msg models.SlackMessage) []string {
-	tags := []string{"slack", msg.Channel}
+	tags := []string{"slack"}
+	tags = append(tags, p.channelTags(msg.Channel)...)
 
 	if msg.Type != "" {
 		tags = append(tags, msg.Type)
@@ -182,6 +597,14 @@ func (p *DocumentProcessor) extractTags(msg models.SlackMessage) []string {
 		tags = append(tags, "has-replies")
 	}
 
+	if msg.IsThreadReply() {
+		tags = append(tags, "thread-reply")
+	}
+
+	if user, ok := p.lookupUser(msg.User); msg.BotID != "" || ok && user.IsBot {
+		tags = append(tags, "bot")
+	}
+
 	return tags
 }
 
@@ -2,12 +2,14 @@ package processing
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/testsabirweb/connect_llm/pkg/embeddings"
 	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/ollama/ollamatest"
 )
 
 // MockEmbedder is a mock implementation for testing
@@ -240,6 +242,16 @@ func TestDocumentProcessor_ExtractTags(t *testing.T) {
 			},
 			wantTags: []string{"slack", "C123", "message", "thread", "has-replies"},
 		},
+		{
+			name: "Thread reply",
+			message: models.SlackMessage{
+				Channel:  "C123",
+				Type:     "message",
+				TS:       "123.789",
+				ThreadTS: "123.456",
+			},
+			wantTags: []string{"slack", "C123", "message", "thread", "thread-reply"},
+		},
 		{
 			name: "System message",
 			message: models.SlackMessage{
@@ -273,6 +285,133 @@ func TestDocumentProcessor_ExtractTags(t *testing.T) {
 	}
 }
 
+func TestDocumentProcessor_ProcessThread(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	server.SetEmbeddingDimension(8)
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder(server.URL, "nomic-embed-text"), 1000, 100)
+
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	thread := models.SlackThread{
+		Channel:  "C123",
+		ThreadTS: "1709287200.000100",
+		Parent: models.SlackMessage{
+			MessageID: "parent", Channel: "C123", User: "U1", TS: "1709287200.000100",
+			ThreadTS: "1709287200.000100", ReplyCount: 2, Timestamp: start,
+			Content:   "Which database should we use?",
+			Reactions: models.SlackReactions{{Name: "eyes", Count: 1}},
+		},
+		Replies: []models.SlackMessage{
+			{MessageID: "r1", Channel: "C123", User: "U2", Timestamp: start.Add(time.Minute), Content: "Postgres"},
+			{MessageID: "r2", Channel: "C123", User: "U1", Timestamp: start.Add(2 * time.Minute), Content: "Decided: Postgres",
+				Reactions: models.SlackReactions{{Name: "+1", Count: 3}, {Name: "tada", Users: []string{"U2"}}},
+				PinnedTo:  []string{"C123"}},
+		},
+	}
+
+	docs, err := processor.ProcessThread(context.Background(), thread)
+	if err != nil {
+		t.Fatalf("ProcessThread() error = %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("ProcessThread() returned %d documents, want 1", len(docs))
+	}
+
+	doc := docs[0]
+	for _, line := range []string{"U1: Which database should we use?", "U2: Postgres", "U1: Decided: Postgres"} {
+		if !strings.Contains(doc.Content, line) {
+			t.Errorf("transcript is missing %q:\n%s", line, doc.Content)
+		}
+	}
+	if doc.SourceID != "C123:1709287200.000100" || doc.Metadata.ThreadID != doc.SourceID {
+		t.Errorf("SourceID = %q, ThreadID = %q", doc.SourceID, doc.Metadata.ThreadID)
+	}
+	if !doc.Metadata.UpdatedAt.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("UpdatedAt = %v, want time of last reply", doc.Metadata.UpdatedAt)
+	}
+	if len(doc.Embedding) != 8 {
+		t.Errorf("embedding has %d dimensions, want 8", len(doc.Embedding))
+	}
+	// Engagement adds up across the thread's messages
+	if doc.Metadata.ReactionCount != 5 || doc.Metadata.ReplyCount != 2 || !doc.Metadata.Pinned {
+		t.Errorf("engagement = %d reactions, %d replies, pinned %v; want 5, 2, true",
+			doc.Metadata.ReactionCount, doc.Metadata.ReplyCount, doc.Metadata.Pinned)
+	}
+
+	// A single message keeps its own engagement
+	replyDocs, err := processor.ProcessMessage(context.Background(), thread.Replies[1])
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if meta := replyDocs[0].Metadata; meta.ReactionCount != 4 || meta.ReplyCount != 0 || !meta.Pinned {
+		t.Errorf("engagement = %d reactions, %d replies, pinned %v; want 4, 0, true",
+			meta.ReactionCount, meta.ReplyCount, meta.Pinned)
+	}
+
+	// Its reactions are stored per emoji
+	storing, store := storingProcessor(t, server.URL)
+	if _, err := storing.ProcessMessage(context.Background(), thread.Replies[1]); err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if len(store.documents) != 1 || !reflect.DeepEqual(store.documents[0].Metadata.Reactions, []string{"+1:3", "tada:1"}) {
+		t.Errorf("stored documents = %+v, want reactions +1:3 and tada:1", store.documents)
+	}
+}
+
+func TestDocumentProcessor_ProcessRecord(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	server.SetEmbeddingDimension(8)
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder(server.URL, "nomic-embed-text"), 1000, 100)
+
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	record := models.Record{
+		ID:          "markdown:docs/setup.md",
+		Source:      "markdown",
+		Text:        "Run make setup before starting the server for the first time.",
+		AuthorID:    "alice",
+		URL:         "docs/setup.md",
+		CreatedAt:   created,
+		Permissions: []string{"public"},
+		Tags:        []string{"markdown", "docs"},
+	}
+
+	docs, err := processor.ProcessRecord(context.Background(), record)
+	if err != nil {
+		t.Fatalf("ProcessRecord() error = %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("ProcessRecord() returned %d documents, want 1", len(docs))
+	}
+
+	doc := docs[0]
+	if doc.Source != "markdown" || doc.SourceID != record.ID || doc.Content != record.Text {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+	if doc.Metadata.Title != "Run make setup before starting the server for t..." {
+		t.Errorf("Title = %q, want the start of the text", doc.Metadata.Title)
+	}
+	if doc.Metadata.Author != "alice" || !doc.Metadata.UpdatedAt.Equal(created) {
+		t.Errorf("Author = %q, UpdatedAt = %v", doc.Metadata.Author, doc.Metadata.UpdatedAt)
+	}
+	if len(doc.Metadata.Permissions) != 1 || len(doc.Metadata.Tags) != 2 || doc.Metadata.URL != record.URL {
+		t.Errorf("unexpected metadata: %+v", doc.Metadata)
+	}
+
+	// Messages and records with the same ID share document IDs
+	msgDocs, err := processor.ProcessMessage(context.Background(), models.SlackMessage{MessageID: record.ID, Channel: "C1", Content: "Hi"})
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if len(msgDocs) != 1 || msgDocs[0].ID != doc.ID || msgDocs[0].Source != "slack" {
+		t.Errorf("unexpected message documents: %+v", msgDocs)
+	}
+
+	if docs, err := processor.ProcessRecord(context.Background(), models.Record{ID: "empty", Text: "  "}); err != nil || docs != nil {
+		t.Errorf("ProcessRecord() of an empty record = %v, %v", docs, err)
+	}
+}
+
 func TestChunkingConfig(t *testing.T) {
 	config := DefaultChunkingConfig()
 
@@ -0,0 +1,303 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+// StageDryRun is the name of the stage a DryRun tallies messages in
+const StageDryRun = "dry-run"
+
+// DefaultEmbedLatency is the time one embedding is assumed to take when
+// estimating the runtime of a dry run
+const DefaultEmbedLatency = 150 * time.Millisecond
+
+// charsPerToken approximates how many characters of English text make up a
+// token for embedding models
+const charsPerToken = 4
+
+// chunkBuckets are the upper bounds, in words, of the chunk size histogram
+var chunkBuckets = []int{25, 50, 100, 200, 300, 400, 500, 750, 1000}
+
+// DryRunConfig configures the estimates of a DryRun
+type DryRunConfig struct {
+	// EmbedLatency is the time one embedding is assumed to take. Zero uses
+	// DefaultEmbedLatency.
+	EmbedLatency time.Duration
+	// EmbedWorkers is the number of embeddings generated at once. Zero
+	// uses one.
+	EmbedWorkers int
+}
+
+// DryRun tallies what ingestion would produce without embedding or
+// storing anything. Its Stage replaces the embed stage to count the
+// messages and records that reach it, and the DryRun itself is the vector
+// store counting the documents that would be stored, thread documents
+// included. It is safe for concurrent use.
+type DryRun struct {
+	config  DryRunConfig
+	started time.Time
+
+	messages  int
+	channels  map[string]int
+	subtypes  map[string]int
+	bots      map[string]int
+	botCount  int
+	documents int
+	threads   int
+	chunks    []int
+	words     int
+	chars     int
+	mu        sync.Mutex
+}
+
+// NewDryRun creates a dry run, timing it from now. The zero config uses
+// the defaults.
+func NewDryRun(cfg DryRunConfig) *DryRun {
+	if cfg.EmbedLatency <= 0 {
+		cfg.EmbedLatency = DefaultEmbedLatency
+	}
+	if cfg.EmbedWorkers <= 0 {
+		cfg.EmbedWorkers = 1
+	}
+	return &DryRun{
+		config:   cfg,
+		started:  time.Now(),
+		channels: make(map[string]int),
+		subtypes: make(map[string]int),
+		bots:     make(map[string]int),
+		chunks:   make([]int, len(chunkBuckets)+1),
+	}
+}
+
+// Stage returns the stage counting messages and records, to be run after
+// the chunk stage in place of embed
+func (d *DryRun) Stage() Stage {
+	return NewStage(StageDryRun, func(ctx context.Context, item *Item) error {
+		d.countRecord(item)
+		return nil
+	})
+}
+
+// DryRunStages returns stages with embed and store replaced by the dry run
+// stage, which is added after chunk if stages embed nothing
+func DryRunStages(stages []string) []string {
+	if len(stages) == 0 {
+		stages = DefaultStages
+	}
+	dryRun := make([]string, 0, len(stages)+1)
+	added := false
+	for _, name := range stages {
+		switch name {
+		case StageEmbed, StageStore:
+			if !added {
+				dryRun = append(dryRun, StageDryRun)
+				added = true
+			}
+		default:
+			dryRun = append(dryRun, name)
+		}
+	}
+	if !added {
+		dryRun = append(dryRun, StageDryRun)
+	}
+	return dryRun
+}
+
+// countRecord adds a message or record to the breakdowns. Threads repeat
+// their messages, so they are only counted by their documents.
+func (d *DryRun) countRecord(item *Item) {
+	if item.Thread != nil {
+		return
+	}
+	record := item.Record
+	channel := record.Metadata["channel"]
+	if channel == "" {
+		channel = record.Source
+	}
+	subtype := record.Metadata["subtype"]
+	if subtype == "" {
+		subtype = "message"
+	}
+	isBot := record.Metadata["bot_id"] != "" || hasTag(record.Tags, "bot")
+	bot := firstNonEmpty(record.Metadata["bot_name"], record.Author, record.Metadata["bot_id"], record.AuthorID, "unknown")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.messages++
+	d.channels[channel]++
+	d.subtypes[subtype]++
+	if isBot {
+		d.botCount++
+		d.bots[bot]++
+	}
+}
+
+// countDocument adds a document that would be stored
+func (d *DryRun) countDocument(doc vector.Document) {
+	words := len(strings.Fields(doc.Content))
+	bucket := sort.SearchInts(chunkBuckets, words)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.documents++
+	if hasTag(doc.Metadata.Tags, "thread-summary") {
+		d.threads++
+	}
+	d.chunks[bucket]++
+	d.words += words
+	d.chars += len(doc.Content)
+}
+
+// Initialize implements vector.Client
+func (d *DryRun) Initialize(ctx context.Context) error {
+	return nil
+}
+
+// Store implements vector.Client by counting the document
+func (d *DryRun) Store(ctx context.Context, doc vector.Document) error {
+	d.countDocument(doc)
+	return nil
+}
+
+// Search implements vector.Client; a dry run holds no documents
+func (d *DryRun) Search(ctx context.Context, query []float32, limit int) ([]vector.Document, error) {
+	return nil, nil
+}
+
+// SearchWithOptions implements vector.Client; a dry run holds no documents
+func (d *DryRun) SearchWithOptions(ctx context.Context, opts vector.SearchOptions) ([]vector.Document, error) {
+	return nil, nil
+}
+
+// Delete implements vector.Client
+func (d *DryRun) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+// HealthCheck implements vector.Client
+func (d *DryRun) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// Count is a number of messages with the same value, such as a channel
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// HistogramBucket counts the chunks of at most MaxWords words, and more
+// than those of the bucket before. The last bucket has no MaxWords.
+type HistogramBucket struct {
+	MaxWords int `json:"max_words,omitempty"`
+	Count    int `json:"count"`
+}
+
+// Label returns the bucket's range, e.g. "101-200" or ">1000"
+func (b HistogramBucket) Label(previous HistogramBucket) string {
+	if b.MaxWords == 0 {
+		return fmt.Sprintf(">%d", previous.MaxWords)
+	}
+	return fmt.Sprintf("%d-%d", previous.MaxWords+1, b.MaxWords)
+}
+
+// DryRunReport is what a dry run found
+type DryRunReport struct {
+	// Messages counts the messages and records that would be embedded,
+	// broken down by channel (or source), subtype and bot
+	Messages    int     `json:"messages"`
+	ByChannel   []Count `json:"by_channel"`
+	BySubtype   []Count `json:"by_subtype"`
+	BotMessages int     `json:"bot_messages"`
+	ByBot       []Count `json:"by_bot"`
+	// Documents counts the documents that would be stored, ThreadDocuments
+	// of them for whole threads
+	Documents       int               `json:"documents"`
+	ThreadDocuments int               `json:"thread_documents"`
+	ChunkWords      []HistogramBucket `json:"chunk_words"`
+	AverageWords    float64           `json:"average_words"`
+	// EstimatedTokens approximates the tokens embedded, at four characters
+	// a token
+	EstimatedTokens int `json:"estimated_tokens"`
+	// Elapsed is how long the dry run took, and EstimatedRuntime how long
+	// the real run is expected to: parsing as long as the dry run, plus
+	// embedding every document at the configured latency and concurrency
+	Elapsed          time.Duration `json:"elapsed"`
+	EstimatedRuntime time.Duration `json:"estimated_runtime"`
+	EmbedLatency     time.Duration `json:"embed_latency"`
+	EmbedWorkers     int           `json:"embed_workers"`
+}
+
+// Report returns what the dry run has counted so far
+func (d *DryRun) Report() DryRunReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	report := DryRunReport{
+		Messages:        d.messages,
+		BotMessages:     d.botCount,
+		ByChannel:       sortedCounts(d.channels),
+		BySubtype:       sortedCounts(d.subtypes),
+		ByBot:           sortedCounts(d.bots),
+		Documents:       d.documents,
+		ThreadDocuments: d.threads,
+		ChunkWords:      make([]HistogramBucket, len(d.chunks)),
+		EstimatedTokens: d.chars / charsPerToken,
+		Elapsed:         time.Since(d.started),
+		EmbedLatency:    d.config.EmbedLatency,
+		EmbedWorkers:    d.config.EmbedWorkers,
+	}
+	for i, count := range d.chunks {
+		report.ChunkWords[i].Count = count
+		if i < len(chunkBuckets) {
+			report.ChunkWords[i].MaxWords = chunkBuckets[i]
+		}
+	}
+	if d.documents > 0 {
+		report.AverageWords = float64(d.words) / float64(d.documents)
+	}
+	embedding := time.Duration(d.documents) * d.config.EmbedLatency / time.Duration(d.config.EmbedWorkers)
+	report.EstimatedRuntime = report.Elapsed + embedding
+	return report
+}
+
+// sortedCounts returns counts by descending count, then name
+func sortedCounts(counts map[string]int) []Count {
+	sorted := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, Count{Name: name, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// hasTag reports whether tags include tag
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package processing
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/embeddings"
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+func TestDryRunStages(t *testing.T) {
+	tests := []struct {
+		stages []string
+		want   []string
+	}{
+		{nil, []string{"normalize", "filter", "enrich", "secrets", "redact", "chunk", "dry-run"}},
+		{[]string{"normalize", "chunk", "embed", "store"}, []string{"normalize", "chunk", "dry-run"}},
+		{[]string{"normalize", "chunk"}, []string{"normalize", "chunk", "dry-run"}},
+	}
+	for _, tt := range tests {
+		if got := DryRunStages(tt.stages); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DryRunStages(%v) = %v, want %v", tt.stages, got, tt.want)
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	// Nothing listens here, so any embedding fails the test
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder("http://127.0.0.1:1", "nomic-embed-text"), 10, 2)
+	dry := NewDryRun(DryRunConfig{EmbedLatency: time.Second, EmbedWorkers: 2})
+	processor.RegisterStage(dry.Stage())
+	if err := processor.SetStages(DryRunStages(nil)...); err != nil {
+		t.Fatalf("SetStages() error = %v", err)
+	}
+
+	ctx := context.Background()
+	long := strings.Repeat("word ", 15)
+	messages := []models.SlackMessage{
+		{MessageID: "m1", Channel: "C1", User: "U1", TS: "1", Content: "Deploy finished"},
+		{MessageID: "m2", Channel: "C1", User: "U2", TS: "2", Content: long},
+		{MessageID: "m3", Channel: "C2", User: "U3", TS: "3", Subtype: "bot_message", BotID: "B1", BotName: "deploybot", Content: "Build 42 passed"},
+	}
+	for _, msg := range messages {
+		docs, err := processor.ProcessMessage(ctx, msg)
+		if err != nil {
+			t.Fatalf("ProcessMessage(%s) error = %v", msg.MessageID, err)
+		}
+		for _, doc := range docs {
+			if len(doc.Embedding) != 0 {
+				t.Errorf("document %s was embedded", doc.ID)
+			}
+			if err := dry.Store(ctx, doc); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	thread := models.SlackThread{
+		Channel:  "C1",
+		ThreadTS: "1",
+		Parent:   messages[0],
+		Replies:  []models.SlackMessage{{MessageID: "r1", Channel: "C1", User: "U2", TS: "5", ThreadTS: "1", Content: "Thanks"}},
+	}
+	docs, err := processor.ProcessThread(ctx, thread)
+	if err != nil {
+		t.Fatalf("ProcessThread() error = %v", err)
+	}
+	for _, doc := range docs {
+		dry.Store(ctx, doc) //nolint:errcheck // A dry run never fails to store
+	}
+
+	report := dry.Report()
+	if report.Messages != 3 || report.BotMessages != 1 {
+		t.Errorf("messages = %d, bot messages = %d; want 3 and 1", report.Messages, report.BotMessages)
+	}
+	if want := []Count{{"C1", 2}, {"C2", 1}}; !reflect.DeepEqual(report.ByChannel, want) {
+		t.Errorf("ByChannel = %v, want %v", report.ByChannel, want)
+	}
+	if want := []Count{{"message", 2}, {"bot_message", 1}}; !reflect.DeepEqual(report.BySubtype, want) {
+		t.Errorf("BySubtype = %v, want %v", report.BySubtype, want)
+	}
+	if want := []Count{{"deploybot", 1}}; !reflect.DeepEqual(report.ByBot, want) {
+		t.Errorf("ByBot = %v, want %v", report.ByBot, want)
+	}
+
+	// The long message and the thread are split into two chunks each
+	if report.Documents != 6 || report.ThreadDocuments != 2 {
+		t.Errorf("documents = %d, thread documents = %d; want 6 and 2", report.Documents, report.ThreadDocuments)
+	}
+	total := 0
+	for _, bucket := range report.ChunkWords {
+		total += bucket.Count
+	}
+	if total != report.Documents || report.ChunkWords[0].MaxWords != 25 || report.ChunkWords[len(report.ChunkWords)-1].MaxWords != 0 {
+		t.Errorf("ChunkWords = %+v", report.ChunkWords)
+	}
+	if report.EstimatedTokens == 0 {
+		t.Error("EstimatedTokens = 0")
+	}
+	// Six embeddings of a second, two at once
+	if embedding := report.EstimatedRuntime - report.Elapsed; embedding != 3*time.Second {
+		t.Errorf("estimated embedding time = %s, want 3s", embedding)
+	}
+}
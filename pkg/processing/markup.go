@@ -0,0 +1,361 @@
+package processing
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// SlackText is Slack message text rendered for reading, together with the
+// references found in it
+type SlackText struct {
+	// Text has mentions, channel references and links resolved, emoji
+	// shortcodes replaced and entities decoded. Code keeps its backticks.
+	Text string
+	// Links are the URLs linked from the text, in order of appearance
+	Links []string
+	// Channels are the IDs of the channels referenced
+	Channels []string
+	// Code holds the inline code spans and code blocks, without backticks
+	Code []string
+}
+
+// NormalizeSlackText renders Slack markup such as <url|label>,
+// <#C123|channel>, <!here>, :emoji: and &gt; as readable text. Users and
+// channels are named through the resolvers when given, or through the
+// labels Slack included. Exports that store text JSON-escaped, with
+// literal \n and \u2019 sequences, are decoded first.
+func NormalizeSlackText(text string, users UserResolver, channels ChannelResolver) SlackText {
+	r := markupRenderer{users: users, channels: channels}
+	return r.render(text)
+}
+
+// normalizeText renders text with the processor's user and channel
+// directories
+func (p *DocumentProcessor) normalizeText(text string) SlackText {
+	p.mu.RLock()
+	r := markupRenderer{users: p.users, channels: p.channels}
+	p.mu.RUnlock()
+	return r.render(text)
+}
+
+var (
+	// slackCode matches code blocks and inline code, which are kept verbatim
+	slackCode = regexp.MustCompile("```[\\s\\S]*?```|`[^`\\n]+`")
+	// slackMarkup matches <...> references: links, mentions and commands
+	slackMarkup = regexp.MustCompile(`<([^<>\s][^<>]*)>`)
+	// slackEmoji matches emoji shortcodes such as :thumbsup: and :+1:, but
+	// not the digits of times such as 10:30:00
+	slackEmoji = regexp.MustCompile(`:([a-z+][a-z0-9_+'-]*|100):`)
+	// exportEscape matches the JSON escapes some exports leave in text
+	exportEscape = regexp.MustCompile(`(?:\\u[0-9a-fA-F]{4})+|\\[nrt"\\/]`)
+	// slackFormats match *bold*, _italic_ and ~strike~ around words
+	slackFormats = []*regexp.Regexp{
+		regexp.MustCompile(`(^|[\s(>"'])\*([^*\s](?:[^*\n]*[^*\s])?)\*($|[\s).,!?:;"'])`),
+		regexp.MustCompile(`(^|[\s(>"'])_([^_\s](?:[^_\n]*[^_\s])?)_($|[\s).,!?:;"'])`),
+		regexp.MustCompile(`(^|[\s(>"'])~([^~\s](?:[^~\n]*[^~\s])?)~($|[\s).,!?:;"'])`),
+	}
+	slackEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+)
+
+// slackEmojiUnicode maps common emoji shortcodes to their characters;
+// others are left as written
+var slackEmojiUnicode = map[string]string{
+	"+1": "👍", "thumbsup": "👍", "-1": "👎", "thumbsdown": "👎",
+	"smile": "😄", "slightly_smiling_face": "🙂", "simple_smile": "🙂", "grinning": "😀",
+	"joy": "😂", "laughing": "😆", "wink": "😉", "sweat_smile": "😅", "thinking_face": "🤔",
+	"pray": "🙏", "clap": "👏", "wave": "👋", "raised_hands": "🙌", "muscle": "💪",
+	"point_up": "☝️", "point_right": "👉", "point_down": "👇", "eyes": "👀",
+	"heart": "❤️", "fire": "🔥", "tada": "🎉", "rocket": "🚀", "star": "⭐", "sparkles": "✨",
+	"white_check_mark": "✅", "heavy_check_mark": "✔️", "x": "❌", "warning": "⚠️",
+	"bomb": "💣", "bug": "🐛", "100": "💯", "chart_with_upwards_trend": "📈",
+	"memo": "📝", "link": "🔗", "bulb": "💡", "sob": "😭", "cry": "😢", "ok_hand": "👌",
+}
+
+// markupRenderer renders Slack markup, naming users and channels through
+// the resolvers
+type markupRenderer struct {
+	users    UserResolver
+	channels ChannelResolver
+}
+
+func (r markupRenderer) render(text string) SlackText {
+	var out SlackText
+	text = strings.ReplaceAll(decodeExportEscapes(text), "\u00a0", " ")
+
+	var sb strings.Builder
+	last := 0
+	for _, loc := range slackCode.FindAllStringIndex(text, -1) {
+		sb.WriteString(r.renderProse(text[last:loc[0]], &out))
+		code := slackEntities.Replace(text[loc[0]:loc[1]])
+		sb.WriteString(code)
+		if span := strings.TrimSpace(strings.Trim(code, "`")); span != "" {
+			out.Code = append(out.Code, span)
+		}
+		last = loc[1]
+	}
+	sb.WriteString(r.renderProse(text[last:], &out))
+
+	out.Text = strings.TrimSpace(sb.String())
+	return out
+}
+
+// renderProse renders text outside code
+func (r markupRenderer) renderProse(text string, out *SlackText) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range slackMarkup.FindAllStringSubmatchIndex(text, -1) {
+		sb.WriteString(renderPlain(text[last:loc[0]]))
+		sb.WriteString(r.renderReference(text[loc[2]:loc[3]], out))
+		last = loc[1]
+	}
+	sb.WriteString(renderPlain(text[last:]))
+	return sb.String()
+}
+
+// renderReference renders the inside of a <...> reference
+func (r markupRenderer) renderReference(ref string, out *SlackText) string {
+	target, label, _ := strings.Cut(ref, "|")
+	target = slackEntities.Replace(target)
+	label = slackEntities.Replace(label)
+
+	switch {
+	case strings.HasPrefix(target, "@"):
+		id := target[1:]
+		if r.users != nil {
+			if user, ok := r.users.Lookup(id); ok {
+				return "@" + user.DisplayName()
+			}
+		}
+		if label != "" {
+			return "@" + strings.TrimPrefix(label, "@")
+		}
+		return target
+	case strings.HasPrefix(target, "#"):
+		id := target[1:]
+		out.Channels = append(out.Channels, id)
+		if r.channels != nil {
+			if channel, ok := r.channels.Lookup(id); ok && channel.Name != "" {
+				return "#" + channel.Name
+			}
+		}
+		if label != "" {
+			return "#" + label
+		}
+		return target
+	case strings.HasPrefix(target, "!"):
+		// Special mentions (<!here>), user groups (<!subteam^S1|@ops>) and
+		// dates (<!date^1392734382^{date}|Feb 18, 2014>)
+		if label != "" {
+			return label
+		}
+		command, _, _ := strings.Cut(target[1:], "^")
+		return "@" + command
+	case strings.HasPrefix(target, "mailto:"):
+		if label != "" {
+			return label
+		}
+		return strings.TrimPrefix(target, "mailto:")
+	default:
+		out.Links = append(out.Links, target)
+		if label == "" || label == target || "http://"+label == target || "https://"+label == target {
+			return target
+		}
+		return label + " (" + target + ")"
+	}
+}
+
+// renderPlain replaces emoji shortcodes and formatting marks and decodes
+// entities in text without references
+func renderPlain(text string) string {
+	text = slackEmoji.ReplaceAllStringFunc(text, func(code string) string {
+		name := strings.Trim(code, ":")
+		if strings.HasPrefix(name, "skin-tone-") {
+			return ""
+		}
+		if emoji, ok := slackEmojiUnicode[name]; ok {
+			return emoji
+		}
+		return code
+	})
+	// Nested marks such as _*Problem*_ take a second pass
+	for i := 0; i < 2; i++ {
+		for _, format := range slackFormats {
+			text = format.ReplaceAllString(text, "$1$2$3")
+		}
+	}
+	return slackEntities.Replace(text)
+}
+
+// decodeExportEscapes decodes text an export stored JSON-escaped. Text
+// with real line breaks, or without escaped ones or escaped characters, is
+// returned unchanged.
+func decodeExportEscapes(text string) string {
+	if strings.Contains(text, "\n") || !strings.Contains(text, `\n`) && !strings.Contains(text, `\u`) {
+		return text
+	}
+	return exportEscape.ReplaceAllStringFunc(text, func(escape string) string {
+		switch escape {
+		case `\n`:
+			return "\n"
+		case `\r`:
+			return ""
+		case `\t`:
+			return "\t"
+		case `\"`, `\\`, `\/`:
+			return escape[1:]
+		}
+		// \uXXXX sequences, decoded together so surrogate pairs combine
+		units := make([]uint16, 0, len(escape)/6)
+		for i := 0; i+6 <= len(escape); i += 6 {
+			unit, _ := strconv.ParseUint(escape[i+2:i+6], 16, 16)
+			units = append(units, uint16(unit))
+		}
+		return string(utf16.Decode(units))
+	})
+}
+
+// slackBlock is a layout block of a message, as stored in the blocks
+// column or field of an export
+type slackBlock struct {
+	Type     string            `json:"type"`
+	Text     *slackTextObject  `json:"text"`
+	Fields   []slackTextObject `json:"fields"`
+	Elements []slackElement    `json:"elements"`
+}
+
+// slackTextObject is the text of section, header and context blocks
+type slackTextObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackElement is an element of a rich_text block or of a context block
+type slackElement struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	URL       string          `json:"url"`
+	UserID    string          `json:"user_id"`
+	ChannelID string          `json:"channel_id"`
+	GroupID   string          `json:"usergroup_id"`
+	Name      string          `json:"name"`
+	Range     string          `json:"range"`
+	Style     json.RawMessage `json:"style"`
+	Elements  []slackElement  `json:"elements"`
+}
+
+// slackMarkupEscaper escapes literal text so it is not read as markup
+var slackMarkupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackBlocksMarkup renders a message's blocks as Slack markup, for
+// messages whose text is empty, such as posts made with Block Kit. It
+// returns "" if the blocks are missing or cannot be read.
+func slackBlocksMarkup(blocks string) string {
+	blocks = strings.TrimSpace(blocks)
+	if blocks == "" || blocks == "null" {
+		return ""
+	}
+	var parsed []slackBlock
+	if err := json.Unmarshal([]byte(blocks), &parsed); err != nil {
+		return ""
+	}
+
+	var parts []string
+	for _, block := range parsed {
+		var sb strings.Builder
+		switch block.Type {
+		case "rich_text":
+			for _, element := range block.Elements {
+				writeRichText(&sb, element)
+			}
+		case "section", "header":
+			if block.Text != nil {
+				sb.WriteString(textObjectMarkup(*block.Text))
+			}
+			for _, field := range block.Fields {
+				sb.WriteString("\n" + textObjectMarkup(field))
+			}
+		case "context":
+			var texts []string
+			for _, element := range block.Elements {
+				if element.Type == "mrkdwn" || element.Type == "plain_text" {
+					texts = append(texts, textObjectMarkup(slackTextObject{Type: element.Type, Text: element.Text}))
+				}
+			}
+			sb.WriteString(strings.Join(texts, " "))
+		}
+		if part := strings.TrimSpace(sb.String()); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// textObjectMarkup returns the markup of a text object; plain text is
+// escaped
+func textObjectMarkup(text slackTextObject) string {
+	if text.Type == "plain_text" {
+		return slackMarkupEscaper.Replace(text.Text)
+	}
+	return text.Text
+}
+
+// writeRichText writes a rich_text element as markup
+func writeRichText(sb *strings.Builder, element slackElement) {
+	switch element.Type {
+	case "rich_text_section":
+		writeRichTextElements(sb, element.Elements)
+		sb.WriteString("\n")
+	case "rich_text_preformatted":
+		sb.WriteString("```")
+		writeRichTextElements(sb, element.Elements)
+		sb.WriteString("```\n")
+	case "rich_text_quote":
+		var quote strings.Builder
+		writeRichTextElements(&quote, element.Elements)
+		for _, line := range strings.Split(quote.String(), "\n") {
+			sb.WriteString("&gt; " + line + "\n")
+		}
+	case "rich_text_list":
+		for _, item := range element.Elements {
+			sb.WriteString("• ")
+			writeRichTextElements(sb, item.Elements)
+			sb.WriteString("\n")
+		}
+	}
+}
+
+// writeRichTextElements writes the inline elements of a rich_text section
+func writeRichTextElements(sb *strings.Builder, elements []slackElement) {
+	for _, element := range elements {
+		switch element.Type {
+		case "text":
+			text := slackMarkupEscaper.Replace(element.Text)
+			var style struct {
+				Code bool `json:"code"`
+			}
+			if json.Unmarshal(element.Style, &style) == nil && style.Code && strings.TrimSpace(text) != "" {
+				text = "`" + text + "`"
+			}
+			sb.WriteString(text)
+		case "link":
+			sb.WriteString("<" + element.URL)
+			if element.Text != "" {
+				sb.WriteString("|" + slackMarkupEscaper.Replace(element.Text))
+			}
+			sb.WriteString(">")
+		case "user":
+			sb.WriteString("<@" + element.UserID + ">")
+		case "channel":
+			sb.WriteString("<#" + element.ChannelID + ">")
+		case "usergroup":
+			sb.WriteString("<!subteam^" + element.GroupID + ">")
+		case "broadcast":
+			sb.WriteString("<!" + element.Range + ">")
+		case "emoji":
+			sb.WriteString(":" + element.Name + ":")
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package processing
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/testsabirweb/connect_llm/pkg/embeddings"
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/ollama/ollamatest"
+)
+
+func TestNormalizeSlackText(t *testing.T) {
+	channels := mapChannels{"C1": {ID: "C1", Name: "deployments"}}
+
+	tests := []struct {
+		name string
+		text string
+		want SlackText
+	}{
+		{
+			name: "links",
+			text: "see <https://example.com/a?x=1&amp;y=2|the docs> and <https://example.com/b>",
+			want: SlackText{
+				Text:  "see the docs (https://example.com/a?x=1&y=2) and https://example.com/b",
+				Links: []string{"https://example.com/a?x=1&y=2", "https://example.com/b"},
+			},
+		},
+		{
+			name: "mentions and channels",
+			text: "<!here> <@U02> asked in <#C1> and <#C9|general>, cc <!subteam^S1|@ops> <mailto:dev@example.com|dev@example.com>",
+			want: SlackText{
+				Text:     "@here @bob asked in #deployments and #general, cc @ops dev@example.com",
+				Channels: []string{"C1", "C9"},
+			},
+		},
+		{
+			name: "emoji, entities and formatting",
+			text: "*Heads up* :tada: :wave::skin-tone-2: a &lt; b &amp;&amp; _really_ ~not~ :custom: at 10:30:00, snake_case_name",
+			want: SlackText{Text: "Heads up 🎉 👋 a < b && really not :custom: at 10:30:00, snake_case_name"},
+		},
+		{
+			name: "code kept verbatim",
+			text: "run `make *all*` then\n```if a &gt; b {\n  <@U02>\n}```",
+			want: SlackText{
+				Text: "run `make *all*` then\n```if a > b {\n  <@U02>\n}```",
+				Code: []string{"make *all*", "if a > b {\n  <@U02>\n}"},
+			},
+		},
+		{
+			name: "JSON-escaped export",
+			text: `_*Problem*_\n\u2022 it doesn\u2019t work \ud83d\ude00 today`,
+			want: SlackText{Text: "Problem\n• it doesn’t work 😀 today"},
+		},
+		{
+			name: "real line breaks keep backslashes",
+			text: "regex \\n here\nsecond line",
+			want: SlackText{Text: "regex \\n here\nsecond line"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeSlackText(tt.text, testUsers, channels)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeSlackText() =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlackBlocksMarkup(t *testing.T) {
+	blocks := `[
+		{"type": "header", "text": {"type": "plain_text", "text": "Release <1.2>"}},
+		{"type": "rich_text", "elements": [
+			{"type": "rich_text_section", "elements": [
+				{"type": "text", "text": "Hi "},
+				{"type": "user", "user_id": "U02"},
+				{"type": "text", "text": ", run "},
+				{"type": "text", "text": "make release", "style": {"code": true}},
+				{"type": "text", "text": " in "},
+				{"type": "channel", "channel_id": "C1"},
+				{"type": "emoji", "name": "rocket"}
+			]},
+			{"type": "rich_text_list", "style": "bullet", "elements": [
+				{"type": "rich_text_section", "elements": [{"type": "link", "url": "https://example.com/notes", "text": "notes"}]}
+			]},
+			{"type": "rich_text_preformatted", "elements": [{"type": "text", "text": "a < b"}]}
+		]},
+		{"type": "context", "elements": [{"type": "mrkdwn", "text": "posted by <!here>"}, {"type": "image"}]}
+	]`
+
+	want := "Release &lt;1.2&gt;\n" +
+		"Hi <@U02>, run `make release` in <#C1>:rocket:\n" +
+		"• <https://example.com/notes|notes>\n" +
+		"```a &lt; b```\n" +
+		"posted by <!here>"
+	if got := slackBlocksMarkup(blocks); got != want {
+		t.Errorf("slackBlocksMarkup() =\n%q\nwant\n%q", got, want)
+	}
+
+	for _, blocks := range []string{"", "null", `[{"type": "rich_text"}]`, "not json"} {
+		if got := slackBlocksMarkup(blocks); got != "" {
+			t.Errorf("slackBlocksMarkup(%q) = %q, want empty", blocks, got)
+		}
+	}
+}
+
+func TestDocumentProcessor_ProcessMessageMarkup(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	server.SetEmbeddingDimension(8)
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder(server.URL, "nomic-embed-text"), 1000, 100)
+
+	raw := "<!here> `0.37.0rc1` is up, see <https://example.com/vote|the vote>"
+	docs, err := processor.ProcessMessage(context.Background(), models.SlackMessage{MessageID: "1", Channel: "C1", Content: raw})
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("ProcessMessage() returned %d documents, want 1", len(docs))
+	}
+	if docs[0].Content != "@here `0.37.0rc1` is up, see the vote (https://example.com/vote)" || docs[0].RawContent != raw {
+		t.Errorf("Content = %q, RawContent = %q", docs[0].Content, docs[0].RawContent)
+	}
+
+	// Messages with only blocks are rendered from them
+	docs, err = processor.ProcessMessage(context.Background(), models.SlackMessage{
+		MessageID: "2",
+		Channel:   "C1",
+		Blocks:    `[{"type": "section", "text": {"type": "mrkdwn", "text": "Deploy *done* :white_check_mark:"}}]`,
+	})
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if len(docs) != 1 || docs[0].Content != "Deploy done ✅" {
+		t.Errorf("unexpected documents for a blocks-only message: %+v", docs)
+	}
+}
+
+func TestDocumentProcessor_StoresMarkupMetadata(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	server.SetEmbeddingDimension(8)
+	processor, store := storingProcessor(t, server.URL)
+	redactor, err := NewRedactor(RedactionConfig{Detectors: []string{DetectorEmail}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor.SetRedactor(redactor)
+
+	raw := "Run `make release OWNER=ops@example.com` in <#C2|deploys>, see <https://example.com/runbook|the runbook>"
+	if _, err := processor.ProcessMessage(context.Background(), models.SlackMessage{MessageID: "1", Channel: "C1", Content: raw}); err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if len(store.documents) != 1 {
+		t.Fatalf("stored %d documents, want 1", len(store.documents))
+	}
+	metadata := store.documents[0].Metadata
+	if !reflect.DeepEqual(metadata.Links, []string{"https://example.com/runbook"}) {
+		t.Errorf("Links = %q", metadata.Links)
+	}
+	if !reflect.DeepEqual(metadata.LinkedChannels, []string{"C2"}) {
+		t.Errorf("LinkedChannels = %q", metadata.LinkedChannels)
+	}
+	// Code spans are screened like the text they come from
+	if !reflect.DeepEqual(metadata.Code, []string{"make release OWNER=[EMAIL]"}) {
+		t.Errorf("Code = %q", metadata.Code)
+	}
+}
@@ -0,0 +1,221 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+// Names of the built-in stages
+const (
+	StageNormalize = "normalize" // renders Slack markup as text and builds the record
+	StageFilter    = "filter"    // drops records without content
+	StageEnrich    = "enrich"    // adds titles, authors, permissions, tags and links
+	StageSecrets   = "secrets"   // applies the SecretGuard, if set
+	StageRedact    = "redact"    // applies the Redactor, if set
+	StageChunk     = "chunk"     // splits the text into documents
+	StageEmbed     = "embed"     // generates the documents' embeddings
+	StageStore     = "store"     // stores the documents; see NewStoreStage
+)
+
+// DefaultStages is the order messages and records are processed in unless
+// configured otherwise. Storing is left to the ingestion service.
+var DefaultStages = []string{
+	StageNormalize, StageFilter, StageEnrich, StageSecrets, StageRedact, StageChunk, StageEmbed,
+}
+
+// Item is what flows through a Pipeline: a message or record and the
+// documents made from it
+type Item struct {
+	// Message is the Slack message being processed, if any. The normalize
+	// stage builds Record from it.
+	Message *models.SlackMessage
+	// Thread is the Slack thread being processed, if any. The normalize
+	// stage builds Record from it, with a transcript of the thread as the
+	// text; the secrets and redact stages screen each message of the
+	// transcript on its own and render the text again.
+	Thread *models.SlackThread
+	Record models.Record
+	// Documents are created by the chunk stage and embedded by the embed
+	// stage
+	Documents []vector.Document
+	// Drop stops processing the item without error; it produces no
+	// documents
+	Drop bool
+
+	// transcript is the thread rendered by the normalize stage
+	transcript *transcript
+}
+
+// Stage is one step of a Pipeline. Stages may change the item, set Drop or
+// fail; they must be safe for concurrent use.
+type Stage interface {
+	Name() string
+	Process(ctx context.Context, item *Item) error
+}
+
+// stageFunc is a Stage implemented by a function
+type stageFunc struct {
+	name    string
+	process func(ctx context.Context, item *Item) error
+}
+
+// NewStage creates a stage from a function, e.g. to detect the language of
+// a record and tag it
+func NewStage(name string, process func(ctx context.Context, item *Item) error) Stage {
+	return stageFunc{name: name, process: process}
+}
+
+// Name implements Stage
+func (s stageFunc) Name() string {
+	return s.name
+}
+
+// Process implements Stage
+func (s stageFunc) Process(ctx context.Context, item *Item) error {
+	return s.process(ctx, item)
+}
+
+// NewStoreStage creates a stage storing each document in client, for
+// pipelines used without the ingestion service, which stores documents
+// itself
+func NewStoreStage(client vector.Client) Stage {
+	return NewStage(StageStore, func(ctx context.Context, item *Item) error {
+		for _, doc := range item.Documents {
+			if err := client.Store(ctx, doc); err != nil {
+				return fmt.Errorf("failed to store document %s: %w", doc.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// StageError is the error of a stage that failed
+type StageError struct {
+	Stage    string
+	RecordID string
+	Err      error
+}
+
+// Error implements error
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s stage failed for %s: %v", e.Stage, e.RecordID, e.Err)
+}
+
+// Unwrap returns the stage's error
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// StageMetrics counts what a stage has done
+type StageMetrics struct {
+	Name string `json:"name"`
+	// Processed counts the items the stage ran on, Dropped those it
+	// dropped and Errors those it failed on
+	Processed int64 `json:"processed"`
+	Dropped   int64 `json:"dropped"`
+	Errors    int64 `json:"errors"`
+	// Duration is the total time spent in the stage
+	Duration  time.Duration `json:"duration"`
+	LastError string        `json:"last_error,omitempty"`
+}
+
+// Pipeline runs items through stages in order, keeping metrics per stage.
+// It is safe for concurrent use.
+type Pipeline struct {
+	stages  []Stage
+	metrics []StageMetrics
+	mu      sync.Mutex
+}
+
+// NewPipeline creates a pipeline running the stages in the given order
+func NewPipeline(stages ...Stage) *Pipeline {
+	pipeline := &Pipeline{
+		stages:  stages,
+		metrics: make([]StageMetrics, len(stages)),
+	}
+	for i, stage := range stages {
+		pipeline.metrics[i].Name = stage.Name()
+	}
+	return pipeline
+}
+
+// Stages returns the names of the stages in order
+func (p *Pipeline) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, stage := range p.stages {
+		names[i] = stage.Name()
+	}
+	return names
+}
+
+// Run runs an item through the stages until one drops it or fails, in
+// which case the error is a *StageError
+func (p *Pipeline) Run(ctx context.Context, item *Item) error {
+	for i, stage := range p.stages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		err := stage.Process(ctx, item)
+		p.record(i, time.Since(start), item.Drop, err)
+		if err != nil {
+			return &StageError{Stage: stage.Name(), RecordID: item.id(), Err: err}
+		}
+		if item.Drop {
+			return nil
+		}
+	}
+	return nil
+}
+
+// record adds a run of stage i to its metrics
+func (p *Pipeline) record(i int, duration time.Duration, dropped bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m := &p.metrics[i]
+	m.Processed++
+	m.Duration += duration
+	switch {
+	case err != nil:
+		m.Errors++
+		m.LastError = err.Error()
+	case dropped:
+		m.Dropped++
+	}
+}
+
+// Metrics returns the metrics of each stage in order
+func (p *Pipeline) Metrics() []StageMetrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]StageMetrics(nil), p.metrics...)
+}
+
+// ResetMetrics clears the metrics, e.g. at the start of an ingestion run
+func (p *Pipeline) ResetMetrics() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.metrics {
+		p.metrics[i] = StageMetrics{Name: p.metrics[i].Name}
+	}
+}
+
+// id returns the ID of the item's record, or of its message or thread
+// before the record is built
+func (item *Item) id() string {
+	switch {
+	case item.Record.ID != "":
+		return item.Record.ID
+	case item.Message != nil:
+		return item.Message.MessageID
+	case item.Thread != nil:
+		return item.Thread.ID()
+	}
+	return ""
+}
@@ -0,0 +1,226 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/testsabirweb/connect_llm/pkg/embeddings"
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/ollama/ollamatest"
+	"github.com/testsabirweb/connect_llm/pkg/secrets"
+	"github.com/testsabirweb/connect_llm/pkg/vector"
+)
+
+// storeRecorder is a vector client recording the stored documents
+type storeRecorder struct {
+	vector.Client
+	stored    []string
+	documents []vector.Document
+	mu        sync.Mutex
+}
+
+func (s *storeRecorder) Store(ctx context.Context, doc vector.Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stored = append(s.stored, doc.ID)
+	s.documents = append(s.documents, doc)
+	return nil
+}
+
+// storingProcessor returns a processor with the default stages followed
+// by a store stage recording what is stored
+func storingProcessor(t *testing.T, url string) (*DocumentProcessor, *storeRecorder) {
+	t.Helper()
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder(url, "nomic-embed-text"), 1000, 100)
+	store := &storeRecorder{}
+	processor.RegisterStage(NewStoreStage(store))
+	if err := processor.SetStages(append(append([]string(nil), DefaultStages...), StageStore)...); err != nil {
+		t.Fatalf("SetStages() error = %v", err)
+	}
+	return processor, store
+}
+
+func TestDocumentProcessor_Stages(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	server.SetEmbeddingDimension(8)
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder(server.URL, "nomic-embed-text"), 1000, 100)
+
+	if got := processor.Pipeline().Stages(); !reflect.DeepEqual(got, DefaultStages) {
+		t.Errorf("Stages() = %v, want %v", got, DefaultStages)
+	}
+
+	// A custom stage sees the enriched record and can tag it
+	processor.RegisterStage(NewStage("language", func(ctx context.Context, item *Item) error {
+		if item.Record.Title == "" {
+			t.Error("language stage ran before enrich")
+		}
+		if strings.Contains(item.Record.Text, "Hola") {
+			item.Record.Tags = append(item.Record.Tags, "lang:es")
+		}
+		return nil
+	}))
+	store := &storeRecorder{}
+	processor.RegisterStage(NewStoreStage(store))
+	if err := processor.SetStages("normalize", "filter", "enrich", "language", "chunk", "embed", "store"); err != nil {
+		t.Fatalf("SetStages() error = %v", err)
+	}
+
+	ctx := context.Background()
+	docs, err := processor.ProcessRecord(ctx, models.Record{ID: "notes:a", Source: "notes", Text: "Hola, equipo"})
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("ProcessRecord() = %d documents, %v", len(docs), err)
+	}
+	if !reflect.DeepEqual(docs[0].Metadata.Tags, []string{"lang:es"}) || len(docs[0].Embedding) != 8 {
+		t.Errorf("document = %+v", docs[0])
+	}
+	if !reflect.DeepEqual(store.stored, []string{docs[0].ID}) {
+		t.Errorf("stored %v, want %s", store.stored, docs[0].ID)
+	}
+	if docs, err := processor.ProcessRecord(ctx, models.Record{ID: "notes:b", Text: "  "}); err != nil || docs != nil {
+		t.Errorf("ProcessRecord() of an empty record = %v, %v", docs, err)
+	}
+
+	metrics := processor.Pipeline().Metrics()
+	byName := make(map[string]StageMetrics, len(metrics))
+	for _, m := range metrics {
+		byName[m.Name] = m
+	}
+	if m := byName[StageFilter]; m.Processed != 2 || m.Dropped != 1 {
+		t.Errorf("filter metrics = %+v, want 2 processed and 1 dropped", m)
+	}
+	if m := byName[StageStore]; m.Processed != 1 || m.Errors != 0 {
+		t.Errorf("store metrics = %+v, want 1 processed", m)
+	}
+
+	// Failures name the stage and record
+	failure := errors.New("detector unavailable")
+	processor.RegisterStage(NewStage("language", func(ctx context.Context, item *Item) error {
+		return failure
+	}))
+	_, err = processor.ProcessRecord(ctx, models.Record{ID: "notes:c", Text: "Hello"})
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "language" || stageErr.RecordID != "notes:c" || !errors.Is(err, failure) {
+		t.Fatalf("ProcessRecord() error = %v, want a language stage error", err)
+	}
+	for _, m := range processor.Pipeline().Metrics() {
+		if m.Name == "language" && (m.Errors != 1 || m.LastError != failure.Error()) {
+			t.Errorf("language metrics = %+v", m)
+		}
+		if m.Name == StageChunk && m.Processed != 0 {
+			t.Errorf("chunk ran after a failed stage: %+v", m)
+		}
+	}
+
+	if err := processor.SetStages("normalize", "translate"); err == nil {
+		t.Error("SetStages() with an unknown stage succeeded")
+	}
+	if err := processor.SetStages(); err != nil || !reflect.DeepEqual(processor.Pipeline().Stages(), DefaultStages) {
+		t.Errorf("SetStages() = %v, stages %v; want the defaults", err, processor.Pipeline().Stages())
+	}
+}
+
+func TestDocumentProcessor_ThreadStages(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	server.SetEmbeddingDimension(8)
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder(server.URL, "nomic-embed-text"), 1000, 100)
+
+	// Custom stages see threads like messages, with the transcript as text
+	processor.RegisterStage(NewStage("language", func(ctx context.Context, item *Item) error {
+		if item.Thread != nil && strings.Contains(item.Record.Text, "U2: Hola") {
+			item.Record.Tags = append(item.Record.Tags, "lang:es")
+		}
+		return nil
+	}))
+	if err := processor.SetStages("normalize", "filter", "enrich", "language", "chunk", "embed"); err != nil {
+		t.Fatalf("SetStages() error = %v", err)
+	}
+
+	thread := models.SlackThread{
+		Channel:  "C1",
+		ThreadTS: "1",
+		Parent:   models.SlackMessage{MessageID: "p", Channel: "C1", User: "U1", TS: "1", Content: "Bienvenidos"},
+		Replies:  []models.SlackMessage{{MessageID: "r1", Channel: "C1", User: "U2", TS: "2", ThreadTS: "1", Content: "Hola"}},
+	}
+	docs, err := processor.ProcessThread(context.Background(), thread)
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("ProcessThread() = %d documents, %v", len(docs), err)
+	}
+	if tags := docs[0].Metadata.Tags; tags[len(tags)-1] != "lang:es" || !hasTag(tags, "thread-summary") {
+		t.Errorf("tags = %v", tags)
+	}
+	if len(docs[0].Embedding) != 8 {
+		t.Errorf("embedding has %d dimensions, want 8", len(docs[0].Embedding))
+	}
+
+	// Threads without text are dropped by normalize
+	empty := models.SlackThread{Channel: "C1", ThreadTS: "3", Parent: models.SlackMessage{MessageID: "e", Channel: "C1", TS: "3"}}
+	if docs, err := processor.ProcessThread(context.Background(), empty); err != nil || docs != nil {
+		t.Errorf("ProcessThread() of an empty thread = %v, %v", docs, err)
+	}
+	for _, m := range processor.Pipeline().Metrics() {
+		switch m.Name {
+		case StageNormalize:
+			if m.Processed != 2 || m.Dropped != 1 {
+				t.Errorf("normalize metrics = %+v, want 2 processed and 1 dropped", m)
+			}
+		case "language", StageEmbed:
+			if m.Processed != 1 {
+				t.Errorf("%s metrics = %+v, want 1 processed", m.Name, m)
+			}
+		}
+	}
+}
+
+func TestDocumentProcessor_SetStagesRejected(t *testing.T) {
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder("http://127.0.0.1:1", "nomic-embed-text"), 1000, 100)
+	processor.RegisterStage(NewStage("language", func(ctx context.Context, item *Item) error { return nil }))
+
+	for _, stages := range [][]string{
+		{"normalize", "chunk", "chunk", "embed"},
+		{"normalize", "embed", "chunk"},
+		{"normalize", "embed"},
+		{"normalize", "chunk", "store", "embed"},
+		{"normalize", "chunk", "redact", "embed"},
+		{"redact", "normalize", "chunk", "embed"},
+		{"normalize", "enrich", "redact", "secrets", "chunk", "embed"},
+	} {
+		if err := processor.SetStages(stages...); err == nil {
+			t.Errorf("SetStages(%v) succeeded", stages)
+		}
+	}
+	for _, stages := range [][]string{
+		{"normalize", "chunk", "embed"},
+		{"normalize", "enrich", "language", "chunk"},
+	} {
+		if err := processor.SetStages(stages...); err != nil {
+			t.Errorf("SetStages(%v) error = %v", stages, err)
+		}
+	}
+
+	// With a redactor and a secret guard set, their stages must run
+	redactor, err := NewRedactor(RedactionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor.SetRedactor(redactor)
+	if err := processor.SetStages("normalize", "chunk", "embed"); err == nil {
+		t.Error("SetStages() without redact succeeded while redacting")
+	}
+	guard, err := NewSecretGuard(secrets.Default(), SecretQuarantine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor.SetSecretGuard(guard)
+	if err := processor.SetStages("normalize", "redact", "chunk", "embed"); err == nil {
+		t.Error("SetStages() without secrets succeeded while scanning for secrets")
+	}
+	if err := processor.SetStages(); err != nil {
+		t.Errorf("SetStages() of the defaults error = %v", err)
+	}
+}
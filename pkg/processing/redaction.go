@@ -0,0 +1,373 @@
+package processing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// RedactionMode is what happens to text containing personal data
+type RedactionMode string
+
+const (
+	// RedactMask replaces each match with its detector's label, e.g.
+	// "[EMAIL]"
+	RedactMask RedactionMode = "mask"
+	// RedactHash replaces each match with its label and a keyed hash of
+	// the value, e.g. "[EMAIL:3f2a9c1b]", so mentions of the same address
+	// can still be related
+	RedactHash RedactionMode = "hash"
+	// RedactDrop leaves messages and records with any match out entirely
+	RedactDrop RedactionMode = "drop"
+)
+
+// Names of the built-in detectors
+const (
+	DetectorEmail = "email"
+	DetectorPhone = "phone"
+	DetectorIP    = "ip"
+	DetectorCard  = "card"
+)
+
+// RedactionConfig configures a Redactor
+type RedactionConfig struct {
+	Mode RedactionMode
+	// Detectors names the built-in detectors to run. Empty runs them all.
+	Detectors []string
+	// Custom holds further detectors as regular expressions by name, e.g.
+	// "employee_id": `\bEMP-\d{6}\b`
+	Custom map[string]string
+	// HashKey keys the hashes of RedactHash, so they cannot be reversed by
+	// hashing guessed values
+	HashKey string
+}
+
+// detector finds one kind of personal data
+type detector struct {
+	name    string
+	pattern *regexp.Regexp
+	// valid, if set, rejects the match text[start:end] if it only looks
+	// like the data
+	valid func(text string, start, end int) bool
+}
+
+// builtinDetectors in the order they run. Card numbers and IP addresses
+// go before phone numbers, whose pattern would match parts of them.
+var builtinDetectors = []detector{
+	{
+		name:    DetectorCard,
+		pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		valid: func(text string, start, end int) bool {
+			return luhnValid(text[start:end])
+		},
+	},
+	{
+		name:    DetectorEmail,
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		name: DetectorIP,
+		pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b` +
+			`|\b(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}\b`),
+	},
+	{
+		name:    DetectorPhone,
+		pattern: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{2,4}\)\s?|\b\d{2,4}[\s.-])\d{3,4}[\s.-]\d{3,4}\b`),
+		valid:   standaloneDigits,
+	},
+}
+
+// standaloneDigits rejects matches that are part of a longer run of digit
+// groups, such as an account number
+func standaloneDigits(text string, start, end int) bool {
+	isDigit := func(i int) bool { return i >= 0 && i < len(text) && text[i] >= '0' && text[i] <= '9' }
+	isSeparator := func(i int) bool { return i >= 0 && i < len(text) && strings.IndexByte(" .-", text[i]) >= 0 }
+	return !(isSeparator(end) && isDigit(end+1)) && !(isSeparator(start-1) && isDigit(start-2))
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum card
+// numbers carry
+func luhnValid(s string) bool {
+	sum, digits := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+// RedactionReport counts what a Redactor found
+type RedactionReport struct {
+	// Redacted counts the texts that had matches masked or hashed
+	Redacted int `json:"redacted"`
+	// Dropped counts the texts left out in RedactDrop mode
+	Dropped int `json:"dropped"`
+	// Matches counts the matches by detector
+	Matches map[string]int `json:"matches"`
+}
+
+// String summarizes the report, e.g. "3 redacted, 0 dropped (email: 2, phone: 1)"
+func (r RedactionReport) String() string {
+	names := make([]string, 0, len(r.Matches))
+	for name := range r.Matches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	counts := make([]string, 0, len(names))
+	for _, name := range names {
+		counts = append(counts, fmt.Sprintf("%s: %d", name, r.Matches[name]))
+	}
+
+	summary := fmt.Sprintf("%d redacted, %d dropped", r.Redacted, r.Dropped)
+	if len(counts) > 0 {
+		summary += " (" + strings.Join(counts, ", ") + ")"
+	}
+	return summary
+}
+
+// Redactor removes personal data such as email addresses and phone numbers
+// from text before it is embedded and stored. It is safe for concurrent
+// use and counts what it finds until Reset.
+type Redactor struct {
+	mode      RedactionMode
+	detectors []detector
+	hashKey   []byte
+
+	report RedactionReport
+	mu     sync.Mutex
+}
+
+// NewRedactor creates a redactor, checking the mode, the detector names
+// and the custom patterns
+func NewRedactor(config RedactionConfig) (*Redactor, error) {
+	switch config.Mode {
+	case RedactMask, RedactHash, RedactDrop:
+	case "":
+		config.Mode = RedactMask
+	default:
+		return nil, fmt.Errorf("invalid redaction mode: %s", config.Mode)
+	}
+
+	r := &Redactor{
+		mode:    config.Mode,
+		hashKey: []byte(config.HashKey),
+		report:  RedactionReport{Matches: make(map[string]int)},
+	}
+
+	enabled := make(map[string]bool, len(config.Detectors))
+	for _, name := range config.Detectors {
+		enabled[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	known := 0
+	for _, d := range builtinDetectors {
+		if len(enabled) == 0 || enabled[d.name] {
+			r.detectors = append(r.detectors, d)
+			known++
+		}
+	}
+	if known < len(enabled) {
+		return nil, fmt.Errorf("unknown redaction detector in %v (available: %s, %s, %s, %s)",
+			config.Detectors, DetectorEmail, DetectorPhone, DetectorIP, DetectorCard)
+	}
+
+	// Custom detectors run in name order so results do not vary
+	names := make([]string, 0, len(config.Custom))
+	for name := range config.Custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pattern, err := regexp.Compile(config.Custom[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %s: %w", name, err)
+		}
+		r.detectors = append(r.detectors, detector{name: name, pattern: pattern})
+	}
+
+	return r, nil
+}
+
+// Mode returns the redactor's mode
+func (r *Redactor) Mode() RedactionMode {
+	return r.mode
+}
+
+// Redact returns text with personal data masked or hashed, and whether
+// the text must be dropped instead. The matches are added to the report.
+func (r *Redactor) Redact(text string) (redacted string, drop bool) {
+	redacted, matches := r.redact(text)
+	if len(matches) == 0 {
+		return text, false
+	}
+	if r.count(matches) {
+		return "", true
+	}
+	return redacted, false
+}
+
+// count adds the matches of one text to the report, reporting whether the
+// text must be dropped
+func (r *Redactor) count(matches map[string]int) (drop bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, count := range matches {
+		r.report.Matches[name] += count
+	}
+	if r.mode == RedactDrop {
+		r.report.Dropped++
+		return true
+	}
+	r.report.Redacted++
+	return false
+}
+
+// redact replaces the matches of every detector, counting them by name
+func (r *Redactor) redact(text string) (string, map[string]int) {
+	var matches map[string]int
+	for _, d := range r.detectors {
+		var sb strings.Builder
+		last := 0
+		for _, loc := range d.pattern.FindAllStringIndex(text, -1) {
+			if d.valid != nil && !d.valid(text, loc[0], loc[1]) {
+				continue
+			}
+			if matches == nil {
+				matches = make(map[string]int)
+			}
+			matches[d.name]++
+			sb.WriteString(text[last:loc[0]])
+			sb.WriteString(r.replacement(d.name, text[loc[0]:loc[1]]))
+			last = loc[1]
+		}
+		if last > 0 {
+			sb.WriteString(text[last:])
+			text = sb.String()
+		}
+	}
+	return text, matches
+}
+
+// replacement returns what a match is replaced with
+func (r *Redactor) replacement(name, match string) string {
+	label := strings.ToUpper(name)
+	if r.mode != RedactHash {
+		return "[" + label + "]"
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(strings.ToLower(match)))
+	return "[" + label + ":" + hex.EncodeToString(mac.Sum(nil))[:8] + "]"
+}
+
+// Report returns what the redactor has found since it was created or
+// last reset
+func (r *Redactor) Report() RedactionReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.report
+	report.Matches = make(map[string]int, len(r.report.Matches))
+	for name, count := range r.report.Matches {
+		report.Matches[name] = count
+	}
+	return report
+}
+
+// Reset clears the report, e.g. at the start of an ingestion run
+func (r *Redactor) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report = RedactionReport{Matches: make(map[string]int)}
+}
+
+// SetRedactor makes the processor redact personal data from text, titles
+// and raw text before embedding and storage. Nil disables redaction. Set it
+// before SetStages, which checks that the stages redact.
+func (p *DocumentProcessor) SetRedactor(redactor *Redactor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.redactor = redactor
+}
+
+// redactRecord redacts a record's text fields, reporting whether the
+// record must be dropped
+func (p *DocumentProcessor) redactRecord(record *models.Record) (drop bool) {
+	redactor := p.activeRedactor()
+	if redactor == nil {
+		return false
+	}
+
+	// Each field is redacted on its own, so no match spans two of them.
+	// The other fields repeat the text, so the whole record counts once.
+	fields := screenedFields(record)
+	redacted := make([]string, len(fields))
+	var matches map[string]int
+	for i, field := range fields {
+		var found map[string]int
+		redacted[i], found = redactor.redact(*field)
+		for name, count := range found {
+			if matches == nil {
+				matches = make(map[string]int)
+			}
+			matches[name] += count
+		}
+	}
+	if len(matches) == 0 {
+		return false
+	}
+	if redactor.count(matches) {
+		return true
+	}
+	for i, field := range fields {
+		*field = redacted[i]
+	}
+	return false
+}
+
+// redactLine redacts a line of a thread transcript, returning "" if it
+// must be dropped. Thread lines repeat messages that were reported on their
+// own, so they are not added to the report again.
+func (r *Redactor) redactLine(text string) string {
+	redacted, matches := r.redact(text)
+	if len(matches) > 0 && r.mode == RedactDrop {
+		return ""
+	}
+	return redacted
+}
+
+// screenedFields returns the text fields of a record that the secret guard
+// and redactor screen: the title, the text and raw text, and the links and
+// code spans pulled out of the text, which are stored with it
+func screenedFields(record *models.Record) []*string {
+	fields := []*string{&record.Title, &record.Text, &record.RawText}
+	for i := range record.Links {
+		fields = append(fields, &record.Links[i])
+	}
+	for i := range record.Code {
+		fields = append(fields, &record.Code[i])
+	}
+	return fields
+}
+
+// activeRedactor returns the processor's redactor, if any
+func (p *DocumentProcessor) activeRedactor() *Redactor {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.redactor
+}
@@ -0,0 +1,190 @@
+package processing
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/embeddings"
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/ollama/ollamatest"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	redactor, err := NewRedactor(RedactionConfig{
+		Mode:   RedactMask,
+		Custom: map[string]string{"employee_id": `\bEMP-\d{6}\b`},
+	})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"email", "Mail jane.doe+ops@example.co.uk today", "Mail [EMAIL] today"},
+		{"phone", "Call +1 555-123-4567 or (555) 123 4567", "Call [PHONE] or [PHONE]"},
+		{"international phone", "Office: +49 30 1234 5678", "Office: [PHONE]"},
+		{"ipv4", "ssh to 10.0.12.255, not 10.0.12", "ssh to [IP], not 10.0.12"},
+		{"ipv6", "bind 2001:0db8:85a3:0000:0000:8a2e:0370:7334", "bind [IP]"},
+		{"card", "Card 4111 1111 1111 1111 expires soon", "Card [CARD] expires soon"},
+		{"card-like number failing the checksum", "Order 4111 1111 1111 1112", "Order 4111 1111 1111 1112"},
+		{"custom", "Ask EMP-004211 about it", "Ask [EMPLOYEE_ID] about it"},
+		{"slack timestamp", "See 1599934232.150700", "See 1599934232.150700"},
+		{"dates and versions", "Released 2021-01-10 as v1.22.3", "Released 2021-01-10 as v1.22.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, drop := redactor.Redact(tt.text)
+			if got != tt.want || drop {
+				t.Errorf("Redact(%q) = %q, %v; want %q", tt.text, got, drop, tt.want)
+			}
+		})
+	}
+
+	report := redactor.Report()
+	want := RedactionReport{
+		Redacted: 7,
+		Matches:  map[string]int{"email": 1, "phone": 3, "ip": 2, "card": 1, "employee_id": 1},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Report() = %+v, want %+v", report, want)
+	}
+	if got := report.String(); got != "7 redacted, 0 dropped (card: 1, email: 1, employee_id: 1, ip: 2, phone: 3)" {
+		t.Errorf("String() = %q", got)
+	}
+
+	redactor.Reset()
+	if report := redactor.Report(); report.Redacted != 0 || len(report.Matches) != 0 {
+		t.Errorf("Report() after Reset() = %+v", report)
+	}
+}
+
+func TestRedactor_Modes(t *testing.T) {
+	hashed, err := NewRedactor(RedactionConfig{Mode: RedactHash, Detectors: []string{"email"}, HashKey: "k1"})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	first, _ := hashed.Redact("from bob@example.com")
+	second, _ := hashed.Redact("to BOB@example.com, call 555-123-4567")
+	token := strings.TrimPrefix(first, "from ")
+	if !strings.HasPrefix(token, "[EMAIL:") || len(token) != len("[EMAIL:12345678]") {
+		t.Fatalf("hashed email = %q", first)
+	}
+	// The same address hashes alike, and only the enabled detectors run
+	if second != "to "+token+", call 555-123-4567" {
+		t.Errorf("Redact() = %q, want the same hash %s", second, token)
+	}
+	otherKey, _ := NewRedactor(RedactionConfig{Mode: RedactHash, HashKey: "k2"})
+	if got, _ := otherKey.Redact("from bob@example.com"); got == first {
+		t.Error("hashes do not depend on the key")
+	}
+
+	dropping, _ := NewRedactor(RedactionConfig{Mode: RedactDrop})
+	if got, drop := dropping.Redact("reach me at 555-123-4567"); got != "" || !drop {
+		t.Errorf("Redact() = %q, %v; want dropped", got, drop)
+	}
+	if got, drop := dropping.Redact("nothing personal"); got != "nothing personal" || drop {
+		t.Errorf("Redact() = %q, %v; want kept", got, drop)
+	}
+	if report := dropping.Report(); report.Dropped != 1 || report.Redacted != 0 || report.Matches["phone"] != 1 {
+		t.Errorf("Report() = %+v", report)
+	}
+
+	for _, config := range []RedactionConfig{
+		{Mode: "blur"},
+		{Detectors: []string{"email", "ssn"}},
+		{Custom: map[string]string{"broken": "(unclosed"}},
+	} {
+		if _, err := NewRedactor(config); err == nil {
+			t.Errorf("NewRedactor(%+v) succeeded", config)
+		}
+	}
+}
+
+func TestDocumentProcessor_Redaction(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	server.SetEmbeddingDimension(8)
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder(server.URL, "nomic-embed-text"), 1000, 100)
+	redactor, err := NewRedactor(RedactionConfig{Mode: RedactMask})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	processor.SetRedactor(redactor)
+
+	msg := models.SlackMessage{
+		MessageID: "m1", Channel: "C1", User: "U1", TS: "1709287200.000100",
+		Timestamp: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		Content:   "*Contact* <mailto:jane@example.com|jane@example.com> on 555-123-4567",
+	}
+	docs, err := processor.ProcessMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("ProcessMessage() returned %d documents, want 1", len(docs))
+	}
+	doc := docs[0]
+	for field, value := range map[string]string{"content": doc.Content, "raw content": doc.RawContent, "title": doc.Metadata.Title} {
+		if strings.Contains(value, "jane@") || strings.Contains(value, "4567") {
+			t.Errorf("%s is not redacted: %q", field, value)
+		}
+	}
+	if doc.Content != "Contact [EMAIL] on [PHONE]" {
+		t.Errorf("Content = %q", doc.Content)
+	}
+	// The title, the text and the raw text count as one message
+	if report := redactor.Report(); report.Redacted != 1 || report.Matches["email"] != 4 || report.Matches["phone"] != 3 {
+		t.Errorf("Report() = %+v, want one redacted message", report)
+	}
+
+	// Dropped messages produce no documents and are left out of threads
+	dropping, _ := NewRedactor(RedactionConfig{Mode: RedactDrop})
+	processor.SetRedactor(dropping)
+	if docs, err := processor.ProcessMessage(context.Background(), msg); err != nil || len(docs) != 0 {
+		t.Errorf("ProcessMessage() = %d documents, %v; want none", len(docs), err)
+	}
+	thread := models.SlackThread{
+		Channel:  "C1",
+		ThreadTS: msg.TS,
+		Parent:   models.SlackMessage{MessageID: "p", Channel: "C1", User: "U1", TS: msg.TS, Content: "Who handles billing?"},
+		Replies:  []models.SlackMessage{msg, {MessageID: "r2", Channel: "C1", User: "U2", Content: "The finance team"}},
+	}
+	docs, err = processor.ProcessThread(context.Background(), thread)
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("ProcessThread() = %d documents, %v", len(docs), err)
+	}
+	if strings.Contains(docs[0].Content, "Contact") || !strings.Contains(docs[0].Content, "The finance team") {
+		t.Errorf("transcript = %q", docs[0].Content)
+	}
+}
+
+func TestDocumentProcessor_RedactionAcrossFields(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	server.SetEmbeddingDimension(8)
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder(server.URL, "nomic-embed-text"), 1000, 100)
+	// The pattern would run on from the end of one field into the next
+	redactor, err := NewRedactor(RedactionConfig{Detectors: []string{DetectorEmail}, Custom: map[string]string{"ticket": `TICKET\S*`}})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	processor.SetRedactor(redactor)
+
+	msg := models.SlackMessage{MessageID: "m1", Channel: "C1", User: "U1", TS: "1709287200.000100", Content: "see TICKET"}
+	docs, err := processor.ProcessMessage(context.Background(), msg)
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("ProcessMessage() = %d documents, %v", len(docs), err)
+	}
+	if docs[0].Content != "see [TICKET]" || docs[0].Metadata.Title != "see [TICKET]" {
+		t.Errorf("content = %q, title = %q", docs[0].Content, docs[0].Metadata.Title)
+	}
+	if report := redactor.Report(); report.Redacted != 1 {
+		t.Errorf("Report() = %+v, want one redacted message", report)
+	}
+}
@@ -0,0 +1,210 @@
+package processing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/secrets"
+)
+
+// SecretAction is what happens to messages and records containing secrets
+type SecretAction string
+
+const (
+	// SecretQuarantine leaves messages and records with a secret out of
+	// the index entirely, recording them in the audit for review
+	SecretQuarantine SecretAction = "quarantine"
+	// SecretRedact replaces each secret with "[REDACTED:<rule>]" and
+	// indexes the rest
+	SecretRedact SecretAction = "redact"
+)
+
+// SecretFinding is an audited secret, located by the record it was found
+// in. Like secrets.Finding it never holds the secret itself.
+type SecretFinding struct {
+	secrets.Finding
+	Source    string       `json:"source"`
+	SourceID  string       `json:"source_id"`
+	Channel   string       `json:"channel,omitempty"`
+	AuthorID  string       `json:"author_id,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	URL       string       `json:"url,omitempty"`
+	Action    SecretAction `json:"action"`
+}
+
+// SecretAudit is the audit report of a SecretGuard
+type SecretAudit struct {
+	// Quarantined and Redacted count the records acted on
+	Quarantined int             `json:"quarantined"`
+	Redacted    int             `json:"redacted"`
+	Findings    []SecretFinding `json:"findings"`
+}
+
+// String summarizes the audit, e.g. "2 quarantined, 0 redacted (github-token: 1, slack-token: 1)"
+func (a SecretAudit) String() string {
+	counts := make(map[string]int)
+	for _, f := range a.Findings {
+		counts[f.Rule]++
+	}
+	rules := make([]string, 0, len(counts))
+	for rule := range counts {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for i, rule := range rules {
+		rules[i] = fmt.Sprintf("%s: %d", rule, counts[rule])
+	}
+
+	summary := fmt.Sprintf("%d quarantined, %d redacted", a.Quarantined, a.Redacted)
+	if len(rules) > 0 {
+		summary += " (" + strings.Join(rules, ", ") + ")"
+	}
+	return summary
+}
+
+// SecretGuard keeps credentials pasted into messages out of the index,
+// quarantining or redacting what a secrets.Scanner finds and auditing it.
+// It is safe for concurrent use and keeps the audit until Reset.
+type SecretGuard struct {
+	scanner *secrets.Scanner
+	action  SecretAction
+
+	audit    SecretAudit
+	auditLog *json.Encoder
+	mu       sync.Mutex
+}
+
+// NewSecretGuard creates a guard taking action on what scanner finds,
+// quarantining by default
+func NewSecretGuard(scanner *secrets.Scanner, action SecretAction) (*SecretGuard, error) {
+	switch action {
+	case SecretQuarantine, SecretRedact:
+	case "":
+		action = SecretQuarantine
+	default:
+		return nil, fmt.Errorf("invalid secret action: %s", action)
+	}
+	return &SecretGuard{scanner: scanner, action: action}, nil
+}
+
+// Action returns what the guard does with secrets
+func (g *SecretGuard) Action() SecretAction {
+	return g.action
+}
+
+// Audit returns what the guard has found since it was created or last
+// reset
+func (g *SecretGuard) Audit() SecretAudit {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	audit := g.audit
+	audit.Findings = append([]SecretFinding(nil), g.audit.Findings...)
+	return audit
+}
+
+// SetAuditLog makes the guard append each finding to w as a line of JSON
+// as soon as it is found, e.g. to a file the security team reviews. Nil
+// disables it.
+func (g *SecretGuard) SetAuditLog(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.auditLog = nil
+	if w != nil {
+		g.auditLog = json.NewEncoder(w)
+	}
+}
+
+// Reset clears the audit, e.g. at the start of an ingestion run
+func (g *SecretGuard) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.audit = SecretAudit{}
+}
+
+// guardRecord scans a record's text fields, reporting whether the record
+// must be quarantined. In redact mode the secrets are replaced in place.
+func (g *SecretGuard) guardRecord(record *models.Record) (quarantine bool) {
+	// The other fields repeat the text, so each secret is audited once and
+	// the record counts once
+	fields := screenedFields(record)
+	var findings []secrets.Finding
+	for _, field := range fields {
+		redacted, found := g.scanner.Redact(*field)
+		if len(found) == 0 {
+			continue
+		}
+		findings = append(findings, found...)
+		if g.action == SecretRedact {
+			*field = redacted
+		}
+	}
+	if len(findings) == 0 {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	seen := make(map[string]bool, len(findings))
+	for _, f := range findings {
+		if seen[f.Fingerprint] {
+			continue
+		}
+		seen[f.Fingerprint] = true
+		finding := SecretFinding{
+			Finding:   f,
+			Source:    record.Source,
+			SourceID:  record.ID,
+			Channel:   record.Metadata["channel"],
+			AuthorID:  record.AuthorID,
+			CreatedAt: record.CreatedAt,
+			URL:       record.URL,
+			Action:    g.action,
+		}
+		g.audit.Findings = append(g.audit.Findings, finding)
+		if g.auditLog != nil {
+			if err := g.auditLog.Encode(finding); err != nil {
+				log.Printf("Failed to write secret audit log: %v", err)
+			}
+		}
+	}
+	if g.action == SecretQuarantine {
+		g.audit.Quarantined++
+		return true
+	}
+	g.audit.Redacted++
+	return false
+}
+
+// guardText screens a line of a thread transcript, returning "" if it
+// must be quarantined. Thread lines repeat messages that were audited on
+// their own, so they are not audited again.
+func (g *SecretGuard) guardText(text string) string {
+	redacted, findings := g.scanner.Redact(text)
+	if len(findings) > 0 && g.action == SecretQuarantine {
+		return ""
+	}
+	return redacted
+}
+
+// SetSecretGuard makes the processor screen text, titles and raw text for
+// secrets before embedding and storage. Nil disables the screening. Set it
+// before SetStages, which checks that the stages screen for secrets.
+func (p *DocumentProcessor) SetSecretGuard(guard *SecretGuard) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.guard = guard
+}
+
+// secretGuard returns the processor's secret guard, if any
+func (p *DocumentProcessor) secretGuard() *SecretGuard {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.guard
+}
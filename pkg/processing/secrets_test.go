@@ -0,0 +1,98 @@
+package processing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/embeddings"
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/ollama/ollamatest"
+	"github.com/testsabirweb/connect_llm/pkg/secrets"
+)
+
+func TestDocumentProcessor_SecretGuard(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	server.SetEmbeddingDimension(8)
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder(server.URL, "nomic-embed-text"), 1000, 100)
+
+	// Assembled from parts so the repository's own secret scanning does not
+	// flag it
+	token := "ghp_" + "Zq8xT2mV9kLp4RwB7nYc" + "3fHd6JsQ1aUe5GtX0bWi"
+	leak := models.SlackMessage{
+		MessageID: "m1", Channel: "C1", User: "U1", TS: "1709287200.000100",
+		Timestamp: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		Content:   "CI token is `" + token + "`",
+	}
+	thread := models.SlackThread{
+		Channel:  "C1",
+		ThreadTS: "1709287100.000100",
+		Parent:   models.SlackMessage{MessageID: "p", Channel: "C1", User: "U2", TS: "1709287100.000100", Content: "Which token does CI use?"},
+		Replies:  []models.SlackMessage{leak, {MessageID: "r2", Channel: "C1", User: "U2", Content: "Thanks, rotating it now"}},
+	}
+
+	quarantine, err := NewSecretGuard(secrets.Default(), "")
+	if err != nil {
+		t.Fatalf("NewSecretGuard() error = %v", err)
+	}
+	var auditLog bytes.Buffer
+	quarantine.SetAuditLog(&auditLog)
+	processor.SetSecretGuard(quarantine)
+
+	docs, err := processor.ProcessMessage(context.Background(), leak)
+	if err != nil || len(docs) != 0 {
+		t.Fatalf("ProcessMessage() = %d documents, %v; want the message quarantined", len(docs), err)
+	}
+	docs, err = processor.ProcessThread(context.Background(), thread)
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("ProcessThread() = %d documents, %v", len(docs), err)
+	}
+	if strings.Contains(docs[0].Content, "CI token is") || !strings.Contains(docs[0].Content, "rotating it now") {
+		t.Errorf("transcript = %q", docs[0].Content)
+	}
+
+	audit := quarantine.Audit()
+	if audit.Quarantined != 1 || len(audit.Findings) != 1 {
+		t.Fatalf("Audit() = %+v, want one quarantined finding", audit)
+	}
+	finding := audit.Findings[0]
+	if finding.Rule != "github-token" || finding.SourceID != "m1" || finding.Channel != "C1" || finding.Action != SecretQuarantine {
+		t.Errorf("finding = %+v", finding)
+	}
+	if audit.String() != "1 quarantined, 0 redacted (github-token: 1)" {
+		t.Errorf("String() = %q", audit.String())
+	}
+	if strings.Contains(auditLog.String(), token) {
+		t.Error("audit log holds the secret")
+	}
+	var logged SecretFinding
+	if err := json.Unmarshal(auditLog.Bytes(), &logged); err != nil || logged.Fingerprint != finding.Fingerprint {
+		t.Errorf("audit log = %q, %v", auditLog.String(), err)
+	}
+
+	redact, _ := NewSecretGuard(secrets.Default(), SecretRedact)
+	processor.SetSecretGuard(redact)
+	docs, err = processor.ProcessMessage(context.Background(), leak)
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("ProcessMessage() = %d documents, %v", len(docs), err)
+	}
+	for field, value := range map[string]string{"content": docs[0].Content, "raw content": docs[0].RawContent, "title": docs[0].Metadata.Title} {
+		if strings.Contains(value, token) {
+			t.Errorf("%s holds the secret: %q", field, value)
+		}
+	}
+	if docs[0].Content != "CI token is `[REDACTED:github-token]`" {
+		t.Errorf("Content = %q", docs[0].Content)
+	}
+	if audit := redact.Audit(); audit.Redacted != 1 || len(audit.Findings) != 1 {
+		t.Errorf("Audit() = %+v, want one redacted finding", audit)
+	}
+
+	if _, err := NewSecretGuard(secrets.Default(), "ignore"); err == nil {
+		t.Error("NewSecretGuard() with an invalid action succeeded")
+	}
+}
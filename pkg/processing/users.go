@@ -0,0 +1,38 @@
+package processing
+
+import (
+	"github.com/testsabirweb/connect_llm/pkg/models"
+)
+
+// UserResolver looks up Slack users by ID
+type UserResolver interface {
+	Lookup(id string) (models.SlackUser, bool)
+}
+
+// SetUserResolver makes the processor store display names as authors and
+// rewrite user mentions into names before embedding
+func (p *DocumentProcessor) SetUserResolver(users UserResolver) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.users = users
+}
+
+// lookupUser returns the user with the given ID if a resolver is set
+func (p *DocumentProcessor) lookupUser(id string) (models.SlackUser, bool) {
+	p.mu.RLock()
+	users := p.users
+	p.mu.RUnlock()
+
+	if users == nil || id == "" {
+		return models.SlackUser{}, false
+	}
+	return users.Lookup(id)
+}
+
+// displayName returns the user's display name, falling back to the ID
+func (p *DocumentProcessor) displayName(id string) string {
+	if user, ok := p.lookupUser(id); ok {
+		return user.DisplayName()
+	}
+	return id
+}
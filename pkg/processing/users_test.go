@@ -0,0 +1,93 @@
+package processing
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testsabirweb/connect_llm/pkg/embeddings"
+	"github.com/testsabirweb/connect_llm/pkg/models"
+	"github.com/testsabirweb/connect_llm/pkg/ollama/ollamatest"
+)
+
+// mapResolver is a UserResolver backed by a map
+type mapResolver map[string]models.SlackUser
+
+func (m mapResolver) Lookup(id string) (models.SlackUser, bool) {
+	user, ok := m[id]
+	return user, ok
+}
+
+var testUsers = mapResolver{
+	"U01B4H1FQTS": {ID: "U01B4H1FQTS", Name: "alice", RealName: "Alice Adams"},
+	"U02":         {ID: "U02", Name: "bob"},
+	"UBOT":        {ID: "UBOT", Name: "github", IsBot: true},
+}
+
+func TestDocumentProcessor_ResolveMentions(t *testing.T) {
+	processor := NewDocumentProcessor(nil, 100, 20)
+	processor.SetUserResolver(testUsers)
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"known user", "thanks <@U01B4H1FQTS>!", "thanks @Alice Adams!"},
+		{"name without real name", "<@U02> can you look?", "@bob can you look?"},
+		{"several mentions", "<@U02> and <@U01B4H1FQTS>", "@bob and @Alice Adams"},
+		{"unknown user with label", "ping <@U999|carol>", "ping @carol"},
+		{"unknown user", "ping <@U999>", "ping @U999"},
+		{"no mentions", "plain text", "plain text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := processor.normalizeText(tt.text).Text; got != tt.want {
+				t.Errorf("normalizeText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Without a resolver mentions keep their ID
+	if got := NewDocumentProcessor(nil, 100, 20).normalizeText("hi <@U02>").Text; got != "hi @U02" {
+		t.Errorf("normalizeText() without users = %q", got)
+	}
+}
+
+func TestDocumentProcessor_ProcessMessageWithUsers(t *testing.T) {
+	server := ollamatest.NewServer("nomic-embed-text")
+	defer server.Close()
+	processor := NewDocumentProcessor(embeddings.NewOllamaEmbedder(server.URL, "nomic-embed-text"), 100, 20)
+	processor.SetUserResolver(testUsers)
+
+	docs, err := processor.ProcessMessage(context.Background(), models.SlackMessage{
+		MessageID: "msg1",
+		Timestamp: time.Now(),
+		Channel:   "C123",
+		User:      "U02",
+		Content:   "<@U01B4H1FQTS> the release is ready",
+		Type:      "message",
+	})
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("got %d documents, want 1", len(docs))
+	}
+
+	doc := docs[0]
+	if doc.Content != "@Alice Adams the release is ready" {
+		t.Errorf("Content = %q", doc.Content)
+	}
+	if !strings.HasPrefix(doc.Metadata.Title, "@Alice Adams") {
+		t.Errorf("Title = %q", doc.Metadata.Title)
+	}
+	if doc.Metadata.Author != "bob" || doc.Metadata.AuthorID != "U02" {
+		t.Errorf("Author = %q, AuthorID = %q", doc.Metadata.Author, doc.Metadata.AuthorID)
+	}
+	if tags := processor.extractTags(models.SlackMessage{Channel: "C123", User: "UBOT"}); tags[len(tags)-1] != "bot" {
+		t.Errorf("bot user not tagged: %v", tags)
+	}
+}
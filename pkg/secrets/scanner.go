@@ -0,0 +1,268 @@
+// Package secrets finds credentials such as API keys, access tokens and
+// private keys in text, so they can be kept out of the index and out of
+// chat answers
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultMinEntropy is the Shannon entropy in bits per character a value
+// caught by a generic rule needs to count as a secret. Random keys score
+// above 4; words and placeholders stay below 3.5.
+const DefaultMinEntropy = 3.5
+
+// Rule describes one kind of secret
+type Rule struct {
+	// ID names the rule in findings, e.g. "github-token"
+	ID          string
+	Description string
+	Pattern     *regexp.Regexp
+	// SecretGroup is the capture group holding the secret itself, 0 being
+	// the whole match. Generic rules match "api_key = <value>" and only
+	// the value is the secret.
+	SecretGroup int
+	// MinEntropy, if set, rejects secrets whose entropy is lower, so that
+	// e.g. "password: changeme" is not reported
+	MinEntropy float64
+}
+
+// DefaultRules are the rules a Scanner uses unless configured otherwise.
+// They follow the gitleaks rules the repository is scanned with.
+var DefaultRules = []Rule{
+	{
+		ID:          "private-key",
+		Description: "Private key",
+		Pattern:     regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?(?:-----END[A-Z ]*PRIVATE KEY( BLOCK)?-----|$)`),
+	},
+	{
+		ID:          "aws-access-key",
+		Description: "AWS access key ID",
+		Pattern:     regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`),
+	},
+	{
+		ID:          "github-token",
+		Description: "GitHub token",
+		Pattern:     regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+	},
+	{
+		ID:          "slack-token",
+		Description: "Slack token",
+		Pattern:     regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	},
+	{
+		ID:          "slack-webhook",
+		Description: "Slack incoming webhook",
+		Pattern:     regexp.MustCompile(`https://hooks\.slack\.com/(?:services|workflows)/[A-Za-z0-9+/]{20,}`),
+	},
+	{
+		ID:          "openai-api-key",
+		Description: "OpenAI, OpenRouter or Anthropic API key",
+		Pattern:     regexp.MustCompile(`\bsk-(?:or-v1-|ant-[a-z0-9]+-|proj-)?[A-Za-z0-9_-]{20,}`),
+		MinEntropy:  3,
+	},
+	{
+		ID:          "google-api-key",
+		Description: "Google API key",
+		Pattern:     regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),
+	},
+	{
+		ID:          "stripe-key",
+		Description: "Stripe secret key",
+		Pattern:     regexp.MustCompile(`\b(?:sk|rk)_live_[0-9A-Za-z]{24,}\b`),
+	},
+	{
+		ID:          "jwt",
+		Description: "JSON Web Token",
+		Pattern:     regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	},
+	{
+		ID:          "generic-api-key",
+		Description: "Generic API key",
+		Pattern:     regexp.MustCompile(`(?i)(?:api[_\-\s]?key|apikey)[ \t]*[:=][ \t]*['"]?([a-zA-Z0-9\-_]{20,})['"]?`),
+		SecretGroup: 1,
+		MinEntropy:  DefaultMinEntropy,
+	},
+	{
+		ID:          "generic-secret",
+		Description: "Password, secret or token assignment",
+		Pattern:     regexp.MustCompile(`(?i)\b(?:password|passwd|secret|token|client_secret|access_token)[ \t]*[:=][ \t]*['"]?([^\s'"]{12,})['"]?`),
+		SecretGroup: 1,
+		MinEntropy:  DefaultMinEntropy,
+	},
+}
+
+// defaultAllowlist matches placeholders that look like secrets but are
+// not, as in .gitleaks.toml
+var defaultAllowlist = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)your[-_].*[-_](?:key|token|secret)[-_]here`),
+	regexp.MustCompile(`(?i)<your-.*-key>`),
+	regexp.MustCompile(`\{\{.*\}\}`),
+	regexp.MustCompile(`(?i)example|placeholder|dummy|x{8,}`),
+}
+
+// Config configures a Scanner
+type Config struct {
+	// Rules replaces DefaultRules if set
+	Rules []Rule
+	// Allowlist holds further regular expressions for values that are not
+	// secrets, e.g. known test keys
+	Allowlist []string
+}
+
+// Finding is a secret found in text. It never holds the secret itself.
+type Finding struct {
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+	// Start and End locate the secret in the scanned text
+	Start int `json:"-"`
+	End   int `json:"-"`
+	// Fingerprint identifies the secret, so repeated leaks of one key can
+	// be told apart from different keys
+	Fingerprint string `json:"fingerprint"`
+	// Preview shows the start and end of the secret, e.g. "ghp_…a1B2", to
+	// help whoever rotates it
+	Preview string  `json:"preview"`
+	Entropy float64 `json:"entropy"`
+}
+
+// Scanner finds secrets in text. It is safe for concurrent use.
+type Scanner struct {
+	rules     []Rule
+	allowlist []*regexp.Regexp
+}
+
+// NewScanner creates a scanner with DefaultRules unless configured
+// otherwise
+func NewScanner(config ...Config) (*Scanner, error) {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	s := &Scanner{rules: cfg.Rules, allowlist: defaultAllowlist}
+	if len(s.rules) == 0 {
+		s.rules = DefaultRules
+	}
+	for _, expr := range cfg.Allowlist {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret allowlist pattern %q: %w", expr, err)
+		}
+		s.allowlist = append(s.allowlist[:len(s.allowlist):len(s.allowlist)], pattern)
+	}
+	return s, nil
+}
+
+// Default returns a scanner with DefaultRules and the default allowlist
+func Default() *Scanner {
+	return &Scanner{rules: DefaultRules, allowlist: defaultAllowlist}
+}
+
+// Scan returns the secrets in text, in order of position. Overlapping
+// matches of later rules are ignored.
+func (s *Scanner) Scan(text string) []Finding {
+	var findings []Finding
+	for _, rule := range s.rules {
+		for _, loc := range rule.Pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[2*rule.SecretGroup], loc[2*rule.SecretGroup+1]
+			if start < 0 || overlaps(findings, start, end) {
+				continue
+			}
+			secret := text[start:end]
+			entropy := Entropy(secret)
+			if entropy < rule.MinEntropy || s.allowed(text[loc[0]:loc[1]]) {
+				continue
+			}
+			findings = append(findings, Finding{
+				Rule:        rule.ID,
+				Description: rule.Description,
+				Start:       start,
+				End:         end,
+				Fingerprint: fingerprint(secret),
+				Preview:     preview(secret),
+				Entropy:     math.Round(entropy*100) / 100,
+			})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+	return findings
+}
+
+// Redact returns text with each secret replaced by "[REDACTED:<rule>]",
+// along with the findings
+func (s *Scanner) Redact(text string) (string, []Finding) {
+	findings := s.Scan(text)
+	if len(findings) == 0 {
+		return text, nil
+	}
+	var sb strings.Builder
+	last := 0
+	for _, f := range findings {
+		sb.WriteString(text[last:f.Start])
+		sb.WriteString("[REDACTED:" + f.Rule + "]")
+		last = f.End
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), findings
+}
+
+// allowed reports whether a match is a known placeholder
+func (s *Scanner) allowed(match string) bool {
+	for _, pattern := range s.allowlist {
+		if pattern.MatchString(match) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether [start, end) overlaps any finding
+func overlaps(findings []Finding, start, end int) bool {
+	for _, f := range findings {
+		if start < f.End && f.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// Entropy returns the Shannon entropy of s in bits per character
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// fingerprint returns a short hash of a secret
+func fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// preview shows at most the first and last four characters of a secret
+func preview(secret string) string {
+	runes := []rune(secret)
+	if len(runes) < 16 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:4]) + "…" + string(runes[len(runes)-4:])
+}
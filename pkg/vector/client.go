@@ -7,31 +7,62 @@ import (
 
 	"github.com/weaviate/weaviate-go-client/v4/weaviate"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/auth"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
 	"github.com/weaviate/weaviate/entities/models"
 )
 
 // Document represents a document to be stored in the vector database
 type Document struct {
-	ID        string
-	Content   string
-	Embedding []float32
-	Source    string
-	SourceID  string
-	Metadata  DocumentMetadata
+	ID      string
+	Content string
+	// RawContent is the source text as written, e.g. with Slack markup,
+	// when Content was rendered from it
+	RawContent string
+	Embedding  []float32
+	Source     string
+	SourceID   string
+	Metadata   DocumentMetadata
 }
 
 // DocumentMetadata contains metadata for a document
 type DocumentMetadata struct {
-	Title       string
-	Author      string
+	Title  string
+	Author string
+	// AuthorID is the source system's ID for the author, whose display
+	// name is stored in Author
+	AuthorID    string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	Permissions []string
 	Tags        []string
 	URL         string
+	// ThreadID groups documents from the same conversation thread, and
+	// ParentID links a reply to the source ID of the message it answers
+	ThreadID string
+	ParentID string
+	// Links, LinkedChannels and Code are the URLs, channel IDs and code
+	// spans referenced by the content
+	Links          []string
+	LinkedChannels []string
+	Code           []string
+	// BotEvent is the kind of event a bot notification reports, e.g.
+	// "pull_request", with the Git ref and the event's URL if it names them
+	BotEvent string
+	Ref      string
+	EventURL string
+	// ReactionCount, ReplyCount and Pinned are engagement signals that let
+	// retrieval favour answers readers found useful
+	ReactionCount int
+	ReplyCount    int
+	Pinned        bool
+	// Reactions breaks ReactionCount down by emoji, as "name:count"
+	Reactions []string
 }
 
+// PublicPermission in a document's permissions makes it readable by everyone
+const PublicPermission = "public"
+
 // SearchOptions contains options for search queries
 type SearchOptions struct {
 	Query   []float32
@@ -107,8 +138,8 @@ func (c *WeaviateClient) Initialize(ctx context.Context) error {
 	}
 
 	if exists {
-		// Class already exists, no need to create
-		return nil
+		// Class already exists; add properties introduced since it was created
+		return c.ensureProperties(ctx)
 	}
 
 	// Create the Document class schema
@@ -173,6 +204,8 @@ func (c *WeaviateClient) Initialize(ctx context.Context) error {
 		},
 	}
 
+	classObj.Properties = append(classObj.Properties, optionalProperties()...)
+
 	err = c.client.Schema().ClassCreator().
 		WithClass(classObj).
 		Do(ctx)
@@ -183,29 +216,171 @@ func (c *WeaviateClient) Initialize(ctx context.Context) error {
 	return nil
 }
 
-// Store stores a document in Weaviate
+// ensureProperties adds properties that are missing from an existing
+// Document class, so upgrades do not require recreating the schema
+func (c *WeaviateClient) ensureProperties(ctx context.Context) error {
+	class, err := c.client.Schema().ClassGetter().
+		WithClassName("Document").
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get class schema: %w", err)
+	}
+
+	existing := make(map[string]bool, len(class.Properties))
+	for _, prop := range class.Properties {
+		existing[prop.Name] = true
+	}
+
+	for _, prop := range optionalProperties() {
+		if existing[prop.Name] {
+			continue
+		}
+		err := c.client.Schema().PropertyCreator().
+			WithClassName("Document").
+			WithProperty(prop).
+			Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to add property %s: %w", prop.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// optionalProperties returns the Document properties added after the
+// original schema
+func optionalProperties() []*models.Property {
+	return []*models.Property{
+		{
+			Name:        "authorId",
+			DataType:    []string{"string"},
+			Description: "Source system ID of the document author",
+		},
+		{
+			Name:        "threadId",
+			DataType:    []string{"string"},
+			Description: "The thread the document belongs to",
+		},
+		{
+			Name:        "parentId",
+			DataType:    []string{"string"},
+			Description: "Source ID of the message this document replies to",
+		},
+		{
+			Name:        "rawContent",
+			DataType:    []string{"text"},
+			Description: "The source text as written, kept for display",
+		},
+		{
+			Name:        "reactionCount",
+			DataType:    []string{"int"},
+			Description: "Number of emoji reactions on the document",
+		},
+		{
+			Name:        "replyCount",
+			DataType:    []string{"int"},
+			Description: "Number of replies in the document's thread",
+		},
+		{
+			Name:        "pinned",
+			DataType:    []string{"boolean"},
+			Description: "Whether the document is pinned in its channel",
+		},
+		{
+			Name:        "links",
+			DataType:    []string{"string[]"},
+			Description: "URLs linked from the document",
+		},
+		{
+			Name:        "linkedChannels",
+			DataType:    []string{"string[]"},
+			Description: "IDs of the channels the document references",
+		},
+		{
+			Name:        "code",
+			DataType:    []string{"text[]"},
+			Description: "Code spans and blocks quoted in the document",
+		},
+		{
+			Name:        "botEvent",
+			DataType:    []string{"string"},
+			Description: "Kind of event a bot notification reports",
+		},
+		{
+			Name:        "ref",
+			DataType:    []string{"string"},
+			Description: "Git ref a bot notification names",
+		},
+		{
+			Name:        "eventUrl",
+			DataType:    []string{"string"},
+			Description: "URL of the event a bot notification reports",
+		},
+		{
+			Name:        "reactions",
+			DataType:    []string{"string[]"},
+			Description: "Emoji reactions on the document, as name:count",
+		},
+	}
+}
+
+// Store stores a document in Weaviate, replacing any document with the
+// same ID
 func (c *WeaviateClient) Store(ctx context.Context, doc Document) error {
 	// Create the data object
 	dataObj := map[string]interface{}{
-		"content":     doc.Content,
-		"source":      doc.Source,
-		"sourceId":    doc.SourceID,
-		"title":       doc.Metadata.Title,
-		"author":      doc.Metadata.Author,
-		"createdAt":   doc.Metadata.CreatedAt,
-		"updatedAt":   doc.Metadata.UpdatedAt,
-		"permissions": doc.Metadata.Permissions,
-		"tags":        doc.Metadata.Tags,
-		"url":         doc.Metadata.URL,
-	}
-
-	// Store the document with its embedding
-	_, err := c.client.Data().Creator().
+		"content":        doc.Content,
+		"rawContent":     doc.RawContent,
+		"source":         doc.Source,
+		"sourceId":       doc.SourceID,
+		"title":          doc.Metadata.Title,
+		"author":         doc.Metadata.Author,
+		"createdAt":      doc.Metadata.CreatedAt,
+		"updatedAt":      doc.Metadata.UpdatedAt,
+		"permissions":    doc.Metadata.Permissions,
+		"tags":           doc.Metadata.Tags,
+		"url":            doc.Metadata.URL,
+		"authorId":       doc.Metadata.AuthorID,
+		"threadId":       doc.Metadata.ThreadID,
+		"parentId":       doc.Metadata.ParentID,
+		"reactionCount":  doc.Metadata.ReactionCount,
+		"replyCount":     doc.Metadata.ReplyCount,
+		"pinned":         doc.Metadata.Pinned,
+		"links":          doc.Metadata.Links,
+		"linkedChannels": doc.Metadata.LinkedChannels,
+		"code":           doc.Metadata.Code,
+		"botEvent":       doc.Metadata.BotEvent,
+		"ref":            doc.Metadata.Ref,
+		"eventUrl":       doc.Metadata.EventURL,
+		"reactions":      doc.Metadata.Reactions,
+	}
+
+	// Documents are re-stored when their source is edited, so an existing
+	// object is replaced rather than rejected
+	exists, err := c.client.Data().Checker().
 		WithClassName("Document").
 		WithID(doc.ID).
-		WithProperties(dataObj).
-		WithVector(doc.Embedding).
 		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check document: %w", err)
+	}
+
+	if exists {
+		err = c.client.Data().Updater().
+			WithClassName("Document").
+			WithID(doc.ID).
+			WithProperties(dataObj).
+			WithVector(doc.Embedding).
+			Do(ctx)
+	} else {
+		// Store the document with its embedding
+		_, err = c.client.Data().Creator().
+			WithClassName("Document").
+			WithID(doc.ID).
+			WithProperties(dataObj).
+			WithVector(doc.Embedding).
+			Do(ctx)
+	}
 
 	if err != nil {
 		return fmt.Errorf("failed to store document: %w", err)
@@ -218,22 +393,7 @@ func (c *WeaviateClient) Store(ctx context.Context, doc Document) error {
 func (c *WeaviateClient) Search(ctx context.Context, query []float32, limit int) ([]Document, error) {
 	result, err := c.client.GraphQL().Get().
 		WithClassName("Document").
-		WithFields(
-			graphql.Field{Name: "content"},
-			graphql.Field{Name: "source"},
-			graphql.Field{Name: "sourceId"},
-			graphql.Field{Name: "title"},
-			graphql.Field{Name: "author"},
-			graphql.Field{Name: "createdAt"},
-			graphql.Field{Name: "updatedAt"},
-			graphql.Field{Name: "permissions"},
-			graphql.Field{Name: "tags"},
-			graphql.Field{Name: "url"},
-			graphql.Field{Name: "_additional", Fields: []graphql.Field{
-				{Name: "id"},
-				{Name: "distance"},
-			}},
-		).
+		WithFields(documentFields()...).
 		WithNearVector(c.client.GraphQL().NearVectorArgBuilder().
 			WithVector(query)).
 		WithLimit(limit).
@@ -246,27 +406,46 @@ func (c *WeaviateClient) Search(ctx context.Context, query []float32, limit int)
 	return c.parseSearchResults(result)
 }
 
+// documentFields returns the Document properties read back by searches
+func documentFields() []graphql.Field {
+	return []graphql.Field{
+		{Name: "content"},
+		{Name: "rawContent"},
+		{Name: "source"},
+		{Name: "sourceId"},
+		{Name: "title"},
+		{Name: "author"},
+		{Name: "createdAt"},
+		{Name: "updatedAt"},
+		{Name: "permissions"},
+		{Name: "tags"},
+		{Name: "url"},
+		{Name: "authorId"},
+		{Name: "threadId"},
+		{Name: "parentId"},
+		{Name: "reactionCount"},
+		{Name: "replyCount"},
+		{Name: "pinned"},
+		{Name: "links"},
+		{Name: "linkedChannels"},
+		{Name: "code"},
+		{Name: "botEvent"},
+		{Name: "ref"},
+		{Name: "eventUrl"},
+		{Name: "reactions"},
+		{Name: "_additional", Fields: []graphql.Field{
+			{Name: "id"},
+			{Name: "distance"},
+		}},
+	}
+}
+
 // SearchWithOptions performs a vector similarity search with filters
 func (c *WeaviateClient) SearchWithOptions(ctx context.Context, opts SearchOptions) ([]Document, error) {
 	// Build the base query
 	query := c.client.GraphQL().Get().
 		WithClassName("Document").
-		WithFields(
-			graphql.Field{Name: "content"},
-			graphql.Field{Name: "source"},
-			graphql.Field{Name: "sourceId"},
-			graphql.Field{Name: "title"},
-			graphql.Field{Name: "author"},
-			graphql.Field{Name: "createdAt"},
-			graphql.Field{Name: "updatedAt"},
-			graphql.Field{Name: "permissions"},
-			graphql.Field{Name: "tags"},
-			graphql.Field{Name: "url"},
-			graphql.Field{Name: "_additional", Fields: []graphql.Field{
-				{Name: "id"},
-				{Name: "distance"},
-			}},
-		)
+		WithFields(documentFields()...)
 
 	// Add vector search
 	if len(opts.Query) > 0 {
@@ -274,8 +453,34 @@ func (c *WeaviateClient) SearchWithOptions(ctx context.Context, opts SearchOptio
 			WithVector(opts.Query))
 	}
 
-	// TODO: Add proper filtering support once we understand the correct Weaviate API
-	// For now, we'll implement basic search without metadata filtering
+	// Restrict results to documents the user can read
+	var where []*filters.WhereBuilder
+	if user, ok := opts.Filters["permissions"].(string); ok && user != "" {
+		where = append(where, filters.Where().
+			WithPath([]string{"permissions"}).
+			WithOperator(filters.ContainsAny).
+			WithValueString(user, PublicPermission))
+	}
+
+	// Keep documents with any of the given tags, e.g. "repo:acme/api"
+	if tags, ok := opts.Filters["tags"].([]string); ok && len(tags) > 0 {
+		where = append(where, filters.Where().
+			WithPath([]string{"tags"}).
+			WithOperator(filters.ContainsAny).
+			WithValueString(tags...))
+	}
+
+	switch len(where) {
+	case 0:
+	case 1:
+		query = query.WithWhere(where[0])
+	default:
+		query = query.WithWhere(filters.Where().
+			WithOperator(filters.And).
+			WithOperands(where))
+	}
+
+	// TODO: Add support for the remaining metadata filters
 
 	// Apply limit
 	if opts.Limit > 0 {
@@ -359,6 +564,9 @@ func (c *WeaviateClient) parseSearchResults(result *models.GraphQLResponse) ([]D
 		if content, ok := docMap["content"].(string); ok {
 			doc.Content = content
 		}
+		if rawContent, ok := docMap["rawContent"].(string); ok {
+			doc.RawContent = rawContent
+		}
 		if source, ok := docMap["source"].(string); ok {
 			doc.Source = source
 		}
@@ -376,6 +584,35 @@ func (c *WeaviateClient) parseSearchResults(result *models.GraphQLResponse) ([]D
 		if url, ok := docMap["url"].(string); ok {
 			doc.Metadata.URL = url
 		}
+		if authorID, ok := docMap["authorId"].(string); ok {
+			doc.Metadata.AuthorID = authorID
+		}
+		if threadID, ok := docMap["threadId"].(string); ok {
+			doc.Metadata.ThreadID = threadID
+		}
+		if parentID, ok := docMap["parentId"].(string); ok {
+			doc.Metadata.ParentID = parentID
+		}
+		if botEvent, ok := docMap["botEvent"].(string); ok {
+			doc.Metadata.BotEvent = botEvent
+		}
+		if ref, ok := docMap["ref"].(string); ok {
+			doc.Metadata.Ref = ref
+		}
+		if eventURL, ok := docMap["eventUrl"].(string); ok {
+			doc.Metadata.EventURL = eventURL
+		}
+
+		// Extract engagement fields, which JSON decodes as float64
+		if reactionCount, ok := docMap["reactionCount"].(float64); ok {
+			doc.Metadata.ReactionCount = int(reactionCount)
+		}
+		if replyCount, ok := docMap["replyCount"].(float64); ok {
+			doc.Metadata.ReplyCount = int(replyCount)
+		}
+		if pinned, ok := docMap["pinned"].(bool); ok {
+			doc.Metadata.Pinned = pinned
+		}
 
 		// Extract date fields
 		if createdAt, ok := docMap["createdAt"].(string); ok {
@@ -390,22 +627,12 @@ func (c *WeaviateClient) parseSearchResults(result *models.GraphQLResponse) ([]D
 		}
 
 		// Extract array fields
-		if perms, ok := docMap["permissions"].([]interface{}); ok {
-			doc.Metadata.Permissions = make([]string, 0, len(perms))
-			for _, p := range perms {
-				if pStr, ok := p.(string); ok {
-					doc.Metadata.Permissions = append(doc.Metadata.Permissions, pStr)
-				}
-			}
-		}
-		if tags, ok := docMap["tags"].([]interface{}); ok {
-			doc.Metadata.Tags = make([]string, 0, len(tags))
-			for _, t := range tags {
-				if tStr, ok := t.(string); ok {
-					doc.Metadata.Tags = append(doc.Metadata.Tags, tStr)
-				}
-			}
-		}
+		doc.Metadata.Permissions = stringList(docMap["permissions"])
+		doc.Metadata.Tags = stringList(docMap["tags"])
+		doc.Metadata.Links = stringList(docMap["links"])
+		doc.Metadata.LinkedChannels = stringList(docMap["linkedChannels"])
+		doc.Metadata.Code = stringList(docMap["code"])
+		doc.Metadata.Reactions = stringList(docMap["reactions"])
 
 		// Extract additional fields (ID and distance)
 		if additional, ok := docMap["_additional"].(map[string]interface{}); ok {
@@ -420,3 +647,19 @@ func (c *WeaviateClient) parseSearchResults(result *models.GraphQLResponse) ([]D
 
 	return documents, nil
 }
+
+// stringList converts a string array property of a search result, or
+// returns nil if it is missing
+func stringList(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
@@ -63,7 +63,8 @@ RESPONSE=$(curl -s -X POST http://localhost:8080/api/v1/ingest \
   -H "Content-Type: application/json" \
   -d '{
     "type": "file",
-    "path": "slack/test_messages.csv"
+    "path": "slack/test_messages.csv",
+    "wait": true
   }')
 
 echo "Ingestion response:"